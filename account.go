@@ -0,0 +1,629 @@
+package main
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AccountService struct {
+	ODataContext                    string `json:"@odata.context"`
+	ODataType                       string `json:"@odata.type"`
+	ODataID                         string `json:"@odata.id"`
+	ID                              string `json:"Id"`
+	Name                            string `json:"Name"`
+	ServiceEnabled                  bool   `json:"ServiceEnabled"`
+	MinPasswordLength               int    `json:"MinPasswordLength"`
+	MaxPasswordLength               int    `json:"MaxPasswordLength"`
+	AccountLockoutThreshold         int    `json:"AccountLockoutThreshold"`
+	AccountLockoutDuration          int    `json:"AccountLockoutDuration"`
+	AccountLockoutCounterResetAfter int    `json:"AccountLockoutCounterResetAfter"`
+	Accounts                        Link   `json:"Accounts"`
+	Roles                           Link   `json:"Roles"`
+	Status                          Status `json:"Status"`
+}
+
+type ManagerAccount struct {
+	ODataContext           string              `json:"@odata.context"`
+	ODataType              string              `json:"@odata.type"`
+	ODataID                string              `json:"@odata.id"`
+	ID                     string              `json:"Id"`
+	Name                   string              `json:"Name"`
+	UserName               string              `json:"UserName"`
+	Password               *string             `json:"Password"`
+	RoleID                 string              `json:"RoleId"`
+	Enabled                bool                `json:"Enabled"`
+	Locked                 bool                `json:"Locked"`
+	PasswordChangeRequired bool                `json:"PasswordChangeRequired"`
+	AccountTypes           []string            `json:"AccountTypes"`
+	Links                  ManagerAccountLinks `json:"Links"`
+}
+
+type ManagerAccountLinks struct {
+	Role Link `json:"Role"`
+}
+
+type Role struct {
+	ODataContext       string   `json:"@odata.context"`
+	ODataType          string   `json:"@odata.type"`
+	ODataID            string   `json:"@odata.id"`
+	ID                 string   `json:"Id"`
+	Name               string   `json:"Name"`
+	RoleID             string   `json:"RoleId"`
+	IsPredefined       bool     `json:"IsPredefined"`
+	AssignedPrivileges []string `json:"AssignedPrivileges"`
+	OemPrivileges      []string `json:"OemPrivileges"`
+}
+
+type AccountCreateRequest struct {
+	UserName string `json:"UserName"`
+	Password string `json:"Password"`
+	RoleID   string `json:"RoleId"`
+	Enabled  *bool  `json:"Enabled"`
+}
+
+type AccountPatchRequest struct {
+	UserName *string `json:"UserName"`
+	Password *string `json:"Password"`
+	RoleID   *string `json:"RoleId"`
+	Enabled  *bool   `json:"Enabled"`
+	Locked   *bool   `json:"Locked"`
+}
+
+type AccountServicePatchRequest struct {
+	MinPasswordLength               *int `json:"MinPasswordLength"`
+	MaxPasswordLength               *int `json:"MaxPasswordLength"`
+	AccountLockoutThreshold         *int `json:"AccountLockoutThreshold"`
+	AccountLockoutDuration          *int `json:"AccountLockoutDuration"`
+	AccountLockoutCounterResetAfter *int `json:"AccountLockoutCounterResetAfter"`
+}
+
+const (
+	privilegeLogin               = "Login"
+	privilegeConfigureManager    = "ConfigureManager"
+	privilegeConfigureUsers      = "ConfigureUsers"
+	privilegeConfigureSelf       = "ConfigureSelf"
+	privilegeConfigureComponents = "ConfigureComponents"
+
+	// accountRoleKey is the gin context key requireAuth stores the
+	// authenticated account's role under.
+	accountRoleKey = "redfish_role"
+)
+
+// rolePrivileges holds the predefined Redfish roles in the order they are
+// listed in the Roles collection.
+var rolePrivileges = []struct {
+	id         string
+	privileges []string
+}{
+	{"Administrator", []string{privilegeLogin, privilegeConfigureManager, privilegeConfigureUsers, privilegeConfigureSelf, privilegeConfigureComponents}},
+	{"Operator", []string{privilegeLogin, privilegeConfigureSelf, privilegeConfigureComponents}},
+	{"ReadOnly", []string{privilegeLogin, privilegeConfigureSelf}},
+}
+
+func privilegesFor(roleID string) ([]string, bool) {
+	for _, role := range rolePrivileges {
+		if role.id == roleID {
+			return role.privileges, true
+		}
+	}
+	return nil, false
+}
+
+func roleHasPrivilege(roleID, privilege string) bool {
+	privileges, _ := privilegesFor(roleID)
+	for _, assigned := range privileges {
+		if assigned == privilege {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	errAccountLocked   = errors.New("account is locked")
+	errAccountDisabled = errors.New("account is disabled")
+	errBadCredentials  = errors.New("invalid username or password")
+)
+
+type mockAccount struct {
+	id       string
+	username string
+	password string
+	roleID   string
+	enabled  bool
+	// passwordChangeRequired restricts the account to changing its own
+	// password, like a BMC still using its factory credentials.
+	passwordChangeRequired bool
+	failures               int
+	lastFailure            time.Time
+	lockedOut              bool
+	lockedUntil            time.Time
+}
+
+// locked reports whether a lockout is in force. A zero lockedUntil keeps the
+// account locked until an administrator clears Locked.
+func (a *mockAccount) locked(now time.Time) bool {
+	return a.lockedOut && (a.lockedUntil.IsZero() || now.Before(a.lockedUntil))
+}
+
+func (a *mockAccount) unlock() {
+	a.lockedOut = false
+	a.lockedUntil = time.Time{}
+	a.failures = 0
+}
+
+// accountStore holds the BMC's user accounts and enforces the password and
+// lockout policy of the AccountService.
+type accountStore struct {
+	sync.Mutex
+	nextID   int
+	policy   AccountServiceConfig
+	accounts map[string]*mockAccount
+}
+
+func newAccountStore(authentication AuthenticationConfig, accounts []AccountConfig, policy AccountServiceConfig) *accountStore {
+	s := &accountStore{}
+	s.reset(authentication, accounts, policy)
+	return s
+}
+
+// reset replaces every account and the policy with the configured ones.
+func (s *accountStore) reset(authentication AuthenticationConfig, accounts []AccountConfig, policy AccountServiceConfig) {
+	s.Lock()
+	defer s.Unlock()
+	s.nextID = 1
+	s.policy = policy
+	s.accounts = map[string]*mockAccount{}
+	s.add(authentication.Username, authentication.Password, "Administrator", true).passwordChangeRequired = authentication.PasswordChangeRequired
+	for _, account := range accounts {
+		s.add(account.Username, account.Password, account.Role, true)
+	}
+}
+
+func (s *accountStore) add(username, password, roleID string, enabled bool) *mockAccount {
+	account := &mockAccount{
+		id:       strconv.Itoa(s.nextID),
+		username: username,
+		password: password,
+		roleID:   roleID,
+		enabled:  enabled,
+	}
+	s.nextID++
+	s.accounts[account.id] = account
+	return account
+}
+
+func (s *accountStore) findByUsername(username string) *mockAccount {
+	for _, account := range s.accounts {
+		if account.username == username {
+			return account
+		}
+	}
+	return nil
+}
+
+// authenticate checks a username and password. Consecutive failures within
+// the counter reset window lock the account for the lockout duration, or
+// until an administrator unlocks it when the duration is 0. A locked account
+// rejects even the correct password.
+func (s *accountStore) authenticate(username, password string, now time.Time) (mockAccount, error) {
+	s.Lock()
+	defer s.Unlock()
+	account := s.findByUsername(username)
+	if account == nil {
+		return mockAccount{}, errBadCredentials
+	}
+	if account.locked(now) {
+		return mockAccount{}, errAccountLocked
+	}
+	if subtle.ConstantTimeCompare([]byte(password), []byte(account.password)) != 1 {
+		if now.Sub(account.lastFailure) > time.Duration(s.policy.AccountLockoutCounterResetAfter)*time.Second {
+			account.failures = 0
+		}
+		account.failures++
+		account.lastFailure = now
+		if s.policy.AccountLockoutThreshold > 0 && account.failures >= s.policy.AccountLockoutThreshold {
+			account.lockedOut = true
+			account.lockedUntil = time.Time{}
+			if s.policy.AccountLockoutDuration > 0 {
+				account.lockedUntil = now.Add(time.Duration(s.policy.AccountLockoutDuration) * time.Second)
+			}
+			account.failures = 0
+		}
+		return mockAccount{}, errBadCredentials
+	}
+	if !account.enabled {
+		return mockAccount{}, errAccountDisabled
+	}
+	account.failures = 0
+	return *account, nil
+}
+
+// active returns the enabled, unlocked account with username, which is how
+// sessions are re-checked on every request.
+func (s *accountStore) active(username string, now time.Time) (mockAccount, bool) {
+	s.Lock()
+	defer s.Unlock()
+	account := s.findByUsername(username)
+	if account == nil || !account.enabled || account.locked(now) {
+		return mockAccount{}, false
+	}
+	return *account, true
+}
+
+func (s *accountStore) get(id string) (mockAccount, bool) {
+	s.Lock()
+	defer s.Unlock()
+	account, ok := s.accounts[id]
+	if !ok {
+		return mockAccount{}, false
+	}
+	return *account, true
+}
+
+func (s *accountStore) list() []mockAccount {
+	s.Lock()
+	defer s.Unlock()
+	list := make([]mockAccount, 0, len(s.accounts))
+	for _, account := range s.accounts {
+		list = append(list, *account)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		left, _ := strconv.Atoi(list[i].id)
+		right, _ := strconv.Atoi(list[j].id)
+		return left < right
+	})
+	return list
+}
+
+func (s *accountStore) currentPolicy() AccountServiceConfig {
+	s.Lock()
+	defer s.Unlock()
+	return s.policy
+}
+
+func (s *accountStore) checkPassword(password string) error {
+	if len(password) < s.policy.MinPasswordLength || len(password) > s.policy.MaxPasswordLength {
+		return fmt.Errorf("The password must be between %d and %d characters long.", s.policy.MinPasswordLength, s.policy.MaxPasswordLength)
+	}
+	return nil
+}
+
+func (s *accountStore) countEnabledAdministrators() int {
+	count := 0
+	for _, account := range s.accounts {
+		if account.enabled && account.roleID == "Administrator" {
+			count++
+		}
+	}
+	return count
+}
+
+func (p AccountServiceConfig) validate() error {
+	if p.MinPasswordLength < 1 || p.MaxPasswordLength < p.MinPasswordLength {
+		return errors.New("min_password_length must be at least 1 and not above max_password_length")
+	}
+	if p.AccountLockoutThreshold < 0 || p.AccountLockoutDuration < 0 || p.AccountLockoutCounterResetAfter < 0 {
+		return errors.New("account lockout settings must not be negative")
+	}
+	return nil
+}
+
+func accountURI(id string) string {
+	return "/redfish/v1/AccountService/Accounts/" + id
+}
+
+func accountResource(account mockAccount, now time.Time) ManagerAccount {
+	return ManagerAccount{
+		ODataContext:           "/redfish/v1/$metadata#ManagerAccount.ManagerAccount",
+		ODataType:              "#ManagerAccount.v1_10_0.ManagerAccount",
+		ODataID:                accountURI(account.id),
+		ID:                     account.id,
+		Name:                   "User Account",
+		UserName:               account.username,
+		RoleID:                 account.roleID,
+		Enabled:                account.enabled,
+		Locked:                 account.locked(now),
+		PasswordChangeRequired: account.passwordChangeRequired,
+		AccountTypes:           []string{"Redfish"},
+		Links:                  ManagerAccountLinks{Role: Link{ODataID: "/redfish/v1/AccountService/Roles/" + account.roleID}},
+	}
+}
+
+// requirePrivilege rejects the request with 403 unless the role requireAuth
+// stored for the caller has privilege.
+func requirePrivilege(privilege string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !roleHasPrivilege(c.GetString(accountRoleKey), privilege) {
+			c.Header("OData-Version", "4.0")
+			redfishError(c, http.StatusForbidden, baseMessage("InsufficientPrivilege"))
+		}
+	}
+}
+
+func (b *mockBMC) getAccountService(c *gin.Context) {
+	c.Header("OData-Version", "4.0")
+	policy := b.accounts.currentPolicy()
+	service := AccountService{
+		ODataContext:                    "/redfish/v1/$metadata#AccountService.AccountService",
+		ODataType:                       "#AccountService.v1_13_0.AccountService",
+		ODataID:                         "/redfish/v1/AccountService",
+		ID:                              "AccountService",
+		Name:                            "Account Service",
+		ServiceEnabled:                  true,
+		MinPasswordLength:               policy.MinPasswordLength,
+		MaxPasswordLength:               policy.MaxPasswordLength,
+		AccountLockoutThreshold:         policy.AccountLockoutThreshold,
+		AccountLockoutDuration:          policy.AccountLockoutDuration,
+		AccountLockoutCounterResetAfter: policy.AccountLockoutCounterResetAfter,
+		Accounts:                        Link{ODataID: "/redfish/v1/AccountService/Accounts"},
+		Roles:                           Link{ODataID: "/redfish/v1/AccountService/Roles"},
+		Status:                          Status{State: "Enabled", Health: "OK"},
+	}
+	c.JSON(http.StatusOK, service)
+}
+
+func (b *mockBMC) patchAccountService(c *gin.Context) {
+	c.Header("OData-Version", "4.0")
+	var req AccountServicePatchRequest
+	if !bindRedfishJSON(c, &req) {
+		return
+	}
+
+	b.accounts.Lock()
+	defer b.accounts.Unlock()
+	policy := b.accounts.policy
+	for _, field := range []struct {
+		name   string
+		value  *int
+		target *int
+	}{
+		{"MinPasswordLength", req.MinPasswordLength, &policy.MinPasswordLength},
+		{"MaxPasswordLength", req.MaxPasswordLength, &policy.MaxPasswordLength},
+		{"AccountLockoutThreshold", req.AccountLockoutThreshold, &policy.AccountLockoutThreshold},
+		{"AccountLockoutDuration", req.AccountLockoutDuration, &policy.AccountLockoutDuration},
+		{"AccountLockoutCounterResetAfter", req.AccountLockoutCounterResetAfter, &policy.AccountLockoutCounterResetAfter},
+	} {
+		if field.value == nil {
+			continue
+		}
+		if *field.value < 0 {
+			redfishError(c, http.StatusBadRequest, baseMessage("PropertyValueOutOfRange", strconv.Itoa(*field.value), field.name))
+			return
+		}
+		*field.target = *field.value
+	}
+	if err := policy.validate(); err != nil {
+		c.JSON(http.StatusBadRequest, errorWithDetail(err.Error(),
+			baseMessage("PropertyValueOutOfRange", strconv.Itoa(policy.MinPasswordLength), "MinPasswordLength")))
+		return
+	}
+	b.accounts.policy = policy
+
+	c.Status(http.StatusNoContent)
+}
+
+func (b *mockBMC) getAccountsCollection(c *gin.Context) {
+	c.Header("OData-Version", "4.0")
+	accounts := b.accounts.list()
+	members := make([]Link, 0, len(accounts))
+	for _, account := range accounts {
+		members = append(members, Link{ODataID: accountURI(account.id)})
+	}
+	collection := Collection{
+		ODataContext: "/redfish/v1/$metadata#ManagerAccountCollection.ManagerAccountCollection",
+		ODataType:    "#ManagerAccountCollection.ManagerAccountCollection",
+		ODataID:      "/redfish/v1/AccountService/Accounts",
+		Name:         "Accounts Collection",
+		MembersCount: len(members),
+		Members:      members,
+	}
+	c.JSON(http.StatusOK, collection)
+}
+
+func (b *mockBMC) getAccount(c *gin.Context) {
+	c.Header("OData-Version", "4.0")
+	account, ok := b.accounts.get(c.Param("id"))
+	if !ok {
+		resourceNotFound(c, "ManagerAccount", c.Param("id"))
+		return
+	}
+	c.JSON(http.StatusOK, accountResource(account, b.now()))
+}
+
+func (b *mockBMC) createAccount(c *gin.Context) {
+	c.Header("OData-Version", "4.0")
+	var req AccountCreateRequest
+	if !bindRedfishJSON(c, &req) {
+		return
+	}
+	for _, required := range []struct{ name, value string }{
+		{"UserName", req.UserName}, {"Password", req.Password}, {"RoleId", req.RoleID},
+	} {
+		if required.value == "" {
+			redfishError(c, http.StatusBadRequest, baseMessage("PropertyMissing", required.name))
+			return
+		}
+	}
+	if _, ok := privilegesFor(req.RoleID); !ok {
+		redfishError(c, http.StatusBadRequest, baseMessage("PropertyValueNotInList", req.RoleID, "RoleId"))
+		return
+	}
+
+	b.accounts.Lock()
+	defer b.accounts.Unlock()
+	if b.accounts.findByUsername(req.UserName) != nil {
+		redfishError(c, http.StatusConflict, baseMessage("ResourceAlreadyExists", "ManagerAccount", "UserName", req.UserName))
+		return
+	}
+	if err := b.accounts.checkPassword(req.Password); err != nil {
+		passwordPolicyError(c, err)
+		return
+	}
+	enabled := req.Enabled == nil || *req.Enabled
+	account := b.accounts.add(req.UserName, req.Password, req.RoleID, enabled)
+
+	resource := accountResource(*account, b.now())
+	c.Header("Location", resource.ODataID)
+	c.JSON(http.StatusCreated, resource)
+}
+
+// patchAccount lets ConfigureUsers change any account. Callers with only
+// ConfigureSelf may change their own password and nothing else.
+func (b *mockBMC) patchAccount(c *gin.Context) {
+	c.Header("OData-Version", "4.0")
+	var req AccountPatchRequest
+	if !bindRedfishJSON(c, &req) {
+		return
+	}
+
+	b.accounts.Lock()
+	defer b.accounts.Unlock()
+	account, ok := b.accounts.accounts[c.Param("id")]
+	if !ok {
+		resourceNotFound(c, "ManagerAccount", c.Param("id"))
+		return
+	}
+	role := c.GetString(accountRoleKey)
+	ownPassword := account.username == c.GetString(gin.AuthUserKey) && req.UserName == nil && req.RoleID == nil && req.Enabled == nil && req.Locked == nil
+	if !roleHasPrivilege(role, privilegeConfigureUsers) && !(ownPassword && roleHasPrivilege(role, privilegeConfigureSelf)) {
+		redfishError(c, http.StatusForbidden, baseMessage("InsufficientPrivilege"))
+		return
+	}
+
+	if req.UserName != nil {
+		if *req.UserName == "" {
+			redfishError(c, http.StatusBadRequest, baseMessage("PropertyValueFormatError", "", "UserName"))
+			return
+		}
+		if other := b.accounts.findByUsername(*req.UserName); other != nil && other != account {
+			redfishError(c, http.StatusConflict, baseMessage("ResourceAlreadyExists", "ManagerAccount", "UserName", *req.UserName))
+			return
+		}
+	}
+	if req.Password != nil {
+		if err := b.accounts.checkPassword(*req.Password); err != nil {
+			passwordPolicyError(c, err)
+			return
+		}
+		if account.passwordChangeRequired && *req.Password == account.password {
+			passwordPolicyError(c, errors.New("The new password must differ from the current password."))
+			return
+		}
+	}
+	if req.RoleID != nil {
+		if _, ok := privilegesFor(*req.RoleID); !ok {
+			redfishError(c, http.StatusBadRequest, baseMessage("PropertyValueNotInList", *req.RoleID, "RoleId"))
+			return
+		}
+	}
+	if req.Locked != nil && *req.Locked {
+		redfishError(c, http.StatusBadRequest, baseMessage("PropertyValueNotInList", "true", "Locked"))
+		return
+	}
+	demotesAdministrator := account.roleID == "Administrator" && account.enabled &&
+		((req.RoleID != nil && *req.RoleID != "Administrator") || (req.Enabled != nil && !*req.Enabled))
+	if demotesAdministrator && b.accounts.countEnabledAdministrators() == 1 {
+		c.JSON(http.StatusBadRequest, errorWithDetail("At least one enabled Administrator account is required.",
+			baseMessage("PropertyValueNotInList", account.roleID, "RoleId")))
+		return
+	}
+
+	if req.UserName != nil {
+		account.username = *req.UserName
+	}
+	if req.Password != nil {
+		account.password = *req.Password
+		account.passwordChangeRequired = false
+	}
+	if req.RoleID != nil {
+		account.roleID = *req.RoleID
+	}
+	if req.Enabled != nil {
+		account.enabled = *req.Enabled
+	}
+	if req.Locked != nil {
+		account.unlock()
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (b *mockBMC) deleteAccount(c *gin.Context) {
+	c.Header("OData-Version", "4.0")
+	b.accounts.Lock()
+	defer b.accounts.Unlock()
+	account, ok := b.accounts.accounts[c.Param("id")]
+	if !ok {
+		resourceNotFound(c, "ManagerAccount", c.Param("id"))
+		return
+	}
+	if account.enabled && account.roleID == "Administrator" && b.accounts.countEnabledAdministrators() == 1 {
+		redfishError(c, http.StatusBadRequest, baseMessage("ResourceCannotBeDeleted"))
+		return
+	}
+	delete(b.accounts.accounts, account.id)
+	c.Status(http.StatusNoContent)
+}
+
+// authenticationFailed rejects a login. A locked account gets its own reason
+// so clients can tell a lockout from a wrong password.
+func authenticationFailed(c *gin.Context, err error) {
+	if errors.Is(err, errAccountLocked) {
+		redfishError(c, http.StatusUnauthorized, baseMessage("ResourceAtUriUnauthorized", c.Request.URL.Path,
+			"The account is locked after too many failed login attempts"))
+		return
+	}
+	redfishError(c, http.StatusUnauthorized, baseMessage("NoValidSession"))
+}
+
+func passwordPolicyError(c *gin.Context, err error) {
+	c.JSON(http.StatusBadRequest, errorWithDetail(err.Error(), baseMessage("PropertyValueFormatError", "******", "Password")))
+}
+
+func getRolesCollection(c *gin.Context) {
+	c.Header("OData-Version", "4.0")
+	members := make([]Link, 0, len(rolePrivileges))
+	for _, role := range rolePrivileges {
+		members = append(members, Link{ODataID: "/redfish/v1/AccountService/Roles/" + role.id})
+	}
+	collection := Collection{
+		ODataContext: "/redfish/v1/$metadata#RoleCollection.RoleCollection",
+		ODataType:    "#RoleCollection.RoleCollection",
+		ODataID:      "/redfish/v1/AccountService/Roles",
+		Name:         "Roles Collection",
+		MembersCount: len(members),
+		Members:      members,
+	}
+	c.JSON(http.StatusOK, collection)
+}
+
+func getRole(c *gin.Context) {
+	c.Header("OData-Version", "4.0")
+	roleID := c.Param("id")
+	privileges, ok := privilegesFor(roleID)
+	if !ok {
+		resourceNotFound(c, "Role", roleID)
+		return
+	}
+	role := Role{
+		ODataContext:       "/redfish/v1/$metadata#Role.Role",
+		ODataType:          "#Role.v1_3_1.Role",
+		ODataID:            "/redfish/v1/AccountService/Roles/" + roleID,
+		ID:                 roleID,
+		Name:               roleID + " Role",
+		RoleID:             roleID,
+		IsPredefined:       true,
+		AssignedPrivileges: privileges,
+		OemPrivileges:      []string{},
+	}
+	c.JSON(http.StatusOK, role)
+}
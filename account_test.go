@@ -0,0 +1,179 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func accountRequest(router *gin.Engine, method, path, body, username, password string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(method, path, strings.NewReader(body))
+	request.SetBasicAuth(username, password)
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func TestReadOnlyAccountIsForbiddenToChangeState(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	config := defaultConfig()
+	config.Accounts = []AccountConfig{
+		{Username: "viewer", Password: "viewer-pass", Role: "ReadOnly"},
+		{Username: "operator", Password: "operator-pass", Role: "Operator"},
+	}
+	bmc := newTestBMC(t, config)
+	router := newRouter(bmc)
+
+	changes := []struct{ method, path, body string }{
+		{http.MethodPatch, "/redfish/v1/Systems/1", `{"Boot":{"BootSourceOverrideTarget":"Pxe"}}`},
+		{http.MethodPost, "/redfish/v1/Systems/1/Actions/ComputerSystem.Reset", `{"ResetType":"ForceOff"}`},
+		{http.MethodPost, "/redfish/v1/Managers/1/VirtualMedia/CD/Actions/VirtualMedia.InsertMedia", `{"Image":"http://example.test/os.iso"}`},
+		{http.MethodPost, "/redfish/v1/UpdateService/Actions/UpdateService.SimpleUpdate", `{"ImageURI":"http://example.test/bios.bin"}`},
+	}
+	for _, change := range changes {
+		recorder := accountRequest(router, change.method, change.path, change.body, "viewer", "viewer-pass")
+		if recorder.Code != http.StatusForbidden || !strings.Contains(recorder.Body.String(), "InsufficientPrivilege") {
+			t.Errorf("ReadOnly %s %s status = %d, body = %s", change.method, change.path, recorder.Code, recorder.Body.String())
+		}
+	}
+	if recorder := accountRequest(router, http.MethodGet, "/redfish/v1/Systems/1", "", "viewer", "viewer-pass"); recorder.Code != http.StatusOK {
+		t.Fatalf("ReadOnly GET status = %d", recorder.Code)
+	}
+	if recorder := accountRequest(router, http.MethodPost, changes[1].path, changes[1].body, "operator", "operator-pass"); recorder.Code != http.StatusNoContent {
+		t.Fatalf("Operator reset status = %d, body = %s", recorder.Code, recorder.Body.String())
+	}
+	if recorder := accountRequest(router, http.MethodPost, "/redfish/v1/AccountService/Accounts", `{"UserName":"x","Password":"long-enough","RoleId":"ReadOnly"}`, "operator", "operator-pass"); recorder.Code != http.StatusForbidden {
+		t.Fatalf("Operator create account status = %d", recorder.Code)
+	}
+
+	// ReadOnly users may still change their own password.
+	recorder := accountRequest(router, http.MethodPatch, "/redfish/v1/AccountService/Accounts/2", `{"Password":"new-viewer-pass"}`, "viewer", "viewer-pass")
+	if recorder.Code != http.StatusNoContent {
+		t.Fatalf("self password change status = %d, body = %s", recorder.Code, recorder.Body.String())
+	}
+	if recorder := accountRequest(router, http.MethodPatch, "/redfish/v1/AccountService/Accounts/2", `{"RoleId":"Administrator"}`, "viewer", "new-viewer-pass"); recorder.Code != http.StatusForbidden {
+		t.Fatalf("self role change status = %d", recorder.Code)
+	}
+}
+
+func TestAccountLifecycleAndPasswordPolicy(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	bmc := newTestBMC(t, defaultConfig())
+	router := newRouter(bmc)
+	admin, password := bmc.config.Authentication.Username, bmc.config.Authentication.Password
+
+	recorder := accountRequest(router, http.MethodPost, "/redfish/v1/AccountService/Accounts", `{"UserName":"ops","Password":"short","RoleId":"Operator"}`, admin, password)
+	if recorder.Code != http.StatusBadRequest || !strings.Contains(recorder.Body.String(), "PropertyValueFormatError") {
+		t.Fatalf("short password status = %d, body = %s", recorder.Code, recorder.Body.String())
+	}
+	recorder = accountRequest(router, http.MethodPost, "/redfish/v1/AccountService/Accounts", `{"UserName":"ops","Password":"ops-password","RoleId":"Operator"}`, admin, password)
+	if recorder.Code != http.StatusCreated || recorder.Header().Get("Location") != "/redfish/v1/AccountService/Accounts/2" {
+		t.Fatalf("create account status = %d, location = %q", recorder.Code, recorder.Header().Get("Location"))
+	}
+	if recorder := accountRequest(router, http.MethodPost, "/redfish/v1/AccountService/Accounts", `{"UserName":"ops","Password":"ops-password","RoleId":"Operator"}`, admin, password); recorder.Code != http.StatusConflict {
+		t.Fatalf("duplicate account status = %d", recorder.Code)
+	}
+	if recorder := accountRequest(router, http.MethodGet, "/redfish/v1/Systems", "", "ops", "ops-password"); recorder.Code != http.StatusOK {
+		t.Fatalf("new account GET status = %d", recorder.Code)
+	}
+	if recorder := accountRequest(router, http.MethodDelete, "/redfish/v1/AccountService/Accounts/1", "", admin, password); recorder.Code != http.StatusBadRequest {
+		t.Fatalf("delete last administrator status = %d", recorder.Code)
+	}
+	if recorder := accountRequest(router, http.MethodDelete, "/redfish/v1/AccountService/Accounts/2", "", admin, password); recorder.Code != http.StatusNoContent {
+		t.Fatalf("delete account status = %d", recorder.Code)
+	}
+	if recorder := accountRequest(router, http.MethodGet, "/redfish/v1/Systems", "", "ops", "ops-password"); recorder.Code != http.StatusUnauthorized {
+		t.Fatalf("deleted account GET status = %d", recorder.Code)
+	}
+}
+
+func TestAccountLockout(t *testing.T) {
+	policy := AccountServiceConfig{MinPasswordLength: 8, MaxPasswordLength: 20, AccountLockoutThreshold: 3, AccountLockoutDuration: 60, AccountLockoutCounterResetAfter: 60}
+	store := newAccountStore(AuthenticationConfig{Username: "admin", Password: "password"}, nil, policy)
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if _, err := store.authenticate("admin", "wrong", start); err != errBadCredentials {
+			t.Fatalf("attempt %d error = %v", i, err)
+		}
+	}
+	if _, err := store.authenticate("admin", "password", start.Add(time.Second)); err != errAccountLocked {
+		t.Fatalf("locked account error = %v, want %v", err, errAccountLocked)
+	}
+	if _, err := store.authenticate("admin", "password", start.Add(61*time.Second)); err != nil {
+		t.Fatalf("after lockout error = %v", err)
+	}
+}
+
+func TestLockedAccountUntilAdministratorUnlock(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	config := defaultConfig()
+	config.AccountService.AccountLockoutThreshold = 2
+	config.AccountService.AccountLockoutDuration = 0
+	config.Accounts = []AccountConfig{{Username: "agent", Password: "agent-pass", Role: "Operator"}}
+	bmc := newTestBMC(t, config)
+	router := newRouter(bmc)
+	admin, password := bmc.config.Authentication.Username, bmc.config.Authentication.Password
+
+	for i := 0; i < 2; i++ {
+		if recorder := accountRequest(router, http.MethodGet, "/redfish/v1/Systems", "", "agent", "wrong"); recorder.Code != http.StatusUnauthorized {
+			t.Fatalf("bad password status = %d", recorder.Code)
+		}
+	}
+	recorder := accountRequest(router, http.MethodGet, "/redfish/v1/Systems", "", "agent", "agent-pass")
+	if recorder.Code != http.StatusUnauthorized || !strings.Contains(recorder.Body.String(), "locked") {
+		t.Fatalf("locked account status = %d, body = %s", recorder.Code, recorder.Body.String())
+	}
+	request := httptest.NewRequest(http.MethodPost, "/redfish/v1/SessionService/Sessions", strings.NewReader(`{"UserName":"agent","Password":"agent-pass"}`))
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusUnauthorized || !strings.Contains(recorder.Body.String(), "locked") {
+		t.Fatalf("locked session login status = %d, body = %s", recorder.Code, recorder.Body.String())
+	}
+	if recorder := accountRequest(router, http.MethodGet, "/redfish/v1/AccountService/Accounts/2", "", admin, password); !strings.Contains(recorder.Body.String(), `"Locked":true`) {
+		t.Fatalf("account = %s, want Locked", recorder.Body.String())
+	}
+
+	if recorder := accountRequest(router, http.MethodPatch, "/redfish/v1/AccountService/Accounts/2", `{"Locked":false}`, admin, password); recorder.Code != http.StatusNoContent {
+		t.Fatalf("unlock status = %d", recorder.Code)
+	}
+	if recorder := accountRequest(router, http.MethodGet, "/redfish/v1/Systems", "", "agent", "agent-pass"); recorder.Code != http.StatusOK {
+		t.Fatalf("unlocked account status = %d", recorder.Code)
+	}
+}
+
+func TestPasswordChangeRequiredUntilPasswordIsPatched(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	config := defaultConfig()
+	config.Authentication.PasswordChangeRequired = true
+	bmc := newTestBMC(t, config)
+	router := newRouter(bmc)
+	admin, password := bmc.config.Authentication.Username, bmc.config.Authentication.Password
+
+	recorder := accountRequest(router, http.MethodGet, "/redfish/v1/Systems", "", admin, password)
+	if recorder.Code != http.StatusForbidden || !strings.Contains(recorder.Body.String(), "PasswordChangeRequired") ||
+		!strings.Contains(recorder.Body.String(), "/redfish/v1/AccountService/Accounts/1") {
+		t.Fatalf("restricted GET status = %d, body = %s", recorder.Code, recorder.Body.String())
+	}
+	request := httptest.NewRequest(http.MethodPost, "/redfish/v1/SessionService/Sessions", strings.NewReader(`{"UserName":"admin","Password":"password"}`))
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusCreated || !strings.Contains(recorder.Body.String(), "PasswordChangeRequired") {
+		t.Fatalf("session login status = %d, body = %s", recorder.Code, recorder.Body.String())
+	}
+	if recorder := accountRequest(router, http.MethodGet, "/redfish/v1/AccountService/Accounts/1", "", admin, password); recorder.Code != http.StatusOK || !strings.Contains(recorder.Body.String(), `"PasswordChangeRequired":true`) {
+		t.Fatalf("own account status = %d, body = %s", recorder.Code, recorder.Body.String())
+	}
+	if recorder := accountRequest(router, http.MethodPatch, "/redfish/v1/AccountService/Accounts/1", `{"Password":"password"}`, admin, password); recorder.Code != http.StatusBadRequest {
+		t.Fatalf("unchanged password status = %d", recorder.Code)
+	}
+	if recorder := accountRequest(router, http.MethodPatch, "/redfish/v1/AccountService/Accounts/1", `{"Password":"new-password"}`, admin, password); recorder.Code != http.StatusNoContent {
+		t.Fatalf("password change status = %d, body = %s", recorder.Code, recorder.Body.String())
+	}
+	if recorder := accountRequest(router, http.MethodGet, "/redfish/v1/Systems", "", admin, "new-password"); recorder.Code != http.StatusOK {
+		t.Fatalf("after password change status = %d", recorder.Code)
+	}
+}
@@ -0,0 +1,378 @@
+package main
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MockAPIConfig sets separate Basic credentials for the /mock control
+// endpoints. Left empty, those endpoints accept the Redfish accounts and
+// sessions instead.
+type MockAPIConfig struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// AdminState is the full mutable state of a mock BMC as served by
+// GET /mock/admin/state.
+type AdminState struct {
+	Time               string                      `json:"time"`
+	ClockOffsetSeconds float64                     `json:"clock_offset_seconds"`
+	Systems            map[string]AdminSystemState `json:"systems"`
+	Firmware           map[string]AdminFirmware    `json:"firmware"`
+	Accounts           []AdminAccount              `json:"accounts"`
+	Sessions           []AdminSession              `json:"sessions"`
+	Tasks              []Task                      `json:"tasks"`
+	Subscriptions      []AdminSubscription         `json:"subscriptions"`
+	Faults             []Fault                     `json:"faults"`
+	LogEntries         map[string]int              `json:"log_entries"`
+}
+
+type AdminSystemState struct {
+	PowerState                string            `json:"power_state"`
+	BootSourceOverrideEnabled string            `json:"boot_source_override_enabled"`
+	BootSourceOverrideTarget  string            `json:"boot_source_override_target"`
+	BootSourceOverrideMode    string            `json:"boot_source_override_mode"`
+	InstallationStatus        string            `json:"installation_status"`
+	VirtualMedia              AdminVirtualMedia `json:"virtual_media"`
+	PowerTransitions          []AdminTransition `json:"power_transitions"`
+}
+
+type AdminVirtualMedia struct {
+	Image          string `json:"image"`
+	Inserted       bool   `json:"inserted"`
+	WriteProtected bool   `json:"write_protected"`
+}
+
+type AdminTransition struct {
+	State string `json:"state"`
+	At    string `json:"at"`
+}
+
+type AdminFirmware struct {
+	Version    string `json:"version"`
+	SoftwareID string `json:"software_id"`
+}
+
+type AdminAccount struct {
+	ID                     string `json:"id"`
+	Username               string `json:"username"`
+	Role                   string `json:"role"`
+	Enabled                bool   `json:"enabled"`
+	Locked                 bool   `json:"locked"`
+	PasswordChangeRequired bool   `json:"password_change_required"`
+	FailedLogins           int    `json:"failed_logins"`
+}
+
+type AdminSession struct {
+	ID        string `json:"id"`
+	Username  string `json:"username"`
+	CreatedAt string `json:"created_at"`
+	LastUsed  string `json:"last_used"`
+}
+
+type AdminSubscription struct {
+	ID          string `json:"id"`
+	Destination string `json:"destination"`
+	Context     string `json:"context"`
+}
+
+// AdminStatePatch overwrites state fields directly. Unlike the Redfish
+// operations it replaces, it neither logs nor sends events.
+type AdminStatePatch struct {
+	Systems  map[string]AdminSystemPatch `json:"systems"`
+	Firmware map[string]string           `json:"firmware"`
+}
+
+type AdminSystemPatch struct {
+	PowerState                *string                 `json:"power_state"`
+	BootSourceOverrideEnabled *string                 `json:"boot_source_override_enabled"`
+	BootSourceOverrideTarget  *string                 `json:"boot_source_override_target"`
+	BootSourceOverrideMode    *string                 `json:"boot_source_override_mode"`
+	InstallationStatus        *string                 `json:"installation_status"`
+	VirtualMedia              *AdminVirtualMediaPatch `json:"virtual_media"`
+}
+
+type AdminVirtualMediaPatch struct {
+	Image          *string `json:"image"`
+	Inserted       *bool   `json:"inserted"`
+	WriteProtected *bool   `json:"write_protected"`
+}
+
+type AdminClock struct {
+	Time          string  `json:"time"`
+	OffsetSeconds float64 `json:"offset_seconds"`
+	Frozen        bool    `json:"frozen"`
+}
+
+type AdminClockRequest struct {
+	Frozen         *bool    `json:"frozen"`
+	AdvanceSeconds *float64 `json:"advance_seconds"`
+}
+
+// requireMockAPI authenticates the /mock control endpoints. With mock_api
+// credentials configured, only those are accepted and they carry every
+// privilege; otherwise the Redfish accounts and sessions apply.
+func (b *mockBMC) requireMockAPI() gin.HandlerFunc {
+	credentials := b.config.MockAPI
+	if credentials.Username == "" {
+		return b.requireAuth()
+	}
+	return func(c *gin.Context) {
+		username, password, ok := c.Request.BasicAuth()
+		if !ok || subtle.ConstantTimeCompare([]byte(username), []byte(credentials.Username)) != 1 ||
+			subtle.ConstantTimeCompare([]byte(password), []byte(credentials.Password)) != 1 {
+			c.Header("WWW-Authenticate", `Basic realm="Mock API"`)
+			redfishError(c, http.StatusUnauthorized, baseMessage("NoValidSession"))
+			return
+		}
+		c.Set(accountRoleKey, "Administrator")
+	}
+}
+
+// reset returns the BMC to the state it had right after loading its config.
+// The served HTTPS certificate is kept.
+func (b *mockBMC) reset() {
+	b.clock.reset()
+	now := b.now()
+	for _, system := range b.config.Systems {
+		state := b.systemStates[system.ID]
+		state.Lock()
+		state.reset(system)
+		state.Unlock()
+	}
+	b.accounts.reset(b.config.Authentication, b.config.Accounts, b.config.AccountService)
+	b.sessions.reset(b.config.SessionService.SessionTimeout)
+	b.tasks.reset()
+	b.firmware.reset()
+	b.events.reset(b.config.EventService)
+	for _, log := range b.systemLogs {
+		log.reset()
+	}
+	for _, log := range b.managerLogs {
+		log.reset()
+	}
+	for _, drift := range b.sensorDrifts {
+		drift.reset(now)
+	}
+	b.faults.reset()
+	b.chaos.set(b.config.Chaos)
+	b.mockup.reset()
+	// The configured faults were accepted by newMockBMC and the fault store
+	// is empty again, so injecting them cannot fail.
+	for _, fault := range b.startFaults {
+		b.injectResolvedFault(fault.config, fault.resource, now)
+	}
+}
+
+func (b *mockBMC) adminState() AdminState {
+	now := b.now()
+	state := AdminState{
+		Time:               now.UTC().Format(time.RFC3339),
+		ClockOffsetSeconds: b.clock.offsetSeconds(),
+		Systems:            make(map[string]AdminSystemState, len(b.config.Systems)),
+		Firmware:           map[string]AdminFirmware{},
+		Accounts:           []AdminAccount{},
+		Sessions:           []AdminSession{},
+		Tasks:              []Task{},
+		Subscriptions:      []AdminSubscription{},
+		Faults:             []Fault{},
+		LogEntries:         map[string]int{},
+	}
+	for _, system := range b.config.Systems {
+		systemState := b.systemStates[system.ID]
+		systemState.Lock()
+		systemState.advancePower(now)
+		b.flushPowerEvents(systemState, system.ID, now)
+		b.advanceInstallation(systemState, system.ID, now)
+		transitions := make([]AdminTransition, 0, len(systemState.powerTransitions))
+		for _, transition := range systemState.powerTransitions {
+			transitions = append(transitions, AdminTransition{State: transition.state, At: transition.at.UTC().Format(time.RFC3339)})
+		}
+		state.Systems[system.ID] = AdminSystemState{
+			PowerState:                systemState.powerState,
+			BootSourceOverrideEnabled: systemState.bootSourceOverrideEnabled,
+			BootSourceOverrideTarget:  systemState.bootSourceOverrideTarget,
+			BootSourceOverrideMode:    systemState.bootSourceOverrideMode,
+			InstallationStatus:        systemState.installationStatus,
+			VirtualMedia: AdminVirtualMedia{
+				Image:          systemState.image,
+				Inserted:       systemState.inserted,
+				WriteProtected: systemState.writeProtected,
+			},
+			PowerTransitions: transitions,
+		}
+		systemState.Unlock()
+		state.LogEntries["/redfish/v1/Systems/"+system.ID+"/LogServices/"+b.systemLogs[system.ID].id] = len(b.systemLogs[system.ID].list())
+	}
+	for _, manager := range b.config.Managers {
+		state.LogEntries["/redfish/v1/Managers/"+manager.ID+"/LogServices/"+b.managerLogs[manager.ID].id] = len(b.managerLogs[manager.ID].list())
+	}
+	for _, item := range b.firmware.current() {
+		state.Firmware[item.ID] = AdminFirmware{Version: item.Version, SoftwareID: item.SoftwareID}
+	}
+	for _, account := range b.accounts.list() {
+		state.Accounts = append(state.Accounts, AdminAccount{
+			ID:                     account.id,
+			Username:               account.username,
+			Role:                   account.roleID,
+			Enabled:                account.enabled,
+			Locked:                 account.locked(now),
+			PasswordChangeRequired: account.passwordChangeRequired,
+			FailedLogins:           account.failures,
+		})
+	}
+	for _, session := range b.sessions.list(now) {
+		state.Sessions = append(state.Sessions, AdminSession{
+			ID:        session.id,
+			Username:  session.username,
+			CreatedAt: session.createdAt.UTC().Format(time.RFC3339),
+			LastUsed:  session.lastUsed.UTC().Format(time.RFC3339),
+		})
+	}
+	for _, id := range b.tasks.list() {
+		if task, ok := b.tasks.get(id, now); ok {
+			state.Tasks = append(state.Tasks, task)
+		}
+	}
+	for _, id := range b.events.list() {
+		if subscription, ok := b.events.get(id); ok {
+			state.Subscriptions = append(state.Subscriptions, AdminSubscription{ID: id, Destination: subscription.destination, Context: subscription.context})
+		}
+	}
+	for _, fault := range b.faults.list() {
+		state.Faults = append(state.Faults, faultResource(fault))
+	}
+	return state
+}
+
+func (b *mockBMC) getAdminState(c *gin.Context) {
+	c.JSON(http.StatusOK, b.adminState())
+}
+
+// checkAdminValue writes PropertyValueNotInList unless value is allowed.
+func checkAdminValue(c *gin.Context, value *string, property string, allowed ...string) bool {
+	if value == nil || slices.Contains(allowed, *value) {
+		return true
+	}
+	redfishError(c, http.StatusBadRequest, baseMessage("PropertyValueNotInList", *value, property))
+	return false
+}
+
+// patchAdminState checks the whole patch before changing anything, so a
+// rejected patch leaves the state as it was.
+func (b *mockBMC) patchAdminState(c *gin.Context) {
+	var req AdminStatePatch
+	if !bindRedfishJSON(c, &req) {
+		return
+	}
+	for id, patch := range req.Systems {
+		if _, ok := b.findSystem(id); !ok {
+			redfishError(c, http.StatusBadRequest, baseMessage("PropertyValueNotInList", id, "systems"))
+			return
+		}
+		if !checkAdminValue(c, patch.PowerState, "power_state", "On", "Off") ||
+			!checkAdminValue(c, patch.BootSourceOverrideEnabled, "boot_source_override_enabled", "Disabled", "Once", "Continuous") ||
+			!checkAdminValue(c, patch.BootSourceOverrideTarget, "boot_source_override_target", "None", "Cd", "Hdd", "Pxe", "Usb") ||
+			!checkAdminValue(c, patch.BootSourceOverrideMode, "boot_source_override_mode", "UEFI", "Legacy") ||
+			!checkAdminValue(c, patch.InstallationStatus, "installation_status", "Ready", "MediaMounted", "Installing", "Installed", "InstallFailed") {
+			return
+		}
+	}
+	for id, version := range req.Firmware {
+		if _, ok := b.firmware.find(id); !ok {
+			redfishError(c, http.StatusBadRequest, baseMessage("PropertyValueNotInList", id, "firmware"))
+			return
+		}
+		if version == "" {
+			redfishError(c, http.StatusBadRequest, baseMessage("PropertyValueFormatError", version, "firmware/"+id))
+			return
+		}
+	}
+
+	now := b.now()
+	for id, patch := range req.Systems {
+		state := b.systemStates[id]
+		state.Lock()
+		if patch.PowerState != nil {
+			state.startPowerSequence(now, powerStep{*patch.PowerState, 0})
+		}
+		if patch.BootSourceOverrideEnabled != nil {
+			state.bootSourceOverrideEnabled = *patch.BootSourceOverrideEnabled
+		}
+		if patch.BootSourceOverrideTarget != nil {
+			state.bootSourceOverrideTarget = *patch.BootSourceOverrideTarget
+		}
+		if patch.BootSourceOverrideMode != nil {
+			state.bootSourceOverrideMode = *patch.BootSourceOverrideMode
+		}
+		if media := patch.VirtualMedia; media != nil {
+			if media.Image != nil {
+				state.image = *media.Image
+			}
+			if media.Inserted != nil {
+				state.inserted = *media.Inserted
+			}
+			if media.WriteProtected != nil {
+				state.writeProtected = *media.WriteProtected
+			}
+		}
+		if patch.InstallationStatus != nil && *patch.InstallationStatus != state.installationStatus {
+			state.installationStatus = *patch.InstallationStatus
+			if state.installationStatus == "Installing" {
+				state.installationStartedAt = now
+				b.scheduleInstallation(state, id)
+			}
+		}
+		state.Unlock()
+	}
+	for id, version := range req.Firmware {
+		b.firmware.apply(firmwareUpdate{targets: []string{id}, version: version})
+	}
+	c.JSON(http.StatusOK, b.adminState())
+}
+
+func (b *mockBMC) resetAdminState(c *gin.Context) {
+	b.reset()
+	c.Status(http.StatusNoContent)
+}
+
+func (b *mockBMC) adminClock() AdminClock {
+	return AdminClock{Time: b.now().UTC().Format(time.RFC3339), OffsetSeconds: b.clock.offsetSeconds(), Frozen: b.clock.isFrozen()}
+}
+
+func (b *mockBMC) getAdminClock(c *gin.Context) {
+	c.JSON(http.StatusOK, b.adminClock())
+}
+
+// setAdminClock freezes or resumes simulated time and steps it forward. A
+// freeze applies before the step, so one request can stop the clock at a
+// known offset. Time cannot move backward, so a deadline that has passed
+// stays passed; the power transitions, installations, and firmware updates
+// that become due complete before the response is sent.
+func (b *mockBMC) setAdminClock(c *gin.Context) {
+	var req AdminClockRequest
+	if !bindRedfishJSON(c, &req) {
+		return
+	}
+	if req.Frozen == nil && req.AdvanceSeconds == nil {
+		redfishError(c, http.StatusBadRequest, baseMessage("PropertyMissing", "advance_seconds"))
+		return
+	}
+	if req.AdvanceSeconds != nil && *req.AdvanceSeconds <= 0 {
+		redfishError(c, http.StatusBadRequest, baseMessage("PropertyValueOutOfRange", strconv.FormatFloat(*req.AdvanceSeconds, 'f', -1, 64), "advance_seconds"))
+		return
+	}
+	if req.Frozen != nil {
+		b.clock.setFrozen(*req.Frozen)
+	}
+	if req.AdvanceSeconds != nil {
+		b.clock.advance(time.Duration(*req.AdvanceSeconds * float64(time.Second)))
+	}
+	c.JSON(http.StatusOK, b.adminClock())
+}
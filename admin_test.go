@@ -0,0 +1,108 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestAdminStateOverrideAndReset(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	config := defaultConfig()
+	config.MockAPI = MockAPIConfig{Username: "ci", Password: "harness"}
+	bmc := newTestBMC(t, config)
+	router := newRouter(bmc)
+	admin, password := bmc.config.Authentication.Username, bmc.config.Authentication.Password
+
+	if recorder := accountRequest(router, http.MethodGet, "/mock/admin/state", "", admin, password); recorder.Code != http.StatusUnauthorized {
+		t.Fatalf("Redfish credentials on the mock API status = %d", recorder.Code)
+	}
+	recorder := accountRequest(router, http.MethodPatch, "/mock/admin/state",
+		`{"systems":{"1":{"power_state":"Sleeping"}}}`, "ci", "harness")
+	if recorder.Code != http.StatusBadRequest {
+		t.Fatalf("bad power_state status = %d", recorder.Code)
+	}
+	recorder = accountRequest(router, http.MethodPatch, "/mock/admin/state",
+		`{"systems":{"1":{"power_state":"Off","installation_status":"Installed","virtual_media":{"image":"http://example.test/os.iso","inserted":true}}},"firmware":{"BIOS":"9.9.9"}}`, "ci", "harness")
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("patch state status = %d, body = %s", recorder.Code, recorder.Body.String())
+	}
+	var state AdminState
+	if err := json.Unmarshal(recorder.Body.Bytes(), &state); err != nil {
+		t.Fatal(err)
+	}
+	system := state.Systems["1"]
+	if system.PowerState != "Off" || system.InstallationStatus != "Installed" || !system.VirtualMedia.Inserted || state.Firmware["BIOS"].Version != "9.9.9" {
+		t.Fatalf("patched state = %+v", state)
+	}
+	var computerSystem ComputerSystem
+	recorder = accountRequest(router, http.MethodGet, "/redfish/v1/Systems/1", "", admin, password)
+	if err := json.Unmarshal(recorder.Body.Bytes(), &computerSystem); err != nil {
+		t.Fatal(err)
+	}
+	if computerSystem.PowerState != "Off" {
+		t.Fatalf("system PowerState = %q", computerSystem.PowerState)
+	}
+
+	if recorder := accountRequest(router, http.MethodPost, "/mock/admin/reset", "", "ci", "harness"); recorder.Code != http.StatusNoContent {
+		t.Fatalf("reset status = %d", recorder.Code)
+	}
+	state = AdminState{}
+	recorder = accountRequest(router, http.MethodGet, "/mock/admin/state", "", "ci", "harness")
+	if err := json.Unmarshal(recorder.Body.Bytes(), &state); err != nil {
+		t.Fatal(err)
+	}
+	system = state.Systems["1"]
+	if system.PowerState != "On" || system.InstallationStatus != "Ready" || system.VirtualMedia.Inserted || state.Firmware["BIOS"].Version != "1.0.0" ||
+		state.LogEntries["/redfish/v1/Systems/1/LogServices/SEL"] != 0 {
+		t.Fatalf("state after reset = %+v", state)
+	}
+}
+
+func TestAdminClockCompletesPowerTransitions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	config := defaultConfig()
+	config.Systems[0].PowerOffDelaySeconds = 600
+	config.Accounts = []AccountConfig{{Username: "viewer", Password: "password1", Role: "ReadOnly"}}
+	bmc := newTestBMC(t, config)
+	router := newRouter(bmc)
+	admin, password := bmc.config.Authentication.Username, bmc.config.Authentication.Password
+
+	if recorder := accountRequest(router, http.MethodPost, "/redfish/v1/Systems/1/Actions/ComputerSystem.Reset", `{"ResetType":"GracefulShutdown"}`, admin, password); recorder.Code != http.StatusNoContent {
+		t.Fatalf("reset status = %d", recorder.Code)
+	}
+	if recorder := accountRequest(router, http.MethodPost, "/mock/admin/clock", `{"advance_seconds":0}`, admin, password); recorder.Code != http.StatusBadRequest {
+		t.Fatalf("zero advance status = %d", recorder.Code)
+	}
+	recorder := accountRequest(router, http.MethodPost, "/mock/admin/clock", `{"advance_seconds":600}`, admin, password)
+	var clock AdminClock
+	if err := json.Unmarshal(recorder.Body.Bytes(), &clock); err != nil {
+		t.Fatalf("decode clock: %v (%s)", err, recorder.Body.String())
+	}
+	if clock.OffsetSeconds != 600 {
+		t.Fatalf("clock = %+v", clock)
+	}
+
+	var state AdminState
+	recorder = accountRequest(router, http.MethodGet, "/mock/admin/state", "", admin, password)
+	if err := json.Unmarshal(recorder.Body.Bytes(), &state); err != nil {
+		t.Fatal(err)
+	}
+	if state.Systems["1"].PowerState != "Off" || state.LogEntries["/redfish/v1/Systems/1/LogServices/SEL"] != 2 {
+		t.Fatalf("state after advancing = %+v", state)
+	}
+	var entry LogEntry
+	recorder = accountRequest(router, http.MethodGet, "/redfish/v1/Systems/1/LogServices/SEL/Entries/2", "", admin, password)
+	if err := json.Unmarshal(recorder.Body.Bytes(), &entry); err != nil {
+		t.Fatal(err)
+	}
+	if entry.MessageID != "ResourceEvent.1.3.0.ResourcePoweredOff" {
+		t.Fatalf("entry = %+v", entry)
+	}
+
+	if recorder := accountRequest(router, http.MethodPost, "/mock/admin/reset", "", "viewer", "password1"); recorder.Code != http.StatusForbidden {
+		t.Fatalf("reset by a ReadOnly account status = %d", recorder.Code)
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// mockBMC holds everything one simulated BMC serves, so a single process can
+// run many independent BMCs side by side.
+type mockBMC struct {
+	config       Config
+	clock        *mockClock
+	oem          oemBehavior
+	systemStates map[string]*mockServerState
+	accounts     *accountStore
+	sessions     *sessionStore
+	tasks        *taskStore
+	firmware     *firmwareState
+	certificates *certificateStore
+	events       *eventService
+	systemLogs   map[string]*logService
+	managerLogs  map[string]*logService
+	sensorDrifts map[string]*sensorDrift
+	faults       *faultStore
+	startFaults  []configuredFault
+	chaos        *chaosInjector
+	recorder     *recorder
+	mockup       *mockupStore
+}
+
+// newMockBMC builds a BMC from a loaded config. It fails when a file the
+// config names cannot be used or a configured fault cannot be injected.
+func newMockBMC(config Config) (*mockBMC, error) {
+	behavior, err := oemBehaviorFor(config.OEM)
+	if err != nil {
+		return nil, err
+	}
+	certificate, err := selfSignedCertificate("localhost", time.Now())
+	if err != nil {
+		return nil, err
+	}
+	recorder, err := newRecorder(config.Recording, config.Authentication)
+	if err != nil {
+		return nil, fmt.Errorf("recording: %w", err)
+	}
+	mockup, err := newMockupStore(config.Mockup)
+	if err != nil {
+		return nil, fmt.Errorf("mockup: %w", err)
+	}
+	clock := newMockClock(config.Clock)
+	b := &mockBMC{
+		config:       config,
+		clock:        clock,
+		oem:          behavior,
+		systemStates: newSystemStates(config.Systems),
+		accounts:     newAccountStore(config.Authentication, config.Accounts, config.AccountService),
+		sessions:     newSessionStore(config.SessionService.SessionTimeout),
+		tasks:        newTaskStore(clock),
+		firmware:     newFirmwareState(config.Firmware),
+		certificates: newCertificateStore(certificate),
+		events:       newEventService(config.EventService, clock),
+		systemLogs:   newSystemLogs(config.Systems),
+		managerLogs:  newManagerLogs(config.Managers),
+		sensorDrifts: newSensorDrifts(config.ChassisMembers, clock.now()),
+		faults:       newFaultStore(),
+		chaos:        newChaosInjector(config.Chaos),
+		recorder:     recorder,
+		mockup:       mockup,
+	}
+	for i, fault := range config.Faults {
+		resolved, resource, err := resolveFault(config, fault)
+		if err == nil {
+			_, err = b.injectResolvedFault(resolved, resource, clock.now())
+		}
+		if err != nil {
+			return nil, fmt.Errorf("faults[%d]: %w", i, err)
+		}
+		b.startFaults = append(b.startFaults, configuredFault{resolved, resource})
+	}
+	return b, nil
+}
+
+// now returns the BMC's simulated time.
+func (b *mockBMC) now() time.Time {
+	return b.clock.now()
+}
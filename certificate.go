@@ -0,0 +1,549 @@
+package main
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CertificateService struct {
+	ODataContext         string                    `json:"@odata.context"`
+	ODataType            string                    `json:"@odata.type"`
+	ODataID              string                    `json:"@odata.id"`
+	ID                   string                    `json:"Id"`
+	Name                 string                    `json:"Name"`
+	Actions              CertificateServiceActions `json:"Actions"`
+	CertificateLocations Link                      `json:"CertificateLocations"`
+}
+
+type CertificateServiceActions struct {
+	GenerateCSR        GenerateCSRAction        `json:"#CertificateService.GenerateCSR"`
+	ReplaceCertificate ReplaceCertificateAction `json:"#CertificateService.ReplaceCertificate"`
+}
+
+type GenerateCSRAction struct {
+	Target            string   `json:"target"`
+	KeyPairAlgorithms []string `json:"KeyPairAlgorithm@Redfish.AllowableValues"`
+	KeyCurveIDs       []string `json:"KeyCurveId@Redfish.AllowableValues"`
+	KeyBitLengths     []int    `json:"KeyBitLength@Redfish.AllowableValues"`
+}
+
+type ReplaceCertificateAction struct {
+	Target           string   `json:"target"`
+	CertificateTypes []string `json:"CertificateType@Redfish.AllowableValues"`
+}
+
+type CertificateLocations struct {
+	ODataContext string                    `json:"@odata.context"`
+	ODataType    string                    `json:"@odata.type"`
+	ODataID      string                    `json:"@odata.id"`
+	ID           string                    `json:"Id"`
+	Name         string                    `json:"Name"`
+	Links        CertificateLocationsLinks `json:"Links"`
+}
+
+type CertificateLocationsLinks struct {
+	Certificates      []Link `json:"Certificates"`
+	CertificatesCount int    `json:"Certificates@odata.count"`
+}
+
+type ManagerNetworkProtocol struct {
+	ODataContext string        `json:"@odata.context"`
+	ODataType    string        `json:"@odata.type"`
+	ODataID      string        `json:"@odata.id"`
+	ID           string        `json:"Id"`
+	Name         string        `json:"Name"`
+	HTTPS        HTTPSProtocol `json:"HTTPS"`
+	Status       Status        `json:"Status"`
+}
+
+type HTTPSProtocol struct {
+	ProtocolEnabled bool `json:"ProtocolEnabled"`
+	Certificates    Link `json:"Certificates"`
+}
+
+type Certificate struct {
+	ODataContext             string                `json:"@odata.context"`
+	ODataType                string                `json:"@odata.type"`
+	ODataID                  string                `json:"@odata.id"`
+	ID                       string                `json:"Id"`
+	Name                     string                `json:"Name"`
+	CertificateString        string                `json:"CertificateString"`
+	CertificateType          string                `json:"CertificateType"`
+	CertificateUsageTypes    []string              `json:"CertificateUsageTypes"`
+	Issuer                   CertificateIdentifier `json:"Issuer"`
+	Subject                  CertificateIdentifier `json:"Subject"`
+	ValidNotBefore           string                `json:"ValidNotBefore"`
+	ValidNotAfter            string                `json:"ValidNotAfter"`
+	SerialNumber             string                `json:"SerialNumber"`
+	Fingerprint              string                `json:"Fingerprint"`
+	FingerprintHashAlgorithm string                `json:"FingerprintHashAlgorithm"`
+	KeyUsage                 []string              `json:"KeyUsage"`
+}
+
+type CertificateIdentifier struct {
+	CommonName         string `json:"CommonName,omitempty"`
+	Organization       string `json:"Organization,omitempty"`
+	OrganizationalUnit string `json:"OrganizationalUnit,omitempty"`
+	City               string `json:"City,omitempty"`
+	State              string `json:"State,omitempty"`
+	Country            string `json:"Country,omitempty"`
+}
+
+type GenerateCSRRequest struct {
+	CertificateCollection *Link    `json:"CertificateCollection"`
+	CommonName            string   `json:"CommonName"`
+	AlternativeNames      []string `json:"AlternativeNames,omitempty"`
+	Organization          string   `json:"Organization,omitempty"`
+	OrganizationalUnit    string   `json:"OrganizationalUnit,omitempty"`
+	City                  string   `json:"City,omitempty"`
+	State                 string   `json:"State,omitempty"`
+	Country               string   `json:"Country,omitempty"`
+	KeyPairAlgorithm      string   `json:"KeyPairAlgorithm,omitempty"`
+	KeyCurveID            string   `json:"KeyCurveId,omitempty"`
+	KeyBitLength          int      `json:"KeyBitLength,omitempty"`
+}
+
+type GenerateCSRResponse struct {
+	CSRString             string `json:"CSRString"`
+	CertificateCollection Link   `json:"CertificateCollection"`
+}
+
+type ReplaceCertificateRequest struct {
+	CertificateURI    *Link  `json:"CertificateUri"`
+	CertificateString string `json:"CertificateString"`
+	CertificateType   string `json:"CertificateType"`
+}
+
+const httpsCertificateID = "1"
+
+var (
+	csrKeyCurves     = map[string]elliptic.Curve{"TPM_ECC_NIST_P256": elliptic.P256(), "TPM_ECC_NIST_P384": elliptic.P384()}
+	csrKeyBitLengths = []int{2048, 3072, 4096}
+)
+
+// certificateStore holds the certificate served by a BMC's HTTPS listener and
+// the private key of the last CSR while it waits for a signed certificate.
+// Every manager of a BMC shares the store, because they share one listener.
+type certificateStore struct {
+	sync.RWMutex
+	current    tls.Certificate
+	pendingKey crypto.Signer
+}
+
+func newCertificateStore(certificate tls.Certificate) *certificateStore {
+	return &certificateStore{current: certificate}
+}
+
+func (s *certificateStore) certificate() tls.Certificate {
+	s.RLock()
+	defer s.RUnlock()
+	return s.current
+}
+
+func (s *certificateStore) install(certificate tls.Certificate) {
+	s.Lock()
+	defer s.Unlock()
+	s.current = certificate
+}
+
+// tlsConfig looks up the served certificate on every handshake, so a
+// ReplaceCertificate takes effect for new connections without a restart.
+func (s *certificateStore) tlsConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			certificate := s.certificate()
+			return &certificate, nil
+		},
+	}
+}
+
+// generateCSR creates a key pair and a PEM certificate signing request for it.
+// Every CSR gets a new key, because the request chooses the key algorithm and
+// size. Like a real BMC, the store keeps only the key of the last CSR, until
+// a certificate for it is installed with replace.
+func (s *certificateStore) generateCSR(req GenerateCSRRequest) (string, error) {
+	var key crypto.Signer
+	var err error
+	switch req.KeyPairAlgorithm {
+	case "TPM_ALG_ECDSA":
+		curveID := req.KeyCurveID
+		if curveID == "" {
+			curveID = "TPM_ECC_NIST_P256"
+		}
+		key, err = ecdsa.GenerateKey(csrKeyCurves[curveID], rand.Reader)
+	default:
+		bits := req.KeyBitLength
+		if bits == 0 {
+			bits = 2048
+		}
+		key, err = rsa.GenerateKey(rand.Reader, bits)
+	}
+	if err != nil {
+		return "", fmt.Errorf("generate CSR key: %w", err)
+	}
+
+	optional := func(value string) []string {
+		if value == "" {
+			return nil
+		}
+		return []string{value}
+	}
+	template := &x509.CertificateRequest{
+		Subject: pkix.Name{
+			CommonName:         req.CommonName,
+			Organization:       optional(req.Organization),
+			OrganizationalUnit: optional(req.OrganizationalUnit),
+			Locality:           optional(req.City),
+			Province:           optional(req.State),
+			Country:            optional(req.Country),
+		},
+	}
+	for _, name := range append([]string{req.CommonName}, req.AlternativeNames...) {
+		if ip := net.ParseIP(name); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else if !slices.Contains(template.DNSNames, name) {
+			template.DNSNames = append(template.DNSNames, name)
+		}
+	}
+	der, err := x509.CreateCertificateRequest(rand.Reader, template, key)
+	if err != nil {
+		return "", fmt.Errorf("create CSR: %w", err)
+	}
+
+	s.Lock()
+	s.pendingKey = key
+	s.Unlock()
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: der})), nil
+}
+
+// replace installs a PEM certificate chain. The private key is taken from the
+// PEM data when it has one, otherwise from the pending CSR or the current
+// certificate with the same public key.
+func (s *certificateStore) replace(pemData string) error {
+	if strings.Contains(pemData, "PRIVATE KEY-----") {
+		certificate, err := tls.X509KeyPair([]byte(pemData), []byte(pemData))
+		if err != nil {
+			return err
+		}
+		s.install(certificate)
+		return nil
+	}
+
+	var chain [][]byte
+	rest := []byte(pemData)
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type == "CERTIFICATE" {
+			chain = append(chain, block.Bytes)
+		}
+	}
+	if len(chain) == 0 {
+		return errors.New("no PEM certificate found")
+	}
+	leaf, err := x509.ParseCertificate(chain[0])
+	if err != nil {
+		return err
+	}
+	public, ok := leaf.PublicKey.(interface{ Equal(crypto.PublicKey) bool })
+	if !ok {
+		return errors.New("unsupported certificate public key")
+	}
+
+	s.Lock()
+	defer s.Unlock()
+	if s.pendingKey != nil && public.Equal(s.pendingKey.Public()) {
+		s.current = tls.Certificate{Certificate: chain, PrivateKey: s.pendingKey, Leaf: leaf}
+		s.pendingKey = nil
+		return nil
+	}
+	if key, ok := s.current.PrivateKey.(crypto.Signer); ok && public.Equal(key.Public()) {
+		s.current = tls.Certificate{Certificate: chain, PrivateKey: key, Leaf: leaf}
+		return nil
+	}
+	return errors.New("the certificate does not match the key of the last GenerateCSR")
+}
+
+func managerCertificatesURI(managerID string) string {
+	return "/redfish/v1/Managers/" + managerID + "/NetworkProtocol/HTTPS/Certificates"
+}
+
+func (b *mockBMC) managerCertificateLinks() []Link {
+	links := make([]Link, 0, len(b.config.Managers))
+	for _, manager := range b.config.Managers {
+		links = append(links, Link{ODataID: managerCertificatesURI(manager.ID) + "/" + httpsCertificateID})
+	}
+	return links
+}
+
+func getCertificateService(c *gin.Context) {
+	c.Header("OData-Version", "4.0")
+	service := CertificateService{
+		ODataContext: "/redfish/v1/$metadata#CertificateService.CertificateService",
+		ODataType:    "#CertificateService.v1_0_4.CertificateService",
+		ODataID:      "/redfish/v1/CertificateService",
+		ID:           "CertificateService",
+		Name:         "Certificate Service",
+		Actions: CertificateServiceActions{
+			GenerateCSR: GenerateCSRAction{
+				Target:            "/redfish/v1/CertificateService/Actions/CertificateService.GenerateCSR",
+				KeyPairAlgorithms: []string{"TPM_ALG_RSA", "TPM_ALG_ECDSA"},
+				KeyCurveIDs:       []string{"TPM_ECC_NIST_P256", "TPM_ECC_NIST_P384"},
+				KeyBitLengths:     csrKeyBitLengths,
+			},
+			ReplaceCertificate: ReplaceCertificateAction{
+				Target:           "/redfish/v1/CertificateService/Actions/CertificateService.ReplaceCertificate",
+				CertificateTypes: []string{"PEM"},
+			},
+		},
+		CertificateLocations: Link{ODataID: "/redfish/v1/CertificateService/CertificateLocations"},
+	}
+	c.JSON(http.StatusOK, service)
+}
+
+func (b *mockBMC) getCertificateLocations(c *gin.Context) {
+	c.Header("OData-Version", "4.0")
+	certificates := b.managerCertificateLinks()
+	locations := CertificateLocations{
+		ODataContext: "/redfish/v1/$metadata#CertificateLocations.CertificateLocations",
+		ODataType:    "#CertificateLocations.v1_0_2.CertificateLocations",
+		ODataID:      "/redfish/v1/CertificateService/CertificateLocations",
+		ID:           "CertificateLocations",
+		Name:         "Certificate Locations",
+		Links: CertificateLocationsLinks{
+			Certificates:      certificates,
+			CertificatesCount: len(certificates),
+		},
+	}
+	c.JSON(http.StatusOK, locations)
+}
+
+func (b *mockBMC) getManagerNetworkProtocol(c *gin.Context) {
+	c.Header("OData-Version", "4.0")
+	manager, ok := b.findManager(c.Param("id"))
+	if !ok {
+		resourceNotFound(c, "Manager", c.Param("id"))
+		return
+	}
+	protocol := ManagerNetworkProtocol{
+		ODataContext: "/redfish/v1/$metadata#ManagerNetworkProtocol.ManagerNetworkProtocol",
+		ODataType:    "#ManagerNetworkProtocol.v1_9_0.ManagerNetworkProtocol",
+		ODataID:      "/redfish/v1/Managers/" + manager.ID + "/NetworkProtocol",
+		ID:           "NetworkProtocol",
+		Name:         "Manager Network Protocol",
+		HTTPS: HTTPSProtocol{
+			ProtocolEnabled: true,
+			Certificates:    Link{ODataID: managerCertificatesURI(manager.ID)},
+		},
+		Status: Status{State: "Enabled", Health: "OK"},
+	}
+	c.JSON(http.StatusOK, protocol)
+}
+
+func (b *mockBMC) getManagerCertificatesCollection(c *gin.Context) {
+	c.Header("OData-Version", "4.0")
+	manager, ok := b.findManager(c.Param("id"))
+	if !ok {
+		resourceNotFound(c, "Manager", c.Param("id"))
+		return
+	}
+	collection := Collection{
+		ODataContext: "/redfish/v1/$metadata#CertificateCollection.CertificateCollection",
+		ODataType:    "#CertificateCollection.CertificateCollection",
+		ODataID:      managerCertificatesURI(manager.ID),
+		Name:         "HTTPS Certificate Collection",
+		MembersCount: 1,
+		Members:      []Link{{ODataID: managerCertificatesURI(manager.ID) + "/" + httpsCertificateID}},
+	}
+	c.JSON(http.StatusOK, collection)
+}
+
+func (b *mockBMC) getManagerCertificate(c *gin.Context) {
+	c.Header("OData-Version", "4.0")
+	manager, ok := b.findManager(c.Param("id"))
+	if !ok {
+		resourceNotFound(c, "Manager", c.Param("id"))
+		return
+	}
+	if c.Param("certID") != httpsCertificateID {
+		resourceNotFound(c, "Certificate", c.Param("certID"))
+		return
+	}
+
+	served := b.certificates.certificate()
+	leaf := served.Leaf
+	if leaf == nil {
+		var err error
+		if leaf, err = x509.ParseCertificate(served.Certificate[0]); err != nil {
+			redfishError(c, http.StatusInternalServerError, baseMessage("InternalError"))
+			return
+		}
+	}
+	var chain strings.Builder
+	for _, der := range served.Certificate {
+		chain.Write(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+	}
+	fingerprint := sha256.Sum256(leaf.Raw)
+	certificate := Certificate{
+		ODataContext:             "/redfish/v1/$metadata#Certificate.Certificate",
+		ODataType:                "#Certificate.v1_5_0.Certificate",
+		ODataID:                  managerCertificatesURI(manager.ID) + "/" + httpsCertificateID,
+		ID:                       httpsCertificateID,
+		Name:                     "HTTPS Certificate",
+		CertificateString:        chain.String(),
+		CertificateType:          "PEM",
+		CertificateUsageTypes:    []string{"Web"},
+		Issuer:                   certificateIdentifier(leaf.Issuer),
+		Subject:                  certificateIdentifier(leaf.Subject),
+		ValidNotBefore:           leaf.NotBefore.UTC().Format(time.RFC3339),
+		ValidNotAfter:            leaf.NotAfter.UTC().Format(time.RFC3339),
+		SerialNumber:             strings.ToUpper(hex.EncodeToString(leaf.SerialNumber.Bytes())),
+		Fingerprint:              strings.ToUpper(hex.EncodeToString(fingerprint[:])),
+		FingerprintHashAlgorithm: "TPM_ALG_SHA256",
+		KeyUsage:                 certificateKeyUsage(leaf),
+	}
+	c.JSON(http.StatusOK, certificate)
+}
+
+func certificateIdentifier(name pkix.Name) CertificateIdentifier {
+	first := func(values []string) string {
+		if len(values) == 0 {
+			return ""
+		}
+		return values[0]
+	}
+	return CertificateIdentifier{
+		CommonName:         name.CommonName,
+		Organization:       first(name.Organization),
+		OrganizationalUnit: first(name.OrganizationalUnit),
+		City:               first(name.Locality),
+		State:              first(name.Province),
+		Country:            first(name.Country),
+	}
+}
+
+func certificateKeyUsage(leaf *x509.Certificate) []string {
+	usage := []string{}
+	if leaf.KeyUsage&x509.KeyUsageDigitalSignature != 0 {
+		usage = append(usage, "DigitalSignature")
+	}
+	if leaf.KeyUsage&x509.KeyUsageKeyEncipherment != 0 {
+		usage = append(usage, "KeyEncipherment")
+	}
+	if slices.Contains(leaf.ExtKeyUsage, x509.ExtKeyUsageServerAuth) {
+		usage = append(usage, "ServerAuthentication")
+	}
+	return usage
+}
+
+func (b *mockBMC) generateCSR(c *gin.Context) {
+	c.Header("OData-Version", "4.0")
+	const action = "CertificateService.GenerateCSR"
+	var req GenerateCSRRequest
+	if !bindRedfishJSON(c, &req) {
+		return
+	}
+	if req.CertificateCollection == nil || req.CertificateCollection.ODataID == "" {
+		redfishError(c, http.StatusBadRequest, baseMessage("ActionParameterMissing", action, "CertificateCollection"))
+		return
+	}
+	if req.CommonName == "" {
+		redfishError(c, http.StatusBadRequest, baseMessage("ActionParameterMissing", action, "CommonName"))
+		return
+	}
+	if !b.isManagerCertificatesURI(req.CertificateCollection.ODataID) {
+		redfishError(c, http.StatusBadRequest, baseMessage("ActionParameterValueNotInList", req.CertificateCollection.ODataID, "CertificateCollection", action))
+		return
+	}
+	switch req.KeyPairAlgorithm {
+	case "", "TPM_ALG_RSA":
+		if req.KeyBitLength != 0 && !slices.Contains(csrKeyBitLengths, req.KeyBitLength) {
+			redfishError(c, http.StatusBadRequest, baseMessage("ActionParameterValueNotInList", fmt.Sprint(req.KeyBitLength), "KeyBitLength", action))
+			return
+		}
+	case "TPM_ALG_ECDSA":
+		if _, ok := csrKeyCurves[req.KeyCurveID]; req.KeyCurveID != "" && !ok {
+			redfishError(c, http.StatusBadRequest, baseMessage("ActionParameterValueNotInList", req.KeyCurveID, "KeyCurveId", action))
+			return
+		}
+	default:
+		redfishError(c, http.StatusBadRequest, baseMessage("ActionParameterValueNotInList", req.KeyPairAlgorithm, "KeyPairAlgorithm", action))
+		return
+	}
+
+	csr, err := b.certificates.generateCSR(req)
+	if err != nil {
+		redfishError(c, http.StatusInternalServerError, baseMessage("InternalError"))
+		return
+	}
+	c.JSON(http.StatusOK, GenerateCSRResponse{
+		CSRString:             csr,
+		CertificateCollection: Link{ODataID: req.CertificateCollection.ODataID},
+	})
+}
+
+func (b *mockBMC) replaceCertificate(c *gin.Context) {
+	c.Header("OData-Version", "4.0")
+	const action = "CertificateService.ReplaceCertificate"
+	var req ReplaceCertificateRequest
+	if !bindRedfishJSON(c, &req) {
+		return
+	}
+	if req.CertificateURI == nil || req.CertificateURI.ODataID == "" {
+		redfishError(c, http.StatusBadRequest, baseMessage("ActionParameterMissing", action, "CertificateUri"))
+		return
+	}
+	if req.CertificateString == "" {
+		redfishError(c, http.StatusBadRequest, baseMessage("ActionParameterMissing", action, "CertificateString"))
+		return
+	}
+	if req.CertificateType != "PEM" {
+		redfishError(c, http.StatusBadRequest, baseMessage("ActionParameterValueNotInList", req.CertificateType, "CertificateType", action))
+		return
+	}
+	if !slices.Contains(b.managerCertificateLinks(), Link{ODataID: req.CertificateURI.ODataID}) {
+		redfishError(c, http.StatusBadRequest, baseMessage("ActionParameterValueNotInList", req.CertificateURI.ODataID, "CertificateUri", action))
+		return
+	}
+
+	// The PEM data may hold a private key, so the error does not echo it:
+	// the value argument of the message is left empty.
+	if err := b.certificates.replace(req.CertificateString); err != nil {
+		c.JSON(http.StatusBadRequest, errorWithDetail(err.Error(),
+			baseMessage("ActionParameterValueFormatError", "", "CertificateString", action)))
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (b *mockBMC) isManagerCertificatesURI(uri string) bool {
+	for _, manager := range b.config.Managers {
+		if uri == managerCertificatesURI(manager.ID) {
+			return true
+		}
+	}
+	return false
+}
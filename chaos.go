@@ -0,0 +1,406 @@
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Latency distributions a chaos rule can draw response delays from.
+const (
+	latencyFixed       = "fixed"
+	latencyUniform     = "uniform"
+	latencyNormal      = "normal"
+	latencyExponential = "exponential"
+)
+
+// ChaosConfig makes the Redfish endpoints slow and unreliable. Each request
+// uses the first rule that matches it; requests no rule matches, and the
+// /mock control API, are served normally. A non-zero Seed makes the random
+// choices repeat from run to run.
+type ChaosConfig struct {
+	Seed  uint64            `json:"seed"`
+	Rules []ChaosRuleConfig `json:"rules"`
+}
+
+// ChaosRuleConfig selects requests by path and method and describes what
+// goes wrong with them. Path is a path.Match pattern for the request path, so
+// * matches one path segment; an empty Path or Methods matches every request.
+// After the latency, a request fails with an injected error, a connection
+// reset, or a truncated body with the given probabilities, which must not
+// add up to more than 1. An injected error carries a Retry-After header with
+// RetryAfterProbability.
+type ChaosRuleConfig struct {
+	Path                  string        `json:"path"`
+	Methods               []string      `json:"methods"`
+	Latency               LatencyConfig `json:"latency"`
+	ErrorProbability      float64       `json:"error_probability"`
+	ErrorStatuses         []int         `json:"error_statuses"`
+	RetryAfterProbability float64       `json:"retry_after_probability"`
+	RetryAfterSeconds     int           `json:"retry_after_seconds"`
+	ResetProbability      float64       `json:"reset_probability"`
+	TruncateProbability   float64       `json:"truncate_probability"`
+}
+
+// LatencyConfig is the distribution of the delay added before a response.
+// Fixed delays by MeanMs, uniform draws between MinMs and MaxMs, normal
+// draws around MeanMs with StddevMs, and exponential draws with mean MeanMs.
+// Every draw is kept between MinMs and MaxMs; a MaxMs of 0 sets no upper
+// bound.
+type LatencyConfig struct {
+	Distribution string `json:"distribution"`
+	MinMs        int    `json:"min_ms"`
+	MaxMs        int    `json:"max_ms"`
+	MeanMs       int    `json:"mean_ms"`
+	StddevMs     int    `json:"stddev_ms"`
+}
+
+// chaosPropertyError reports a chaos setting that is out of range.
+type chaosPropertyError struct {
+	property string
+	value    string
+}
+
+func (e *chaosPropertyError) Error() string {
+	return fmt.Sprintf("%s %s is not valid", e.property, e.value)
+}
+
+func (c ChaosConfig) validate() error {
+	for i, rule := range c.Rules {
+		invalid := func(property string, value any) error {
+			return &chaosPropertyError{property: fmt.Sprintf("rules[%d].%s", i, property), value: fmt.Sprint(value)}
+		}
+		if rule.Path != "" {
+			if _, err := path.Match(rule.Path, "/"); err != nil {
+				return invalid("path", rule.Path)
+			}
+		}
+		probabilities := []struct {
+			name  string
+			value float64
+		}{
+			{"error_probability", rule.ErrorProbability},
+			{"retry_after_probability", rule.RetryAfterProbability},
+			{"reset_probability", rule.ResetProbability},
+			{"truncate_probability", rule.TruncateProbability},
+		}
+		for _, probability := range probabilities {
+			if probability.value < 0 || probability.value > 1 {
+				return invalid(probability.name, probability.value)
+			}
+		}
+		if total := rule.ErrorProbability + rule.ResetProbability + rule.TruncateProbability; total > 1 {
+			return invalid("error_probability", total)
+		}
+		for _, status := range rule.ErrorStatuses {
+			if status < 500 || status > 599 {
+				return invalid("error_statuses", status)
+			}
+		}
+		if rule.RetryAfterSeconds < 0 {
+			return invalid("retry_after_seconds", rule.RetryAfterSeconds)
+		}
+		latency := rule.Latency
+		switch latency.Distribution {
+		case "", latencyFixed, latencyUniform, latencyNormal, latencyExponential:
+		default:
+			return invalid("latency.distribution", latency.Distribution)
+		}
+		for name, value := range map[string]int{"min_ms": latency.MinMs, "mean_ms": latency.MeanMs, "stddev_ms": latency.StddevMs} {
+			if value < 0 {
+				return invalid("latency."+name, value)
+			}
+		}
+		if latency.MaxMs != 0 && latency.MaxMs < latency.MinMs {
+			return invalid("latency.max_ms", latency.MaxMs)
+		}
+	}
+	return nil
+}
+
+// chaosInjector holds the chaos settings in effect and the random source the
+// middleware draws from.
+type chaosInjector struct {
+	sync.Mutex
+	config ChaosConfig
+	random *rand.Rand
+}
+
+func newChaosInjector(config ChaosConfig) *chaosInjector {
+	injector := &chaosInjector{}
+	injector.set(config)
+	return injector
+}
+
+// set replaces the settings and restarts the random sequence.
+func (i *chaosInjector) set(config ChaosConfig) {
+	i.Lock()
+	defer i.Unlock()
+	seed := config.Seed
+	if seed == 0 {
+		seed = rand.Uint64()
+	}
+	i.config = config
+	i.random = rand.New(rand.NewPCG(seed, seed))
+}
+
+func (i *chaosInjector) get() ChaosConfig {
+	i.Lock()
+	defer i.Unlock()
+	return i.config
+}
+
+// chaosOutcome is what the middleware does to one request.
+type chaosOutcome struct {
+	delay      time.Duration
+	status     int
+	retryAfter int
+	reset      bool
+	truncate   bool
+}
+
+// choose draws the outcome for a request. The second result is false when
+// no rule matches it.
+func (i *chaosInjector) choose(method, requestPath string) (chaosOutcome, bool) {
+	i.Lock()
+	defer i.Unlock()
+	for _, rule := range i.config.Rules {
+		if len(rule.Methods) > 0 && !slices.Contains(rule.Methods, method) {
+			continue
+		}
+		if rule.Path != "" {
+			if matched, _ := path.Match(rule.Path, requestPath); !matched {
+				continue
+			}
+		}
+		outcome := chaosOutcome{delay: i.latency(rule.Latency)}
+		roll := i.random.Float64()
+		switch {
+		case roll < rule.ErrorProbability:
+			outcome.status = http.StatusInternalServerError
+			if len(rule.ErrorStatuses) > 0 {
+				outcome.status = rule.ErrorStatuses[i.random.IntN(len(rule.ErrorStatuses))]
+			}
+			if i.random.Float64() < rule.RetryAfterProbability {
+				outcome.retryAfter = rule.RetryAfterSeconds
+			}
+		case roll < rule.ErrorProbability+rule.ResetProbability:
+			outcome.reset = true
+		case roll < rule.ErrorProbability+rule.ResetProbability+rule.TruncateProbability:
+			outcome.truncate = true
+		}
+		return outcome, true
+	}
+	return chaosOutcome{}, false
+}
+
+// latency draws one delay from config. The caller must hold the lock.
+func (i *chaosInjector) latency(config LatencyConfig) time.Duration {
+	var ms float64
+	switch config.Distribution {
+	case latencyFixed:
+		ms = float64(config.MeanMs)
+	case latencyUniform:
+		ms = float64(config.MinMs) + i.random.Float64()*float64(max(config.MaxMs-config.MinMs, 0))
+	case latencyNormal:
+		ms = float64(config.MeanMs) + i.random.NormFloat64()*float64(config.StddevMs)
+	case latencyExponential:
+		ms = i.random.ExpFloat64() * float64(config.MeanMs)
+	}
+	ms = math.Max(ms, float64(config.MinMs))
+	if config.MaxMs > 0 {
+		ms = math.Min(ms, float64(config.MaxMs))
+	}
+	return time.Duration(ms * float64(time.Millisecond))
+}
+
+// injectChaos is the middleware that applies the chaos rules. It must run
+// outside gin's recovery middleware: resets and truncated bodies end the
+// response with http.ErrAbortHandler, which the HTTP server turns into a
+// closed connection or, on HTTP/2, a reset stream.
+func (b *mockBMC) injectChaos() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if strings.HasPrefix(c.Request.URL.Path, "/mock/") {
+			return
+		}
+		outcome, ok := b.chaos.choose(c.Request.Method, c.Request.URL.Path)
+		if !ok {
+			return
+		}
+		if outcome.delay > 0 {
+			timer := time.NewTimer(outcome.delay)
+			select {
+			case <-timer.C:
+			case <-c.Request.Context().Done():
+				timer.Stop()
+				c.Abort()
+				return
+			}
+		}
+		switch {
+		case outcome.status != 0:
+			c.Header("OData-Version", "4.0")
+			if outcome.retryAfter > 0 {
+				c.Header("Retry-After", strconv.Itoa(outcome.retryAfter))
+			}
+			message := baseMessage("InternalError")
+			if outcome.status == http.StatusServiceUnavailable {
+				message = baseMessage("ServiceTemporarilyUnavailable", strconv.Itoa(outcome.retryAfter))
+			}
+			redfishError(c, outcome.status, message)
+		case outcome.reset:
+			resetConnection(c)
+		case outcome.truncate:
+			writer := &truncatingWriter{ResponseWriter: c.Writer}
+			c.Writer = writer
+			c.Next()
+			c.Writer = writer.ResponseWriter
+			if writer.truncate() {
+				panic(http.ErrAbortHandler)
+			}
+		}
+	}
+}
+
+// resetConnection drops the client's connection without a response. An
+// HTTP/1 connection is closed with a TCP reset.
+func resetConnection(c *gin.Context) {
+	if c.Request.ProtoMajor == 1 {
+		if conn, _, err := c.Writer.Hijack(); err == nil {
+			if tlsConn, ok := conn.(*tls.Conn); ok {
+				conn = tlsConn.NetConn()
+			}
+			if tcpConn, ok := conn.(*net.TCPConn); ok {
+				tcpConn.SetLinger(0)
+			}
+			conn.Close()
+			c.Abort()
+			return
+		}
+	}
+	panic(http.ErrAbortHandler)
+}
+
+// truncatingWriter holds back a response body so that only its first half
+// is sent, under a Content-Length for the whole body. Event streams pass
+// through unchanged.
+type truncatingWriter struct {
+	gin.ResponseWriter
+	status int
+	body   []byte
+	stream bool
+}
+
+// passThrough reports whether the response is an event stream, which is
+// sent unchanged. A held back status is sent when the stream is detected.
+func (w *truncatingWriter) passThrough() bool {
+	if !w.stream && len(w.body) == 0 && strings.HasPrefix(w.Header().Get("Content-Type"), "text/event-stream") {
+		w.stream = true
+		if w.status != 0 {
+			w.ResponseWriter.WriteHeader(w.status)
+		}
+	}
+	return w.stream
+}
+
+func (w *truncatingWriter) WriteHeader(status int) {
+	if w.passThrough() {
+		w.ResponseWriter.WriteHeader(status)
+		return
+	}
+	w.status = status
+}
+
+func (w *truncatingWriter) WriteHeaderNow() {
+	if w.passThrough() {
+		w.ResponseWriter.WriteHeaderNow()
+	}
+}
+
+func (w *truncatingWriter) Write(data []byte) (int, error) {
+	if w.passThrough() {
+		return w.ResponseWriter.Write(data)
+	}
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	w.body = append(w.body, data...)
+	return len(data), nil
+}
+
+func (w *truncatingWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *truncatingWriter) Flush() {
+	if w.passThrough() {
+		w.ResponseWriter.Flush()
+	}
+}
+
+func (w *truncatingWriter) Status() int {
+	if w.stream || w.status == 0 {
+		return w.ResponseWriter.Status()
+	}
+	return w.status
+}
+
+func (w *truncatingWriter) Size() int {
+	if w.stream {
+		return w.ResponseWriter.Size()
+	}
+	return len(w.body)
+}
+
+func (w *truncatingWriter) Written() bool {
+	return w.stream || w.status != 0 || w.ResponseWriter.Written()
+}
+
+// truncate sends the held back response cut in half and reports whether it
+// cut anything.
+func (w *truncatingWriter) truncate() bool {
+	if w.stream {
+		return false
+	}
+	if len(w.body) == 0 {
+		if w.status != 0 {
+			w.ResponseWriter.WriteHeader(w.status)
+		}
+		return false
+	}
+	w.Header().Set("Content-Length", strconv.Itoa(len(w.body)))
+	w.ResponseWriter.WriteHeader(w.status)
+	w.ResponseWriter.Write(w.body[:len(w.body)/2])
+	w.ResponseWriter.Flush()
+	return true
+}
+
+func (b *mockBMC) getChaos(c *gin.Context) {
+	c.JSON(http.StatusOK, b.chaos.get())
+}
+
+// putChaos replaces the chaos settings. An empty object turns chaos off.
+func (b *mockBMC) putChaos(c *gin.Context) {
+	var req ChaosConfig
+	if !bindRedfishJSON(c, &req) {
+		return
+	}
+	if err := req.validate(); err != nil {
+		propertyErr := err.(*chaosPropertyError)
+		redfishError(c, http.StatusBadRequest, baseMessage("PropertyValueOutOfRange", propertyErr.value, propertyErr.property))
+		return
+	}
+	b.chaos.set(req)
+	c.JSON(http.StatusOK, b.chaos.get())
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestChaosRulesBreakMatchingRequests(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	config := defaultConfig()
+	config.Chaos = ChaosConfig{Seed: 1, Rules: []ChaosRuleConfig{
+		{Path: "/redfish/v1/Systems/*", Methods: []string{http.MethodGet}, ErrorProbability: 1, ErrorStatuses: []int{http.StatusServiceUnavailable}, RetryAfterProbability: 1, RetryAfterSeconds: 7},
+		{Path: "/redfish/v1/Chassis", ResetProbability: 1},
+		{Path: "/redfish/v1/Managers/*", TruncateProbability: 1},
+		{Path: "/redfish/v1/UpdateService", Latency: LatencyConfig{Distribution: latencyFixed, MeanMs: 50}},
+	}}
+	if err := config.Chaos.validate(); err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+	bmc := newTestBMC(t, config)
+	server := httptest.NewServer(newRouter(bmc))
+	defer server.Close()
+	client := server.Client()
+	get := func(path string) (*http.Response, error) {
+		request, err := http.NewRequest(http.MethodGet, server.URL+path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		request.SetBasicAuth(bmc.config.Authentication.Username, bmc.config.Authentication.Password)
+		return client.Do(request)
+	}
+
+	response, err := get("/redfish/v1/Systems/1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(response.Body)
+	response.Body.Close()
+	if response.StatusCode != http.StatusServiceUnavailable || response.Header.Get("Retry-After") != "7" || !strings.Contains(string(body), "ServiceTemporarilyUnavailable") {
+		t.Fatalf("error response = %d %v %s", response.StatusCode, response.Header, body)
+	}
+
+	if response, err := get("/redfish/v1/Chassis"); err == nil {
+		response.Body.Close()
+		t.Fatalf("reset request status = %d", response.StatusCode)
+	}
+
+	response, err = get("/redfish/v1/Managers/1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = io.ReadAll(response.Body)
+	response.Body.Close()
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("truncated body error = %v", err)
+	}
+
+	started := time.Now()
+	response, err = get("/redfish/v1/UpdateService")
+	if err != nil {
+		t.Fatal(err)
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusOK || time.Since(started) < 50*time.Millisecond {
+		t.Fatalf("delayed response = %d after %s", response.StatusCode, time.Since(started))
+	}
+
+	// The control API is never affected, and it can turn chaos off.
+	request, _ := http.NewRequest(http.MethodPut, server.URL+"/mock/chaos", strings.NewReader(`{}`))
+	request.SetBasicAuth(bmc.config.Authentication.Username, bmc.config.Authentication.Password)
+	request.Header.Set("Content-Type", "application/json")
+	response, err = client.Do(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		t.Fatalf("PUT /mock/chaos status = %d", response.StatusCode)
+	}
+	response, err = get("/redfish/v1/Systems/1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		t.Fatalf("status without chaos = %d", response.StatusCode)
+	}
+}
+
+func TestChaosConfigValidation(t *testing.T) {
+	tests := []struct {
+		rule     ChaosRuleConfig
+		property string
+	}{
+		{ChaosRuleConfig{ErrorProbability: 1.5}, "rules[0].error_probability"},
+		{ChaosRuleConfig{ErrorProbability: 0.6, ResetProbability: 0.6}, "rules[0].error_probability"},
+		{ChaosRuleConfig{ErrorProbability: 1, ErrorStatuses: []int{404}}, "rules[0].error_statuses"},
+		{ChaosRuleConfig{Path: "/redfish/v1/["}, "rules[0].path"},
+		{ChaosRuleConfig{Latency: LatencyConfig{Distribution: "pareto"}}, "rules[0].latency.distribution"},
+		{ChaosRuleConfig{Latency: LatencyConfig{MinMs: 100, MaxMs: 10}}, "rules[0].latency.max_ms"},
+	}
+	for _, test := range tests {
+		err := ChaosConfig{Rules: []ChaosRuleConfig{test.rule}}.validate()
+		var propertyErr *chaosPropertyError
+		if !errors.As(err, &propertyErr) || propertyErr.property != test.property {
+			t.Errorf("validate(%+v) = %v, want an error for %s", test.rule, err, test.property)
+		}
+	}
+
+	injector := newChaosInjector(ChaosConfig{Seed: 7, Rules: []ChaosRuleConfig{{Latency: LatencyConfig{Distribution: latencyNormal, MinMs: 10, MaxMs: 30, MeanMs: 20, StddevMs: 50}}}})
+	for range 100 {
+		outcome, ok := injector.choose(http.MethodGet, "/redfish/v1")
+		if !ok || outcome.delay < 10*time.Millisecond || outcome.delay > 30*time.Millisecond {
+			t.Fatalf("outcome = %+v, %v", outcome, ok)
+		}
+	}
+}
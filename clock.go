@@ -0,0 +1,269 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ClockConfig sets the simulated time a BMC starts with. StartTime, an
+// RFC 3339 timestamp, replaces the wall clock time at startup; Frozen stops
+// simulated time until the admin API steps or resumes it.
+type ClockConfig struct {
+	StartTime string `json:"start_time"`
+	Frozen    bool   `json:"frozen"`
+}
+
+func (c ClockConfig) validate() error {
+	if c.StartTime == "" {
+		return nil
+	}
+	if _, err := time.Parse(time.RFC3339, c.StartTime); err != nil {
+		return errors.New("clock.start_time must be an RFC 3339 timestamp")
+	}
+	return nil
+}
+
+// timeSource is the time every timed behavior of a BMC follows: transitions
+// scheduled with afterFunc and waits made with after. The stores take it
+// rather than a *mockClock so that they only see time, not its controls.
+type timeSource interface {
+	now() time.Time
+	// afterFunc calls f once d has passed. Due callbacks run one after
+	// another on a single goroutine, so f must not block.
+	afterFunc(d time.Duration, f func())
+	// after returns a channel that receives nil once d has passed, or
+	// errClockReset if the clock is reset first, and a function that drops
+	// the wait.
+	after(d time.Duration) (<-chan error, func())
+}
+
+// errClockReset ends the waits that are pending when a clock is reset.
+var errClockReset = errors.New("clock was reset")
+
+// sleep waits for d on source. It returns ctx's error if ctx is done first
+// and errClockReset if the clock is reset first.
+func sleep(ctx context.Context, source timeSource, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	wake, stop := source.after(d)
+	defer stop()
+	select {
+	case err := <-wake:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// mockClock is the BMC's simulated time. Running, it follows the wall clock
+// shifted by an offset; frozen, it stands still until it is advanced. Every
+// timed transition and wait of the mock is scheduled on it, so advancing the
+// clock completes whatever became due.
+type mockClock struct {
+	sync.Mutex
+	config      ClockConfig
+	offset      time.Duration
+	frozen      bool
+	frozenAt    time.Time
+	nextTimerID uint64
+	timers      []clockTimer
+	wake        *time.Timer
+}
+
+// clockTimer is a pending afterFunc, which has f, or after, which has wake.
+// A reset drops transitions but ends waits with errClockReset, so that
+// nothing waits for a time that will not come.
+type clockTimer struct {
+	id   uint64
+	at   time.Time
+	f    func()
+	wake chan error
+}
+
+func newMockClock(config ClockConfig) *mockClock {
+	c := &mockClock{config: config}
+	c.reset()
+	return c
+}
+
+func (c *mockClock) nowLocked() time.Time {
+	if c.frozen {
+		return c.frozenAt
+	}
+	return time.Now().Add(c.offset)
+}
+
+func (c *mockClock) now() time.Time {
+	c.Lock()
+	defer c.Unlock()
+	return c.nowLocked()
+}
+
+// afterFunc calls f once simulated time has moved d past now. f runs in
+// runDue, after the clock lock is released, so callers may hold locks that f
+// takes. Every timer that is due at once runs one after another on the same
+// goroutine: the wall clock timer's, or that of the caller of advance or
+// setFrozen. A callback that blocks therefore holds up the other due timers
+// and the admin request that moved the clock.
+func (c *mockClock) afterFunc(d time.Duration, f func()) {
+	c.Lock()
+	defer c.Unlock()
+	c.addLocked(clockTimer{at: c.nowLocked().Add(d), f: f})
+}
+
+// after returns a channel that receives nil once simulated time has moved d
+// past now, or errClockReset when the clock is reset first, and a function
+// that drops the wait.
+func (c *mockClock) after(d time.Duration) (<-chan error, func()) {
+	wake := make(chan error, 1)
+	c.Lock()
+	defer c.Unlock()
+	id := c.addLocked(clockTimer{at: c.nowLocked().Add(d), wake: wake})
+	return wake, func() { c.stop(id) }
+}
+
+func (c *mockClock) addLocked(timer clockTimer) uint64 {
+	c.nextTimerID++
+	timer.id = c.nextTimerID
+	c.timers = append(c.timers, timer)
+	c.scheduleLocked()
+	return timer.id
+}
+
+// stop drops a pending timer.
+func (c *mockClock) stop(id uint64) {
+	c.Lock()
+	defer c.Unlock()
+	for i, timer := range c.timers {
+		if timer.id == id {
+			c.timers = append(c.timers[:i], c.timers[i+1:]...)
+			c.scheduleLocked()
+			return
+		}
+	}
+}
+
+// scheduleLocked arms a wall clock timer for the earliest pending timer. A
+// frozen clock only wakes for timers that are already due.
+func (c *mockClock) scheduleLocked() {
+	if c.wake != nil {
+		c.wake.Stop()
+		c.wake = nil
+	}
+	if len(c.timers) == 0 {
+		return
+	}
+	earliest := c.timers[0].at
+	for _, timer := range c.timers[1:] {
+		if timer.at.Before(earliest) {
+			earliest = timer.at
+		}
+	}
+	delay := earliest.Sub(c.nowLocked())
+	if c.frozen && delay > 0 {
+		return
+	}
+	c.wake = time.AfterFunc(max(delay, 0), c.runDue)
+}
+
+// runDue calls the timers that are due, oldest first, without holding the
+// clock lock.
+func (c *mockClock) runDue() {
+	c.Lock()
+	now := c.nowLocked()
+	var due, pending []clockTimer
+	for _, timer := range c.timers {
+		if now.Before(timer.at) {
+			pending = append(pending, timer)
+		} else {
+			due = append(due, timer)
+		}
+	}
+	c.timers = pending
+	c.scheduleLocked()
+	c.Unlock()
+
+	sort.SliceStable(due, func(i, j int) bool { return due[i].at.Before(due[j].at) })
+	for _, timer := range due {
+		if timer.wake != nil {
+			timer.wake <- nil
+			continue
+		}
+		timer.f()
+	}
+}
+
+// advance moves simulated time forward by d and runs the timers that became
+// due before returning.
+func (c *mockClock) advance(d time.Duration) {
+	c.Lock()
+	if c.frozen {
+		c.frozenAt = c.frozenAt.Add(d)
+	} else {
+		c.offset += d
+	}
+	c.Unlock()
+	c.runDue()
+}
+
+// setFrozen stops or resumes simulated time. A resumed clock continues from
+// where it stood.
+func (c *mockClock) setFrozen(frozen bool) {
+	c.Lock()
+	if frozen && !c.frozen {
+		c.frozenAt = c.nowLocked()
+	} else if !frozen && c.frozen {
+		c.offset = time.Until(c.frozenAt)
+	}
+	c.frozen = frozen
+	c.Unlock()
+	c.runDue()
+}
+
+func (c *mockClock) isFrozen() bool {
+	c.Lock()
+	defer c.Unlock()
+	return c.frozen
+}
+
+// reset returns the clock to its configured start, drops pending transitions,
+// and ends pending waits with errClockReset.
+func (c *mockClock) reset() {
+	c.Lock()
+	var waits []clockTimer
+	for _, timer := range c.timers {
+		if timer.wake != nil {
+			waits = append(waits, timer)
+		}
+	}
+	c.offset = 0
+	start := time.Now()
+	if c.config.StartTime != "" {
+		start, _ = time.Parse(time.RFC3339, c.config.StartTime)
+		c.offset = time.Until(start)
+	}
+	c.frozen = c.config.Frozen
+	c.frozenAt = start
+	c.timers = nil
+	c.scheduleLocked()
+	c.Unlock()
+
+	for _, timer := range waits {
+		timer.wake <- errClockReset
+	}
+}
+
+// offsetSeconds reports how far simulated time is ahead of the wall clock.
+// While the clock is frozen the offset shrinks as the wall clock moves on.
+func (c *mockClock) offsetSeconds() float64 {
+	c.Lock()
+	defer c.Unlock()
+	if c.frozen {
+		return time.Until(c.frozenAt).Seconds()
+	}
+	return c.offset.Seconds()
+}
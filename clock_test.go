@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestFrozenClockRunsTimersInOrder(t *testing.T) {
+	clock := newMockClock(ClockConfig{StartTime: "2030-01-01T00:00:00Z", Frozen: true})
+	var fired []string
+	clock.afterFunc(20*time.Second, func() { fired = append(fired, "second") })
+	clock.afterFunc(10*time.Second, func() { fired = append(fired, "first") })
+
+	clock.advance(9 * time.Second)
+	if len(fired) != 0 {
+		t.Fatalf("fired early: %v", fired)
+	}
+	clock.advance(30 * time.Second)
+	if len(fired) != 2 || fired[0] != "first" || fired[1] != "second" {
+		t.Fatalf("fired = %v", fired)
+	}
+	if got := clock.now().UTC().Format(time.RFC3339); got != "2030-01-01T00:00:39Z" {
+		t.Fatalf("now = %s", got)
+	}
+
+	clock.setFrozen(false)
+	if clock.isFrozen() || clock.now().Before(time.Date(2030, 1, 1, 0, 0, 39, 0, time.UTC)) {
+		t.Fatalf("resumed clock = %s", clock.now())
+	}
+	clock.reset()
+	if !clock.isFrozen() || !clock.now().Equal(time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("reset clock = %s", clock.now())
+	}
+}
+
+func TestFrozenClockControlsWaits(t *testing.T) {
+	clock := newMockClock(ClockConfig{Frozen: true})
+	wake, _ := clock.after(10 * time.Second)
+	_, stop := clock.after(time.Second)
+	stop()
+	clock.advance(5 * time.Second)
+	select {
+	case <-wake:
+		t.Fatal("wait ended early")
+	default:
+	}
+	clock.advance(5 * time.Second)
+	if err := <-wake; err != nil {
+		t.Fatalf("elapsed wait = %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error)
+	go func() { done <- sleep(ctx, clock, time.Hour) }()
+	for {
+		clock.Lock()
+		pending := len(clock.timers)
+		clock.Unlock()
+		if pending == 1 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	clock.reset()
+	if err := <-done; !errors.Is(err, errClockReset) {
+		t.Fatalf("sleep across a reset = %v", err)
+	}
+	if len(clock.timers) != 0 {
+		t.Fatalf("timers after reset = %d", len(clock.timers))
+	}
+}
+
+func TestFrozenClockControlsInstallation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	config := defaultConfig()
+	config.Clock = ClockConfig{StartTime: "2030-01-01T00:00:00Z", Frozen: true}
+	config.Systems[0].InstallationDurationSeconds = 1200
+	bmc := newTestBMC(t, config)
+	router := newRouter(bmc)
+	admin, password := bmc.config.Authentication.Username, bmc.config.Authentication.Password
+	installationStatus := func() string {
+		t.Helper()
+		var state AdminState
+		recorder := accountRequest(router, http.MethodGet, "/mock/admin/state", "", admin, password)
+		if err := json.Unmarshal(recorder.Body.Bytes(), &state); err != nil {
+			t.Fatal(err)
+		}
+		return state.Systems["1"].InstallationStatus
+	}
+	setClock := func(body string) AdminClock {
+		t.Helper()
+		var clock AdminClock
+		recorder := accountRequest(router, http.MethodPost, "/mock/admin/clock", body, admin, password)
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("POST clock %s status = %d", body, recorder.Code)
+		}
+		if err := json.Unmarshal(recorder.Body.Bytes(), &clock); err != nil {
+			t.Fatal(err)
+		}
+		return clock
+	}
+
+	if recorder := accountRequest(router, http.MethodPatch, "/mock/admin/state", `{"systems":{"1":{"installation_status":"Installing"}}}`, admin, password); recorder.Code != http.StatusOK {
+		t.Fatalf("patch status = %d, body = %s", recorder.Code, recorder.Body.String())
+	}
+	setClock(`{"advance_seconds":1199}`)
+	if status := installationStatus(); status != "Installing" {
+		t.Fatalf("status after 1199s = %s", status)
+	}
+	clock := setClock(`{"advance_seconds":1}`)
+	if status := installationStatus(); status != "Installed" {
+		t.Fatalf("status after 1200s = %s", status)
+	}
+	if clock.Time != "2030-01-01T00:20:00Z" || !clock.Frozen {
+		t.Fatalf("clock = %+v", clock)
+	}
+
+	if clock := setClock(`{"frozen":false}`); clock.Frozen {
+		t.Fatalf("resumed clock = %+v", clock)
+	}
+	if recorder := accountRequest(router, http.MethodPost, "/mock/admin/clock", `{}`, admin, password); recorder.Code != http.StatusBadRequest {
+		t.Fatalf("empty clock request status = %d", recorder.Code)
+	}
+}
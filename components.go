@@ -0,0 +1,150 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Processor struct {
+	ODataContext  string `json:"@odata.context"`
+	ODataType     string `json:"@odata.type"`
+	ODataID       string `json:"@odata.id"`
+	ID            string `json:"Id"`
+	Name          string `json:"Name"`
+	ProcessorType string `json:"ProcessorType"`
+	Model         string `json:"Model"`
+	Socket        string `json:"Socket"`
+	Status        Status `json:"Status"`
+}
+
+type Memory struct {
+	ODataContext     string `json:"@odata.context"`
+	ODataType        string `json:"@odata.type"`
+	ODataID          string `json:"@odata.id"`
+	ID               string `json:"Id"`
+	Name             string `json:"Name"`
+	MemoryDeviceType string `json:"MemoryDeviceType"`
+	CapacityMiB      int    `json:"CapacityMiB"`
+	DeviceLocator    string `json:"DeviceLocator"`
+	Status           Status `json:"Status"`
+}
+
+// processorIDs names one processor per configured socket: CPU1, CPU2, ...
+func processorIDs(system SystemConfig) []string {
+	ids := make([]string, 0, system.ProcessorCount)
+	for i := 1; i <= system.ProcessorCount; i++ {
+		ids = append(ids, "CPU"+strconv.Itoa(i))
+	}
+	return ids
+}
+
+// memoryIDs names the DIMMs of a system. Each processor has two DIMMs that
+// split the configured memory evenly.
+func memoryIDs(system SystemConfig) []string {
+	count := max(1, 2*system.ProcessorCount)
+	ids := make([]string, 0, count)
+	for i := 1; i <= count; i++ {
+		ids = append(ids, "DIMM"+strconv.Itoa(i))
+	}
+	return ids
+}
+
+func (b *mockBMC) lookupSystem(c *gin.Context) (SystemConfig, bool) {
+	system, ok := b.findSystem(c.Param("id"))
+	if !ok {
+		resourceNotFound(c, "ComputerSystem", c.Param("id"))
+	}
+	return system, ok
+}
+
+func componentsCollection(uri, resourceType, name string, ids []string) Collection {
+	members := make([]Link, 0, len(ids))
+	for _, id := range ids {
+		members = append(members, Link{ODataID: uri + "/" + id})
+	}
+	return Collection{
+		ODataContext: "/redfish/v1/$metadata#" + resourceType + "." + resourceType,
+		ODataType:    "#" + resourceType + "." + resourceType,
+		ODataID:      uri,
+		Name:         name,
+		MembersCount: len(members),
+		Members:      members,
+	}
+}
+
+func (b *mockBMC) getProcessorsCollection(c *gin.Context) {
+	c.Header("OData-Version", "4.0")
+	system, ok := b.lookupSystem(c)
+	if !ok {
+		return
+	}
+	uri := "/redfish/v1/Systems/" + system.ID + "/Processors"
+	c.JSON(http.StatusOK, componentsCollection(uri, "ProcessorCollection", "Processors Collection", processorIDs(system)))
+}
+
+func (b *mockBMC) getProcessor(c *gin.Context) {
+	c.Header("OData-Version", "4.0")
+	system, ok := b.lookupSystem(c)
+	if !ok {
+		return
+	}
+	for i, id := range processorIDs(system) {
+		if id != c.Param("componentID") {
+			continue
+		}
+		uri := "/redfish/v1/Systems/" + system.ID + "/Processors/" + id
+		c.JSON(http.StatusOK, Processor{
+			ODataContext:  "/redfish/v1/$metadata#Processor.Processor",
+			ODataType:     "#Processor.v1_18_0.Processor",
+			ODataID:       uri,
+			ID:            id,
+			Name:          "Processor " + strconv.Itoa(i+1),
+			ProcessorType: "CPU",
+			Model:         system.ProcessorModel,
+			Socket:        "CPU " + strconv.Itoa(i+1),
+			Status:        Status{State: "Enabled", Health: b.faults.health(uri)},
+		})
+		return
+	}
+	resourceNotFound(c, "Processor", c.Param("componentID"))
+}
+
+func (b *mockBMC) getMemoryCollection(c *gin.Context) {
+	c.Header("OData-Version", "4.0")
+	system, ok := b.lookupSystem(c)
+	if !ok {
+		return
+	}
+	uri := "/redfish/v1/Systems/" + system.ID + "/Memory"
+	c.JSON(http.StatusOK, componentsCollection(uri, "MemoryCollection", "Memory Collection", memoryIDs(system)))
+}
+
+func (b *mockBMC) getMemory(c *gin.Context) {
+	c.Header("OData-Version", "4.0")
+	system, ok := b.lookupSystem(c)
+	if !ok {
+		return
+	}
+	ids := memoryIDs(system)
+	for _, id := range ids {
+		if id != c.Param("componentID") {
+			continue
+		}
+		uri := "/redfish/v1/Systems/" + system.ID + "/Memory/" + id
+		c.JSON(http.StatusOK, Memory{
+			ODataContext:     "/redfish/v1/$metadata#Memory.Memory",
+			ODataType:        "#Memory.v1_17_0.Memory",
+			ODataID:          uri,
+			ID:               id,
+			Name:             "Memory " + id,
+			MemoryDeviceType: "DDR5",
+			CapacityMiB:      system.TotalSystemMemoryGiB * 1024 / len(ids),
+			DeviceLocator:    id,
+			Status:           Status{State: "Enabled", Health: b.faults.health(uri)},
+		})
+		return
+	}
+	resourceNotFound(c, "Memory", c.Param("componentID"))
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type RedfishError struct {
+	Error RedfishErrorBody `json:"error"`
+}
+
+type RedfishErrorBody struct {
+	Code         string    `json:"code"`
+	Message      string    `json:"message"`
+	ExtendedInfo []Message `json:"@Message.ExtendedInfo"`
+}
+
+type Message struct {
+	ODataType       string   `json:"@odata.type,omitempty"`
+	MessageID       string   `json:"MessageId"`
+	Message         string   `json:"Message"`
+	MessageArgs     []string `json:"MessageArgs"`
+	Severity        string   `json:"Severity"`
+	MessageSeverity string   `json:"MessageSeverity,omitempty"`
+	Resolution      string   `json:"Resolution,omitempty"`
+}
+
+func newRedfishError(messages ...Message) RedfishError {
+	body := RedfishErrorBody{
+		Code:         baseMessage("GeneralError").MessageID,
+		Message:      "A general error has occurred. See ExtendedInfo for more information.",
+		ExtendedInfo: messages,
+	}
+	if len(messages) == 1 {
+		body.Code = messages[0].MessageID
+		body.Message = messages[0].Message
+	}
+	return RedfishError{Error: body}
+}
+
+func redfishError(c *gin.Context, status int, messages ...Message) {
+	c.AbortWithStatusJSON(status, newRedfishError(messages...))
+}
+
+func bindRedfishJSON(c *gin.Context, req any) bool {
+	err := c.ShouldBindJSON(req)
+	if err == nil {
+		return true
+	}
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		redfishError(c, http.StatusBadRequest, baseMessage("PropertyValueTypeError", typeErr.Value, typeErr.Field))
+		return false
+	}
+	redfishError(c, http.StatusBadRequest, baseMessage("MalformedJSON"))
+	return false
+}
+
+func resourceNotFound(c *gin.Context, resourceType, name string) {
+	redfishError(c, http.StatusNotFound, baseMessage("ResourceNotFound", resourceType, name))
+}
+
+func notFoundRoute(c *gin.Context) {
+	c.Header("OData-Version", "4.0")
+	redfishError(c, http.StatusNotFound, baseMessage("InvalidURI", c.Request.URL.Path))
+}
+
+func errorWithDetail(detail string, message Message) RedfishError {
+	body := newRedfishError(message)
+	body.Error.Message = fmt.Sprintf("%s %s", message.Message, detail)
+	return body
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRedfishErrorResponses(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	bmc := newTestBMC(t, defaultConfig())
+	router := newRouter(bmc)
+
+	tests := []struct {
+		name      string
+		method    string
+		path      string
+		body      string
+		status    int
+		messageID string
+		args      []string
+	}{
+		{
+			name: "malformed JSON", method: http.MethodPatch, path: "/redfish/v1/Systems/1", body: `{"Boot":`,
+			status: http.StatusBadRequest, messageID: "Base.1.16.0.MalformedJSON", args: []string{},
+		},
+		{
+			name: "property type", method: http.MethodPatch, path: "/redfish/v1/Systems/1", body: `{"Boot":{"BootSourceOverrideTarget":5}}`,
+			status: http.StatusBadRequest, messageID: "Base.1.16.0.PropertyValueTypeError", args: []string{"number", "Boot.BootSourceOverrideTarget"},
+		},
+		{
+			name: "property missing", method: http.MethodPatch, path: "/redfish/v1/Systems/1", body: `{}`,
+			status: http.StatusBadRequest, messageID: "Base.1.16.0.PropertyMissing", args: []string{"Boot"},
+		},
+		{
+			name: "property value", method: http.MethodPatch, path: "/redfish/v1/Systems/1", body: `{"Boot":{"BootSourceOverrideTarget":"Floppy"}}`,
+			status: http.StatusBadRequest, messageID: "Base.1.16.0.PropertyValueNotInList", args: []string{"Floppy", "BootSourceOverrideTarget"},
+		},
+		{
+			name: "action parameter value", method: http.MethodPost, path: "/redfish/v1/Systems/1/Actions/ComputerSystem.Reset", body: `{"ResetType":"Nmi"}`,
+			status: http.StatusBadRequest, messageID: "Base.1.16.0.ActionParameterValueNotInList", args: []string{"Nmi", "ResetType", "ComputerSystem.Reset"},
+		},
+		{
+			name: "action parameter missing", method: http.MethodPost, path: "/redfish/v1/UpdateService/Actions/UpdateService.SimpleUpdate", body: `{}`,
+			status: http.StatusBadRequest, messageID: "Base.1.16.0.ActionParameterMissing", args: []string{"UpdateService.SimpleUpdate", "ImageURI"},
+		},
+		{
+			name: "resource not found", method: http.MethodGet, path: "/redfish/v1/UpdateService/FirmwareInventory/CPLD",
+			status: http.StatusNotFound, messageID: "Base.1.16.0.ResourceNotFound", args: []string{"SoftwareInventory", "CPLD"},
+		},
+		{
+			name: "unknown URI", method: http.MethodGet, path: "/redfish/v1/Unknown",
+			status: http.StatusNotFound, messageID: "Base.1.16.0.InvalidURI", args: []string{"/redfish/v1/Unknown"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			request := httptest.NewRequest(test.method, test.path, strings.NewReader(test.body))
+			request.SetBasicAuth(bmc.config.Authentication.Username, bmc.config.Authentication.Password)
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, request)
+			if recorder.Code != test.status {
+				t.Fatalf("status = %d, want %d", recorder.Code, test.status)
+			}
+
+			var response RedfishError
+			if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+				t.Fatalf("decode error response: %v", err)
+			}
+			if response.Error.Code != test.messageID || len(response.Error.ExtendedInfo) != 1 {
+				t.Fatalf("error = %#v, want code %s", response.Error, test.messageID)
+			}
+			info := response.Error.ExtendedInfo[0]
+			if info.MessageID != test.messageID || strings.Join(info.MessageArgs, "|") != strings.Join(test.args, "|") ||
+				info.Message == "" || strings.Contains(info.Message, "%") {
+				t.Fatalf("extended info = %#v, want %s %v", info, test.messageID, test.args)
+			}
+		})
+	}
+}
+
+func TestUnauthorizedResponseUsesRedfishError(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	recorder := httptest.NewRecorder()
+	newRouter(newTestBMC(t, defaultConfig())).ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/redfish/v1/Systems", nil))
+	if recorder.Code != http.StatusUnauthorized {
+		t.Fatalf("status = %d, want %d", recorder.Code, http.StatusUnauthorized)
+	}
+	var response RedfishError
+	if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil || response.Error.Code != "Base.1.16.0.NoValidSession" {
+		t.Fatalf("unauthorized response = %s", recorder.Body.String())
+	}
+}
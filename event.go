@@ -0,0 +1,642 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type EventService struct {
+	ODataContext                 string              `json:"@odata.context"`
+	ODataType                    string              `json:"@odata.type"`
+	ODataID                      string              `json:"@odata.id"`
+	ID                           string              `json:"Id"`
+	Name                         string              `json:"Name"`
+	ServiceEnabled               bool                `json:"ServiceEnabled"`
+	DeliveryRetryAttempts        int                 `json:"DeliveryRetryAttempts"`
+	DeliveryRetryIntervalSeconds int                 `json:"DeliveryRetryIntervalSeconds"`
+	EventFormatTypes             []string            `json:"EventFormatTypes"`
+	RegistryPrefixes             []string            `json:"RegistryPrefixes"`
+	ServerSentEventURI           string              `json:"ServerSentEventUri"`
+	Subscriptions                Link                `json:"Subscriptions"`
+	Actions                      EventServiceActions `json:"Actions"`
+	Status                       Status              `json:"Status"`
+}
+
+type EventServiceActions struct {
+	SubmitTestEvent SubmitTestEventAction `json:"#EventService.SubmitTestEvent"`
+}
+
+type SubmitTestEventAction struct {
+	Target string `json:"target"`
+}
+
+type EventDestination struct {
+	ODataContext        string   `json:"@odata.context"`
+	ODataType           string   `json:"@odata.type"`
+	ODataID             string   `json:"@odata.id"`
+	ID                  string   `json:"Id"`
+	Name                string   `json:"Name"`
+	Destination         string   `json:"Destination"`
+	Context             string   `json:"Context"`
+	Protocol            string   `json:"Protocol"`
+	SubscriptionType    string   `json:"SubscriptionType"`
+	EventFormatType     string   `json:"EventFormatType"`
+	RegistryPrefixes    []string `json:"RegistryPrefixes"`
+	DeliveryRetryPolicy string   `json:"DeliveryRetryPolicy"`
+	HTTPHeaders         []any    `json:"HttpHeaders"`
+	Status              Status   `json:"Status"`
+}
+
+type EventDestinationCreateRequest struct {
+	Destination         string              `json:"Destination"`
+	Context             string              `json:"Context"`
+	Protocol            string              `json:"Protocol"`
+	SubscriptionType    string              `json:"SubscriptionType"`
+	EventFormatType     string              `json:"EventFormatType"`
+	RegistryPrefixes    []string            `json:"RegistryPrefixes"`
+	DeliveryRetryPolicy string              `json:"DeliveryRetryPolicy"`
+	HTTPHeaders         []map[string]string `json:"HttpHeaders"`
+}
+
+type EventServicePatchRequest struct {
+	DeliveryRetryAttempts        *int `json:"DeliveryRetryAttempts"`
+	DeliveryRetryIntervalSeconds *int `json:"DeliveryRetryIntervalSeconds"`
+}
+
+type SubmitTestEventRequest struct {
+	EventID           string   `json:"EventId"`
+	EventTimestamp    string   `json:"EventTimestamp"`
+	MessageID         string   `json:"MessageId"`
+	Message           string   `json:"Message"`
+	MessageArgs       []string `json:"MessageArgs"`
+	MessageSeverity   string   `json:"MessageSeverity"`
+	Severity          string   `json:"Severity"`
+	OriginOfCondition string   `json:"OriginOfCondition"`
+}
+
+// Event is the payload pushed to subscribers and written to SSE streams.
+type Event struct {
+	ODataType string        `json:"@odata.type"`
+	ID        string        `json:"Id"`
+	Name      string        `json:"Name"`
+	Context   string        `json:"Context,omitempty"`
+	Events    []EventRecord `json:"Events"`
+}
+
+type EventRecord struct {
+	MemberID          string   `json:"MemberId"`
+	EventType         string   `json:"EventType"`
+	EventID           string   `json:"EventId"`
+	EventTimestamp    string   `json:"EventTimestamp"`
+	MessageID         string   `json:"MessageId"`
+	Message           string   `json:"Message"`
+	MessageArgs       []string `json:"MessageArgs"`
+	MessageSeverity   string   `json:"MessageSeverity"`
+	Severity          string   `json:"Severity"`
+	OriginOfCondition *Link    `json:"OriginOfCondition,omitempty"`
+}
+
+var eventHTTPClient = &http.Client{Timeout: 30 * time.Second}
+
+const eventQueueSize = 64
+
+type eventSubscription struct {
+	id               string
+	destination      string
+	context          string
+	registryPrefixes []string
+	retryPolicy      string
+	headers          map[string]string
+	queue            chan Event
+	done             chan struct{}
+}
+
+// matches reports whether the subscription wants message. An empty
+// RegistryPrefixes list subscribes to every registry.
+func (s *eventSubscription) matches(record EventRecord) bool {
+	if len(s.registryPrefixes) == 0 {
+		return true
+	}
+	prefix, _, _ := strings.Cut(record.MessageID, ".")
+	for _, wanted := range s.registryPrefixes {
+		if wanted == prefix {
+			return true
+		}
+	}
+	return false
+}
+
+// eventService fans events out to push subscriptions and SSE streams. Each
+// subscription has its own delivery goroutine, so a slow or failing
+// subscriber delays only its own events, which arrive in order.
+type eventService struct {
+	sync.Mutex
+	nextEventID        int
+	nextSubscriptionID int
+	retryAttempts      int
+	retryInterval      time.Duration
+	subscriptions      map[string]*eventSubscription
+	streams            map[chan Event]bool
+	clock              timeSource
+}
+
+// newEventService returns an event service that waits between delivery
+// retries on clock.
+func newEventService(config EventServiceConfig, clock timeSource) *eventService {
+	return &eventService{
+		clock:              clock,
+		nextEventID:        1,
+		nextSubscriptionID: 1,
+		retryAttempts:      config.DeliveryRetryAttempts,
+		retryInterval:      time.Duration(config.DeliveryRetryIntervalSeconds) * time.Second,
+		subscriptions:      map[string]*eventSubscription{},
+		streams:            map[chan Event]bool{},
+	}
+}
+
+// publish sends message to every matching subscriber. origin is the URI of
+// the resource the event is about and may be empty.
+func (s *eventService) publish(message Message, origin string, now time.Time) {
+	s.Lock()
+	record := EventRecord{
+		MemberID:        "0",
+		EventType:       "Other",
+		EventID:         strconv.Itoa(s.nextEventID),
+		EventTimestamp:  now.UTC().Format(time.RFC3339),
+		MessageID:       message.MessageID,
+		Message:         message.Message,
+		MessageArgs:     message.MessageArgs,
+		MessageSeverity: message.MessageSeverity,
+		Severity:        message.Severity,
+	}
+	if origin != "" {
+		record.OriginOfCondition = &Link{ODataID: origin}
+	}
+	s.nextEventID++
+	s.publishRecord(record)
+	s.Unlock()
+}
+
+// publishRecord queues record for subscribers and streams. The caller must
+// hold the lock. Events for a full queue are dropped rather than blocking the
+// request that caused them.
+func (s *eventService) publishRecord(record EventRecord) {
+	event := Event{ODataType: "#Event.v1_7_0.Event", ID: record.EventID, Name: "Event Array", Events: []EventRecord{record}}
+	for _, subscription := range s.subscriptions {
+		if !subscription.matches(record) {
+			continue
+		}
+		event.Context = subscription.context
+		select {
+		case subscription.queue <- event:
+		default:
+			log.Printf("event subscription %s: queue full, dropping event %s", subscription.id, record.EventID)
+		}
+	}
+	event.Context = ""
+	for stream := range s.streams {
+		select {
+		case stream <- event:
+		default:
+		}
+	}
+}
+
+func (s *eventService) subscribe(subscription *eventSubscription) {
+	s.Lock()
+	subscription.id = strconv.Itoa(s.nextSubscriptionID)
+	subscription.queue = make(chan Event, eventQueueSize)
+	subscription.done = make(chan struct{})
+	s.nextSubscriptionID++
+	s.subscriptions[subscription.id] = subscription
+	s.Unlock()
+	go s.deliver(subscription)
+}
+
+func (s *eventService) unsubscribe(id string) bool {
+	s.Lock()
+	defer s.Unlock()
+	subscription, ok := s.subscriptions[id]
+	if !ok {
+		return false
+	}
+	delete(s.subscriptions, id)
+	close(subscription.done)
+	return true
+}
+
+// reset removes every subscription and restores the configured retry
+// settings. Open SSE streams stay connected.
+func (s *eventService) reset(config EventServiceConfig) {
+	s.Lock()
+	defer s.Unlock()
+	for id, subscription := range s.subscriptions {
+		delete(s.subscriptions, id)
+		close(subscription.done)
+	}
+	s.nextEventID = 1
+	s.nextSubscriptionID = 1
+	s.retryAttempts = config.DeliveryRetryAttempts
+	s.retryInterval = time.Duration(config.DeliveryRetryIntervalSeconds) * time.Second
+}
+
+func (s *eventService) get(id string) (eventSubscription, bool) {
+	s.Lock()
+	defer s.Unlock()
+	subscription, ok := s.subscriptions[id]
+	if !ok {
+		return eventSubscription{}, false
+	}
+	return *subscription, true
+}
+
+func (s *eventService) list() []string {
+	s.Lock()
+	defer s.Unlock()
+	ids := make([]string, 0, len(s.subscriptions))
+	for id := range s.subscriptions {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		left, _ := strconv.Atoi(ids[i])
+		right, _ := strconv.Atoi(ids[j])
+		return left < right
+	})
+	return ids
+}
+
+func (s *eventService) retrySettings() (int, time.Duration) {
+	s.Lock()
+	defer s.Unlock()
+	return s.retryAttempts, s.retryInterval
+}
+
+func (s *eventService) openStream() chan Event {
+	stream := make(chan Event, eventQueueSize)
+	s.Lock()
+	s.streams[stream] = true
+	s.Unlock()
+	return stream
+}
+
+func (s *eventService) closeStream(stream chan Event) {
+	s.Lock()
+	delete(s.streams, stream)
+	s.Unlock()
+}
+
+// deliver POSTs queued events to the subscription's destination until it is
+// deleted. A failed POST is retried DeliveryRetryAttempts times, waiting
+// DeliveryRetryIntervalSeconds between tries. After that the subscription is
+// removed, unless its policy is RetryForever.
+func (s *eventService) deliver(subscription *eventSubscription) {
+	for {
+		select {
+		case <-subscription.done:
+			return
+		case event := <-subscription.queue:
+			for attempt := 0; ; attempt++ {
+				err := postEvent(subscription, event)
+				if err == nil {
+					break
+				}
+				select {
+				case <-subscription.done:
+					return
+				default:
+				}
+				attempts, interval := s.retrySettings()
+				if attempt >= attempts && subscription.retryPolicy != "RetryForever" {
+					log.Printf("event subscription %s: %v; giving up after %d retries", subscription.id, err, attempts)
+					s.unsubscribe(subscription.id)
+					return
+				}
+				wake, stop := s.clock.after(interval)
+				select {
+				case <-subscription.done:
+					stop()
+					return
+				case err := <-wake:
+					if err != nil {
+						return
+					}
+				}
+			}
+		}
+	}
+}
+
+func postEvent(subscription *eventSubscription, event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-subscription.done:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.destination, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range subscription.headers {
+		req.Header.Set(name, value)
+	}
+	resp, err := eventHTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("destination returned %s", resp.Status)
+	}
+	return nil
+}
+
+func subscriptionResource(subscription eventSubscription) EventDestination {
+	prefixes := subscription.registryPrefixes
+	if prefixes == nil {
+		prefixes = []string{}
+	}
+	return EventDestination{
+		ODataContext:        "/redfish/v1/$metadata#EventDestination.EventDestination",
+		ODataType:           "#EventDestination.v1_13_0.EventDestination",
+		ODataID:             "/redfish/v1/EventService/Subscriptions/" + subscription.id,
+		ID:                  subscription.id,
+		Name:                "Event Subscription",
+		Destination:         subscription.destination,
+		Context:             subscription.context,
+		Protocol:            "Redfish",
+		SubscriptionType:    "RedfishEvent",
+		EventFormatType:     "Event",
+		RegistryPrefixes:    prefixes,
+		DeliveryRetryPolicy: subscription.retryPolicy,
+		Status:              Status{State: "Enabled", Health: "OK"},
+	}
+}
+
+func registryPrefixes() []string {
+	prefixes := make([]string, 0, len(messageRegistries))
+	for prefix := range messageRegistries {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Strings(prefixes)
+	return prefixes
+}
+
+func (b *mockBMC) getEventService(c *gin.Context) {
+	c.Header("OData-Version", "4.0")
+	attempts, interval := b.events.retrySettings()
+	service := EventService{
+		ODataContext:                 "/redfish/v1/$metadata#EventService.EventService",
+		ODataType:                    "#EventService.v1_10_0.EventService",
+		ODataID:                      "/redfish/v1/EventService",
+		ID:                           "EventService",
+		Name:                         "Event Service",
+		ServiceEnabled:               true,
+		DeliveryRetryAttempts:        attempts,
+		DeliveryRetryIntervalSeconds: int(interval / time.Second),
+		EventFormatTypes:             []string{"Event"},
+		RegistryPrefixes:             registryPrefixes(),
+		ServerSentEventURI:           "/redfish/v1/EventService/SSE",
+		Subscriptions:                Link{ODataID: "/redfish/v1/EventService/Subscriptions"},
+		Actions: EventServiceActions{
+			SubmitTestEvent: SubmitTestEventAction{Target: "/redfish/v1/EventService/Actions/EventService.SubmitTestEvent"},
+		},
+		Status: Status{State: "Enabled", Health: "OK"},
+	}
+	c.JSON(http.StatusOK, service)
+}
+
+func (b *mockBMC) patchEventService(c *gin.Context) {
+	c.Header("OData-Version", "4.0")
+	var req EventServicePatchRequest
+	if !bindRedfishJSON(c, &req) {
+		return
+	}
+	if req.DeliveryRetryAttempts != nil && *req.DeliveryRetryAttempts < 0 {
+		redfishError(c, http.StatusBadRequest, baseMessage("PropertyValueOutOfRange", strconv.Itoa(*req.DeliveryRetryAttempts), "DeliveryRetryAttempts"))
+		return
+	}
+	if req.DeliveryRetryIntervalSeconds != nil && *req.DeliveryRetryIntervalSeconds < 0 {
+		redfishError(c, http.StatusBadRequest, baseMessage("PropertyValueOutOfRange", strconv.Itoa(*req.DeliveryRetryIntervalSeconds), "DeliveryRetryIntervalSeconds"))
+		return
+	}
+
+	b.events.Lock()
+	if req.DeliveryRetryAttempts != nil {
+		b.events.retryAttempts = *req.DeliveryRetryAttempts
+	}
+	if req.DeliveryRetryIntervalSeconds != nil {
+		b.events.retryInterval = time.Duration(*req.DeliveryRetryIntervalSeconds) * time.Second
+	}
+	b.events.Unlock()
+
+	c.Status(http.StatusNoContent)
+}
+
+func (b *mockBMC) getSubscriptionsCollection(c *gin.Context) {
+	c.Header("OData-Version", "4.0")
+	ids := b.events.list()
+	members := make([]Link, 0, len(ids))
+	for _, id := range ids {
+		members = append(members, Link{ODataID: "/redfish/v1/EventService/Subscriptions/" + id})
+	}
+	collection := Collection{
+		ODataContext: "/redfish/v1/$metadata#EventDestinationCollection.EventDestinationCollection",
+		ODataType:    "#EventDestinationCollection.EventDestinationCollection",
+		ODataID:      "/redfish/v1/EventService/Subscriptions",
+		Name:         "Event Subscriptions Collection",
+		MembersCount: len(members),
+		Members:      members,
+	}
+	c.JSON(http.StatusOK, collection)
+}
+
+func (b *mockBMC) createSubscription(c *gin.Context) {
+	c.Header("OData-Version", "4.0")
+	var req EventDestinationCreateRequest
+	if !bindRedfishJSON(c, &req) {
+		return
+	}
+	if req.Destination == "" {
+		redfishError(c, http.StatusBadRequest, baseMessage("PropertyMissing", "Destination"))
+		return
+	}
+	destination, err := url.ParseRequestURI(req.Destination)
+	if err != nil || (destination.Scheme != "http" && destination.Scheme != "https") || destination.Host == "" {
+		redfishError(c, http.StatusBadRequest, baseMessage("PropertyValueFormatError", req.Destination, "Destination"))
+		return
+	}
+	for _, field := range []struct{ name, value, allowed string }{
+		{"Protocol", req.Protocol, "Redfish"},
+		{"SubscriptionType", req.SubscriptionType, "RedfishEvent"},
+		{"EventFormatType", req.EventFormatType, "Event"},
+	} {
+		if field.value != "" && field.value != field.allowed {
+			redfishError(c, http.StatusBadRequest, baseMessage("PropertyValueNotInList", field.value, field.name))
+			return
+		}
+	}
+	retryPolicy := req.DeliveryRetryPolicy
+	switch retryPolicy {
+	case "":
+		retryPolicy = "TerminateAfterRetries"
+	case "TerminateAfterRetries", "RetryForever":
+	default:
+		redfishError(c, http.StatusBadRequest, baseMessage("PropertyValueNotInList", retryPolicy, "DeliveryRetryPolicy"))
+		return
+	}
+	for _, prefix := range req.RegistryPrefixes {
+		if _, ok := messageRegistries[prefix]; !ok {
+			redfishError(c, http.StatusBadRequest, baseMessage("PropertyValueNotInList", prefix, "RegistryPrefixes"))
+			return
+		}
+	}
+	headers := map[string]string{}
+	for _, header := range req.HTTPHeaders {
+		for name, value := range header {
+			headers[name] = value
+		}
+	}
+
+	subscription := &eventSubscription{
+		destination:      req.Destination,
+		context:          req.Context,
+		registryPrefixes: req.RegistryPrefixes,
+		retryPolicy:      retryPolicy,
+		headers:          headers,
+	}
+	b.events.subscribe(subscription)
+	resource := subscriptionResource(*subscription)
+	c.Header("Location", resource.ODataID)
+	c.JSON(http.StatusCreated, resource)
+}
+
+func (b *mockBMC) getSubscription(c *gin.Context) {
+	c.Header("OData-Version", "4.0")
+	subscription, ok := b.events.get(c.Param("id"))
+	if !ok {
+		resourceNotFound(c, "EventDestination", c.Param("id"))
+		return
+	}
+	c.JSON(http.StatusOK, subscriptionResource(subscription))
+}
+
+func (b *mockBMC) deleteSubscription(c *gin.Context) {
+	c.Header("OData-Version", "4.0")
+	if !b.events.unsubscribe(c.Param("id")) {
+		resourceNotFound(c, "EventDestination", c.Param("id"))
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// submitTestEvent publishes a caller-described event. A MessageId from one of
+// the embedded registries fills in the message text and severity.
+func (b *mockBMC) submitTestEvent(c *gin.Context) {
+	c.Header("OData-Version", "4.0")
+	var req SubmitTestEventRequest
+	if !bindRedfishJSON(c, &req) {
+		return
+	}
+	if req.MessageID == "" {
+		redfishError(c, http.StatusBadRequest, baseMessage("ActionParameterMissing", "EventService.SubmitTestEvent", "MessageId"))
+		return
+	}
+
+	message := Message{MessageID: req.MessageID, MessageArgs: req.MessageArgs, MessageSeverity: "OK"}
+	prefix, _, _ := strings.Cut(req.MessageID, ".")
+	id := req.MessageID[strings.LastIndex(req.MessageID, ".")+1:]
+	if registry, ok := messageRegistries[prefix]; ok {
+		if _, ok := registry.Messages[id]; ok {
+			message = registryMessage(prefix, id, req.MessageArgs...)
+		}
+	}
+	if req.Message != "" {
+		message.Message = req.Message
+	}
+	if req.MessageSeverity != "" {
+		message.MessageSeverity = req.MessageSeverity
+	}
+	message.Severity = message.MessageSeverity
+	if req.Severity != "" {
+		message.Severity = req.Severity
+	}
+	if message.MessageArgs == nil {
+		message.MessageArgs = []string{}
+	}
+
+	b.events.Lock()
+	record := EventRecord{
+		MemberID:        "0",
+		EventType:       "Other",
+		EventID:         req.EventID,
+		EventTimestamp:  req.EventTimestamp,
+		MessageID:       message.MessageID,
+		Message:         message.Message,
+		MessageArgs:     message.MessageArgs,
+		MessageSeverity: message.MessageSeverity,
+		Severity:        message.Severity,
+	}
+	if record.EventID == "" {
+		record.EventID = strconv.Itoa(b.events.nextEventID)
+		b.events.nextEventID++
+	}
+	if record.EventTimestamp == "" {
+		record.EventTimestamp = b.now().UTC().Format(time.RFC3339)
+	}
+	if req.OriginOfCondition != "" {
+		record.OriginOfCondition = &Link{ODataID: req.OriginOfCondition}
+	}
+	b.events.publishRecord(record)
+	b.events.Unlock()
+
+	c.Status(http.StatusNoContent)
+}
+
+// streamEvents serves the Redfish SSE stream: every event is written as one
+// "data:" line holding the Event JSON, with the EventId as the SSE id.
+func (b *mockBMC) streamEvents(c *gin.Context) {
+	stream := b.events.openStream()
+	defer b.events.closeStream(stream)
+
+	c.Header("OData-Version", "4.0")
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case event := <-stream:
+			body, err := json.Marshal(event)
+			if err != nil {
+				continue
+			}
+			if _, err := fmt.Fprintf(c.Writer, "id: %s\ndata: %s\n\n", event.ID, body); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func eventReceiver(t *testing.T, status int) (*httptest.Server, chan Event) {
+	t.Helper()
+	received := make(chan Event, 16)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event Event
+		if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+			t.Errorf("decode pushed event: %v", err)
+		}
+		if r.Header.Get("X-Subscriber") != "ci" {
+			t.Errorf("X-Subscriber = %q", r.Header.Get("X-Subscriber"))
+		}
+		received <- event
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+	return server, received
+}
+
+func nextEvent(t *testing.T, events chan Event) Event {
+	t.Helper()
+	select {
+	case event := <-events:
+		return event
+	case <-time.After(5 * time.Second):
+		t.Fatal("no event delivered")
+		return Event{}
+	}
+}
+
+func TestSubscriptionReceivesStateChangeEvents(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	bmc := newTestBMC(t, defaultConfig())
+	router := newRouter(bmc)
+	admin, password := bmc.config.Authentication.Username, bmc.config.Authentication.Password
+	receiver, received := eventReceiver(t, http.StatusOK)
+
+	recorder := accountRequest(router, http.MethodPost, "/redfish/v1/EventService/Subscriptions",
+		`{"Destination":"ftp://example.test/events","Protocol":"Redfish"}`, admin, password)
+	if recorder.Code != http.StatusBadRequest || !strings.Contains(recorder.Body.String(), "PropertyValueFormatError") {
+		t.Fatalf("ftp destination status = %d, body = %s", recorder.Code, recorder.Body.String())
+	}
+	recorder = accountRequest(router, http.MethodPost, "/redfish/v1/EventService/Subscriptions",
+		`{"Destination":"`+receiver.URL+`","Protocol":"Redfish","Context":"ci-run","RegistryPrefixes":["ResourceEvent"],"HttpHeaders":[{"X-Subscriber":"ci"}]}`, admin, password)
+	if recorder.Code != http.StatusCreated || recorder.Header().Get("Location") != "/redfish/v1/EventService/Subscriptions/1" {
+		t.Fatalf("subscribe status = %d, body = %s", recorder.Code, recorder.Body.String())
+	}
+
+	// Test events from other registries are filtered out by RegistryPrefixes.
+	if recorder := accountRequest(router, http.MethodPost, "/redfish/v1/EventService/Actions/EventService.SubmitTestEvent",
+		`{"MessageId":"Update.1.0.2.UpdateSuccessful","MessageArgs":["BIOS","bios.bin"]}`, admin, password); recorder.Code != http.StatusNoContent {
+		t.Fatalf("SubmitTestEvent status = %d, body = %s", recorder.Code, recorder.Body.String())
+	}
+	if recorder := accountRequest(router, http.MethodPatch, "/redfish/v1/Systems/1", `{"Boot":{"BootSourceOverrideTarget":"Pxe"}}`, admin, password); recorder.Code != http.StatusNoContent {
+		t.Fatalf("patch system status = %d", recorder.Code)
+	}
+	event := nextEvent(t, received)
+	if event.Context != "ci-run" || len(event.Events) != 1 {
+		t.Fatalf("event = %+v", event)
+	}
+	record := event.Events[0]
+	if record.MessageID != "ResourceEvent.1.3.0.ResourceChanged" || record.OriginOfCondition == nil || record.OriginOfCondition.ODataID != "/redfish/v1/Systems/1" {
+		t.Fatalf("record = %+v", record)
+	}
+
+	if recorder := accountRequest(router, http.MethodPost, "/redfish/v1/Systems/1/Actions/ComputerSystem.Reset", `{"ResetType":"ForceOff"}`, admin, password); recorder.Code != http.StatusNoContent {
+		t.Fatalf("reset status = %d", recorder.Code)
+	}
+	if record := nextEvent(t, received).Events[0]; record.MessageID != "ResourceEvent.1.3.0.ResourceChanged" {
+		t.Fatalf("first reset event = %+v", record)
+	}
+	if record := nextEvent(t, received).Events[0]; record.MessageID != "ResourceEvent.1.3.0.ResourcePoweredOff" || record.MessageArgs[0] != "/redfish/v1/Systems/1" {
+		t.Fatalf("second reset event = %+v", record)
+	}
+
+	if recorder := accountRequest(router, http.MethodDelete, "/redfish/v1/EventService/Subscriptions/1", "", admin, password); recorder.Code != http.StatusNoContent {
+		t.Fatalf("unsubscribe status = %d", recorder.Code)
+	}
+	if recorder := accountRequest(router, http.MethodGet, "/redfish/v1/EventService/Subscriptions/1", "", admin, password); recorder.Code != http.StatusNotFound {
+		t.Fatalf("deleted subscription status = %d", recorder.Code)
+	}
+}
+
+func TestSubscriptionTerminatedAfterRetries(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	config := defaultConfig()
+	config.EventService.DeliveryRetryAttempts = 2
+	config.EventService.DeliveryRetryIntervalSeconds = 0
+	bmc := newTestBMC(t, config)
+	router := newRouter(bmc)
+	admin, password := bmc.config.Authentication.Username, bmc.config.Authentication.Password
+	receiver, received := eventReceiver(t, http.StatusServiceUnavailable)
+
+	recorder := accountRequest(router, http.MethodPost, "/redfish/v1/EventService/Subscriptions",
+		`{"Destination":"`+receiver.URL+`","HttpHeaders":[{"X-Subscriber":"ci"}]}`, admin, password)
+	if recorder.Code != http.StatusCreated {
+		t.Fatalf("subscribe status = %d, body = %s", recorder.Code, recorder.Body.String())
+	}
+	if recorder := accountRequest(router, http.MethodPost, "/redfish/v1/EventService/Actions/EventService.SubmitTestEvent",
+		`{"MessageId":"Base.1.16.0.Success"}`, admin, password); recorder.Code != http.StatusNoContent {
+		t.Fatalf("SubmitTestEvent status = %d", recorder.Code)
+	}
+	for attempt := 0; attempt < 3; attempt++ {
+		if event := nextEvent(t, received); event.Events[0].Message != "The request completed successfully." {
+			t.Fatalf("attempt %d event = %+v", attempt, event)
+		}
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for len(bmc.events.list()) != 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("subscription was not removed after its retries ran out")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestServerSentEventStream(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	bmc := newTestBMC(t, defaultConfig())
+	server := httptest.NewServer(newRouter(bmc))
+	defer server.Close()
+	admin, password := bmc.config.Authentication.Username, bmc.config.Authentication.Password
+
+	request, _ := http.NewRequest(http.MethodGet, server.URL+"/redfish/v1/EventService/SSE", nil)
+	request.SetBasicAuth(admin, password)
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK || response.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("SSE status = %d, content type = %q", response.StatusCode, response.Header.Get("Content-Type"))
+	}
+
+	request, _ = http.NewRequest(http.MethodPost, server.URL+"/redfish/v1/Managers/1/VirtualMedia/CD/Actions/VirtualMedia.EjectMedia", strings.NewReader(`{}`))
+	request.SetBasicAuth(admin, password)
+	ejected, err := http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ejected.Body.Close()
+
+	lines := bufio.NewScanner(response.Body)
+	var id, data string
+	for lines.Scan() && lines.Text() != "" {
+		field, value, _ := strings.Cut(lines.Text(), ": ")
+		switch field {
+		case "id":
+			id = value
+		case "data":
+			data = value
+		}
+	}
+	var event Event
+	if err := json.Unmarshal([]byte(data), &event); err != nil {
+		t.Fatalf("decode SSE data %q: %v", data, err)
+	}
+	if id != event.ID || event.Events[0].OriginOfCondition.ODataID != "/redfish/v1/Managers/1/VirtualMedia/CD" {
+		t.Fatalf("SSE id = %q, event = %+v", id, event)
+	}
+}
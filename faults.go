@@ -0,0 +1,423 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Fault types that can be injected through the faults config section or the
+// /mock/faults API.
+const (
+	faultFanFailed                 = "fan_failed"
+	faultPowerSupplyRedundancyLost = "power_supply_redundancy_lost"
+	faultOverTemperature           = "over_temperature"
+	faultMemoryECCErrors           = "memory_ecc_errors"
+	faultProcessorDegraded         = "processor_degraded"
+)
+
+// overTemperatureMargin is how far above its critical threshold an
+// overheating sensor reads.
+const overTemperatureMargin = 5
+
+// FaultConfig describes one hardware fault. Fan, power supply, and
+// temperature faults name a chassis and one of its sensors; memory and
+// processor faults name a system and one of its DIMMs or processors. An empty
+// ID or component selects the first one.
+type FaultConfig struct {
+	Type      string `json:"type"`
+	ChassisID string `json:"chassis_id,omitempty"`
+	SystemID  string `json:"system_id,omitempty"`
+	Component string `json:"component,omitempty"`
+}
+
+// Fault is an active fault as reported by the /mock/faults API.
+type Fault struct {
+	ID string `json:"id"`
+	FaultConfig
+	Resource   string `json:"resource"`
+	Health     string `json:"health"`
+	InjectedAt string `json:"injected_at"`
+}
+
+// faultHealth is the Health a faulted component reports.
+var faultHealth = map[string]string{
+	faultFanFailed:                 "Critical",
+	faultPowerSupplyRedundancyLost: "Critical",
+	faultOverTemperature:           "Critical",
+	faultMemoryECCErrors:           "Warning",
+	faultProcessorDegraded:         "Warning",
+}
+
+var healthRank = map[string]int{"OK": 0, "Warning": 1, "Critical": 2}
+
+// faultPropertyError reports a fault property whose value names nothing the
+// mock serves.
+type faultPropertyError struct {
+	property string
+	value    string
+}
+
+func (e *faultPropertyError) Error() string {
+	return fmt.Sprintf("%s %q is not valid", e.property, e.value)
+}
+
+// resolveFault fills in the default chassis or system and component of fault
+// and returns the URI of the faulted component.
+func resolveFault(config Config, fault FaultConfig) (FaultConfig, string, error) {
+	switch fault.Type {
+	case faultFanFailed, faultPowerSupplyRedundancyLost, faultOverTemperature:
+		if len(config.ChassisMembers) == 0 {
+			return FaultConfig{}, "", &faultPropertyError{"chassis_id", fault.ChassisID}
+		}
+		if fault.ChassisID == "" {
+			fault.ChassisID = config.ChassisMembers[0].ID
+		}
+		index := slices.IndexFunc(config.ChassisMembers, func(chassis ChassisConfig) bool { return chassis.ID == fault.ChassisID })
+		if index < 0 || fault.SystemID != "" {
+			return FaultConfig{}, "", &faultPropertyError{"chassis_id", fault.ChassisID}
+		}
+		telemetry := config.ChassisMembers[index].Telemetry
+		var names []string
+		collection := "/redfish/v1/Chassis/" + fault.ChassisID
+		switch fault.Type {
+		case faultFanFailed:
+			collection += "/ThermalSubsystem/Fans/"
+			for _, fan := range telemetry.Fans {
+				names = append(names, fan.Name)
+			}
+		case faultPowerSupplyRedundancyLost:
+			collection += "/PowerSubsystem/PowerSupplies/"
+			for _, supply := range telemetry.PowerSupplies {
+				names = append(names, supply.Name)
+			}
+		default:
+			collection += "/Sensors/"
+			for _, temperature := range telemetry.Temperatures {
+				names = append(names, temperature.Name)
+			}
+		}
+		for _, name := range names {
+			if fault.Component == "" || fault.Component == name || fault.Component == sensorID(name) {
+				fault.Component = sensorID(name)
+				return fault, collection + fault.Component, nil
+			}
+		}
+		return FaultConfig{}, "", &faultPropertyError{"component", fault.Component}
+	case faultMemoryECCErrors, faultProcessorDegraded:
+		if len(config.Systems) == 0 {
+			return FaultConfig{}, "", &faultPropertyError{"system_id", fault.SystemID}
+		}
+		if fault.SystemID == "" {
+			fault.SystemID = config.Systems[0].ID
+		}
+		index := slices.IndexFunc(config.Systems, func(system SystemConfig) bool { return system.ID == fault.SystemID })
+		if index < 0 || fault.ChassisID != "" {
+			return FaultConfig{}, "", &faultPropertyError{"system_id", fault.SystemID}
+		}
+		ids, collection := memoryIDs(config.Systems[index]), "/Memory/"
+		if fault.Type == faultProcessorDegraded {
+			ids, collection = processorIDs(config.Systems[index]), "/Processors/"
+		}
+		for _, id := range ids {
+			if fault.Component == "" || fault.Component == id {
+				fault.Component = id
+				return fault, "/redfish/v1/Systems/" + fault.SystemID + collection + id, nil
+			}
+		}
+		return FaultConfig{}, "", &faultPropertyError{"component", fault.Component}
+	default:
+		return FaultConfig{}, "", &faultPropertyError{"type", fault.Type}
+	}
+}
+
+// validateFaults checks the faults config section, including that no
+// component has the same fault twice.
+func validateFaults(config Config) error {
+	seen := map[string]bool{}
+	for i, fault := range config.Faults {
+		resolved, resource, err := resolveFault(config, fault)
+		if err != nil {
+			return fmt.Errorf("faults[%d]: %w", i, err)
+		}
+		if seen[resolved.Type+" "+resource] {
+			return fmt.Errorf("faults[%d]: %s is already injected on %s", i, resolved.Type, resource)
+		}
+		seen[resolved.Type+" "+resource] = true
+	}
+	return nil
+}
+
+type mockFault struct {
+	id         string
+	config     FaultConfig
+	resource   string
+	injectedAt time.Time
+}
+
+type faultStore struct {
+	sync.Mutex
+	nextID int
+	faults []mockFault
+}
+
+func newFaultStore() *faultStore {
+	return &faultStore{nextID: 1}
+}
+
+// add records a fault unless the component already has one of that type.
+func (s *faultStore) add(config FaultConfig, resource string, now time.Time) (mockFault, bool) {
+	s.Lock()
+	defer s.Unlock()
+	for _, fault := range s.faults {
+		if fault.config.Type == config.Type && fault.resource == resource {
+			return mockFault{}, false
+		}
+	}
+	fault := mockFault{id: strconv.Itoa(s.nextID), config: config, resource: resource, injectedAt: now}
+	s.nextID++
+	s.faults = append(s.faults, fault)
+	return fault, true
+}
+
+func (s *faultStore) remove(id string) (mockFault, bool) {
+	s.Lock()
+	defer s.Unlock()
+	for i, fault := range s.faults {
+		if fault.id == id {
+			s.faults = slices.Delete(s.faults, i, i+1)
+			return fault, true
+		}
+	}
+	return mockFault{}, false
+}
+
+// reset clears every fault and starts fault IDs over.
+func (s *faultStore) reset() {
+	s.Lock()
+	defer s.Unlock()
+	s.nextID = 1
+	s.faults = nil
+}
+
+func (s *faultStore) list() []mockFault {
+	s.Lock()
+	defer s.Unlock()
+	return append([]mockFault(nil), s.faults...)
+}
+
+func (s *faultStore) get(id string) (mockFault, bool) {
+	s.Lock()
+	defer s.Unlock()
+	for _, fault := range s.faults {
+		if fault.id == id {
+			return fault, true
+		}
+	}
+	return mockFault{}, false
+}
+
+// has reports whether the component at resource has a fault of faultType.
+func (s *faultStore) has(faultType, resource string) bool {
+	s.Lock()
+	defer s.Unlock()
+	for _, fault := range s.faults {
+		if fault.config.Type == faultType && fault.resource == resource {
+			return true
+		}
+	}
+	return false
+}
+
+// health returns the Health of the component at resource.
+func (s *faultStore) health(resource string) string {
+	return s.rollup(func(fault mockFault) bool { return fault.resource == resource })
+}
+
+// rollup returns the worst Health of the faults that match, or "OK".
+func (s *faultStore) rollup(match func(mockFault) bool) string {
+	s.Lock()
+	defer s.Unlock()
+	worst := "OK"
+	for _, fault := range s.faults {
+		if health := faultHealth[fault.config.Type]; match(fault) && healthRank[health] > healthRank[worst] {
+			worst = health
+		}
+	}
+	return worst
+}
+
+// rollupUnder returns the worst Health of the components below any of uris.
+func (s *faultStore) rollupUnder(uris ...string) string {
+	return s.rollup(func(fault mockFault) bool {
+		return slices.ContainsFunc(uris, func(uri string) bool { return strings.HasPrefix(fault.resource, uri+"/") })
+	})
+}
+
+// systemHealthRollup returns the worst Health of a system's components and of
+// the chassis that contains it, whose fans and power supplies it depends on.
+func (b *mockBMC) systemHealthRollup(system SystemConfig) string {
+	return b.faults.rollupUnder("/redfish/v1/Systems/"+system.ID, "/redfish/v1/Chassis/"+system.ChassisID)
+}
+
+// chassisHealthRollup returns the worst Health of a chassis's components and
+// of the systems it contains.
+func (b *mockBMC) chassisHealthRollup(chassisID string) string {
+	uris := []string{"/redfish/v1/Chassis/" + chassisID}
+	for _, system := range b.config.Systems {
+		if system.ChassisID == chassisID {
+			uris = append(uris, "/redfish/v1/Systems/"+system.ID)
+		}
+	}
+	return b.faults.rollupUnder(uris...)
+}
+
+// rollupOf returns the worst Health of the chassis's faults of the given types.
+func (s *faultStore) rollupOf(chassisID string, types ...string) string {
+	return s.rollup(func(fault mockFault) bool {
+		return fault.config.ChassisID == chassisID && slices.Contains(types, fault.config.Type)
+	})
+}
+
+// configuredFault is a fault from the config with its defaults filled in,
+// kept so that a reset can inject it again.
+type configuredFault struct {
+	config   FaultConfig
+	resource string
+}
+
+// injectFault activates a fault, logs it, and sends the matching event.
+func (b *mockBMC) injectFault(config FaultConfig, now time.Time) (mockFault, error) {
+	resolved, resource, err := resolveFault(b.config, config)
+	if err != nil {
+		return mockFault{}, err
+	}
+	return b.injectResolvedFault(resolved, resource, now)
+}
+
+// injectResolvedFault activates a fault that resolveFault has accepted.
+func (b *mockBMC) injectResolvedFault(resolved FaultConfig, resource string, now time.Time) (mockFault, error) {
+	fault, ok := b.faults.add(resolved, resource, now)
+	if !ok {
+		return mockFault{}, errFaultExists
+	}
+	var message Message
+	switch resolved.Type {
+	case faultOverTemperature:
+		chassis, _ := b.findChassis(resolved.ChassisID)
+		threshold := 0.0
+		for _, temperature := range chassis.Telemetry.Temperatures {
+			if sensorID(temperature.Name) == resolved.Component {
+				threshold = temperature.UpperThresholdCritical
+			}
+		}
+		message = registryMessage("ResourceEvent", "ResourceErrorThresholdExceeded", resolved.Component, strconv.FormatFloat(threshold, 'f', -1, 64))
+	case faultMemoryECCErrors:
+		message = registryMessage("ResourceEvent", "ResourceErrorsDetected", resolved.Component, "CorrectableECC")
+	case faultProcessorDegraded:
+		message = registryMessage("ResourceEvent", "ResourceStatusChangedWarning", resource, "Warning")
+	default:
+		message = registryMessage("ResourceEvent", "ResourceStatusChangedCritical", resource, "Critical")
+	}
+	b.logFaultEvent(fault, message, now)
+	return fault, nil
+}
+
+var errFaultExists = errors.New("fault is already injected")
+
+// clearFault removes a fault and reports the component healthy again.
+func (b *mockBMC) clearFault(id string, now time.Time) bool {
+	fault, ok := b.faults.remove(id)
+	if !ok {
+		return false
+	}
+	b.logFaultEvent(fault, registryMessage("ResourceEvent", "ResourceStatusChangedOK", fault.resource, "OK"), now)
+	return true
+}
+
+// logFaultEvent records a fault change in the SEL of the affected systems.
+// A chassis without systems records it in its manager's log instead.
+func (b *mockBMC) logFaultEvent(fault mockFault, message Message, now time.Time) {
+	if fault.config.SystemID != "" {
+		b.logSystemEvent(fault.config.SystemID, message, fault.resource, now)
+		return
+	}
+	logged := false
+	for _, system := range b.config.Systems {
+		if system.ChassisID == fault.config.ChassisID {
+			b.systemLogs[system.ID].append(message, fault.resource, now)
+			logged = true
+		}
+	}
+	if !logged {
+		chassis, _ := b.findChassis(fault.config.ChassisID)
+		b.managerLogs[chassis.ManagerID].append(message, fault.resource, now)
+	}
+	b.events.publish(message, fault.resource, now)
+}
+
+func faultResource(fault mockFault) Fault {
+	return Fault{
+		ID:          fault.id,
+		FaultConfig: fault.config,
+		Resource:    fault.resource,
+		Health:      faultHealth[fault.config.Type],
+		InjectedAt:  fault.injectedAt.UTC().Format(time.RFC3339),
+	}
+}
+
+func (b *mockBMC) getFaults(c *gin.Context) {
+	faults := b.faults.list()
+	resources := make([]Fault, 0, len(faults))
+	for _, fault := range faults {
+		resources = append(resources, faultResource(fault))
+	}
+	c.JSON(http.StatusOK, gin.H{"faults": resources})
+}
+
+func (b *mockBMC) getFault(c *gin.Context) {
+	fault, ok := b.faults.get(c.Param("id"))
+	if !ok {
+		resourceNotFound(c, "Fault", c.Param("id"))
+		return
+	}
+	c.JSON(http.StatusOK, faultResource(fault))
+}
+
+func (b *mockBMC) createFault(c *gin.Context) {
+	var req FaultConfig
+	if !bindRedfishJSON(c, &req) {
+		return
+	}
+	if req.Type == "" {
+		redfishError(c, http.StatusBadRequest, baseMessage("PropertyMissing", "type"))
+		return
+	}
+	fault, err := b.injectFault(req, b.now())
+	var propertyErr *faultPropertyError
+	switch {
+	case errors.As(err, &propertyErr):
+		redfishError(c, http.StatusBadRequest, baseMessage("PropertyValueNotInList", propertyErr.value, propertyErr.property))
+		return
+	case errors.Is(err, errFaultExists):
+		redfishError(c, http.StatusConflict, baseMessage("ResourceAlreadyExists", "Fault", "type", req.Type))
+		return
+	}
+	c.Header("Location", "/mock/faults/"+fault.id)
+	c.JSON(http.StatusCreated, faultResource(fault))
+}
+
+func (b *mockBMC) deleteFault(c *gin.Context) {
+	if !b.clearFault(c.Param("id"), b.now()) {
+		resourceNotFound(c, "Fault", c.Param("id"))
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestInjectedFaultsRollUpAndLog(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	bmc := newTestBMC(t, defaultConfig())
+	router := newRouter(bmc)
+	admin, password := bmc.config.Authentication.Username, bmc.config.Authentication.Password
+	get := func(path string, response any) {
+		t.Helper()
+		recorder := accountRequest(router, http.MethodGet, path, "", admin, password)
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("GET %s status = %d", path, recorder.Code)
+		}
+		if err := json.Unmarshal(recorder.Body.Bytes(), response); err != nil {
+			t.Fatalf("decode %s: %v", path, err)
+		}
+	}
+
+	recorder := accountRequest(router, http.MethodPost, "/mock/faults", `{"type":"fan_failed","component":"System Fan 2"}`, admin, password)
+	if recorder.Code != http.StatusCreated || recorder.Header().Get("Location") != "/mock/faults/1" {
+		t.Fatalf("inject status = %d, body = %s", recorder.Code, recorder.Body.String())
+	}
+	var fault Fault
+	if err := json.Unmarshal(recorder.Body.Bytes(), &fault); err != nil {
+		t.Fatal(err)
+	}
+	const fanURI = "/redfish/v1/Chassis/1/ThermalSubsystem/Fans/SystemFan2"
+	if fault.ChassisID != "1" || fault.Component != "SystemFan2" || fault.Resource != fanURI || fault.Health != "Critical" {
+		t.Fatalf("fault = %+v", fault)
+	}
+	if recorder := accountRequest(router, http.MethodPost, "/mock/faults", `{"type":"fan_failed","component":"SystemFan2"}`, admin, password); recorder.Code != http.StatusConflict {
+		t.Fatalf("duplicate fault status = %d", recorder.Code)
+	}
+	if recorder := accountRequest(router, http.MethodPost, "/mock/faults", `{"type":"fan_failed","component":"Fan 9"}`, admin, password); recorder.Code != http.StatusBadRequest ||
+		!strings.Contains(recorder.Body.String(), "PropertyValueNotInList") {
+		t.Fatalf("unknown fan status = %d, body = %s", recorder.Code, recorder.Body.String())
+	}
+
+	var fan Fan
+	get(fanURI, &fan)
+	if fan.Status.Health != "Critical" || fan.SpeedPercent.SpeedRPM != 0 {
+		t.Fatalf("failed fan = %+v", fan)
+	}
+	var chassis Chassis
+	get("/redfish/v1/Chassis/1", &chassis)
+	if chassis.Status.Health != "OK" || chassis.Status.HealthRollup != "Critical" {
+		t.Fatalf("chassis status = %+v", chassis.Status)
+	}
+	var entry LogEntry
+	get("/redfish/v1/Systems/1/LogServices/SEL/Entries/1", &entry)
+	if entry.MessageID != "ResourceEvent.1.3.0.ResourceStatusChangedCritical" || entry.Severity != "Critical" || entry.Links.OriginOfCondition.ODataID != fanURI {
+		t.Fatalf("fan fault entry = %+v", entry)
+	}
+
+	if recorder := accountRequest(router, http.MethodPost, "/mock/faults", `{"type":"processor_degraded","component":"CPU2"}`, admin, password); recorder.Code != http.StatusCreated {
+		t.Fatalf("inject processor fault status = %d, body = %s", recorder.Code, recorder.Body.String())
+	}
+	// The system rolls up the failed fan of its chassis.
+	var system ComputerSystem
+	get("/redfish/v1/Systems/1", &system)
+	if system.Status.HealthRollup != "Critical" || system.ProcessorSummary.Status.HealthRollup != "Warning" || system.MemorySummary.Status.HealthRollup != "OK" {
+		t.Fatalf("system status = %+v, processors = %+v", system.Status, system.ProcessorSummary.Status)
+	}
+	var processor Processor
+	get("/redfish/v1/Systems/1/Processors/CPU2", &processor)
+	if processor.Status.Health != "Warning" {
+		t.Fatalf("processor = %+v", processor)
+	}
+
+	if recorder := accountRequest(router, http.MethodDelete, "/mock/faults/1", "", admin, password); recorder.Code != http.StatusNoContent {
+		t.Fatalf("clear status = %d", recorder.Code)
+	}
+	// The chassis still rolls up the degraded processor of its system.
+	get("/redfish/v1/Chassis/1", &chassis)
+	if chassis.Status.HealthRollup != "Warning" {
+		t.Fatalf("chassis status after clear = %+v", chassis.Status)
+	}
+	get("/redfish/v1/Systems/1", &system)
+	if system.Status.HealthRollup != "Warning" {
+		t.Fatalf("system status after clear = %+v", system.Status)
+	}
+	get("/redfish/v1/Systems/1/LogServices/SEL/Entries/3", &entry)
+	if entry.MessageID != "ResourceEvent.1.3.0.ResourceStatusChangedOK" || entry.Links.OriginOfCondition.ODataID != fanURI {
+		t.Fatalf("clear entry = %+v", entry)
+	}
+	var faults struct {
+		Faults []Fault `json:"faults"`
+	}
+	get("/mock/faults", &faults)
+	if len(faults.Faults) != 1 || faults.Faults[0].ID != "2" {
+		t.Fatalf("faults = %+v", faults)
+	}
+}
+
+func TestConfiguredFaultsAffectReadings(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	config := defaultConfig()
+	config.ChassisMembers[0].Telemetry.DriftPercent = 0
+	config.Faults = []FaultConfig{
+		{Type: faultPowerSupplyRedundancyLost, Component: "PSU2"},
+		{Type: faultOverTemperature, Component: "CPU1Temp"},
+		{Type: faultMemoryECCErrors},
+	}
+	if err := validateFaults(config); err != nil {
+		t.Fatalf("validateFaults: %v", err)
+	}
+	bmc := newTestBMC(t, config)
+	router := newRouter(bmc)
+	admin, password := bmc.config.Authentication.Username, bmc.config.Authentication.Password
+
+	var power Power
+	recorder := accountRequest(router, http.MethodGet, "/redfish/v1/Chassis/1/Power", "", admin, password)
+	if err := json.Unmarshal(recorder.Body.Bytes(), &power); err != nil {
+		t.Fatal(err)
+	}
+	if power.PowerSupplies[0].LastPowerOutputWatts != 320 || power.PowerSupplies[1].LastPowerOutputWatts != 0 || power.PowerSupplies[1].Status.Health != "Critical" {
+		t.Fatalf("power supplies = %+v", power.PowerSupplies)
+	}
+	if len(power.Redundancy) != 1 || power.Redundancy[0].Status.Health != "Warning" || len(power.Redundancy[0].RedundancySet) != 2 {
+		t.Fatalf("power redundancy = %+v", power.Redundancy)
+	}
+	var subsystem PowerSubsystem
+	recorder = accountRequest(router, http.MethodGet, "/redfish/v1/Chassis/1/PowerSubsystem", "", admin, password)
+	if err := json.Unmarshal(recorder.Body.Bytes(), &subsystem); err != nil {
+		t.Fatal(err)
+	}
+	if len(subsystem.PowerSupplyRedundancy) != 1 || subsystem.PowerSupplyRedundancy[0].Status.Health != "Warning" ||
+		subsystem.PowerSupplyRedundancy[0].MinNeededInGroup != 1 {
+		t.Fatalf("power supply redundancy = %+v", subsystem.PowerSupplyRedundancy)
+	}
+	var sensor Sensor
+	recorder = accountRequest(router, http.MethodGet, "/redfish/v1/Chassis/1/Sensors/CPU1Temp", "", admin, password)
+	if err := json.Unmarshal(recorder.Body.Bytes(), &sensor); err != nil {
+		t.Fatal(err)
+	}
+	if sensor.Reading != 100 || sensor.Status.Health != "Critical" {
+		t.Fatalf("CPU1Temp = %+v", sensor)
+	}
+	var memory Memory
+	recorder = accountRequest(router, http.MethodGet, "/redfish/v1/Systems/1/Memory/DIMM1", "", admin, password)
+	if err := json.Unmarshal(recorder.Body.Bytes(), &memory); err != nil {
+		t.Fatal(err)
+	}
+	if memory.Status.Health != "Warning" || memory.CapacityMiB != 16384 {
+		t.Fatalf("DIMM1 = %+v", memory)
+	}
+	bmc.reset()
+	if faults := bmc.faults.list(); len(faults) != 3 || faults[2].config.Component != "DIMM1" {
+		t.Fatalf("faults after reset = %+v", faults)
+	}
+
+	config.Faults = append(config.Faults, FaultConfig{Type: faultMemoryECCErrors, Component: "DIMM1"})
+	if err := validateFaults(config); err == nil {
+		t.Fatal("validateFaults accepted a duplicate fault")
+	}
+	config.Faults = []FaultConfig{{Type: "disk_failed"}}
+	if err := validateFaults(config); err == nil || !strings.Contains(err.Error(), "type") {
+		t.Fatalf("validateFaults unknown type = %v", err)
+	}
+	if _, err := newMockBMC(config); err == nil || !strings.Contains(err.Error(), "faults[0]") {
+		t.Fatalf("newMockBMC unknown fault type = %v", err)
+	}
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// firmwareMetadataPrefix marks an optional first line in a firmware image that
+// carries JSON update metadata, for example:
+//
+//	REDFISH-MOCK-FIRMWARE {"version":"2.0.0","targets":["BIOS"]}
+const firmwareMetadataPrefix = "REDFISH-MOCK-FIRMWARE "
+
+var firmwareVersionPattern = regexp.MustCompile(`\d+(?:\.\d+)+`)
+
+type firmwareMetadata struct {
+	Version    string   `json:"version"`
+	SoftwareID string   `json:"software_id"`
+	Targets    []string `json:"targets"`
+}
+
+type firmwareUpdate struct {
+	targets    []string
+	version    string
+	softwareID string
+}
+
+type firmwareState struct {
+	sync.RWMutex
+	inventory []FirmwareItemConfig
+	updated   map[string]FirmwareItemConfig
+}
+
+func newFirmwareState(inventory []FirmwareItemConfig) *firmwareState {
+	return &firmwareState{inventory: inventory, updated: map[string]FirmwareItemConfig{}}
+}
+
+func (f *firmwareState) current() []FirmwareItemConfig {
+	f.RLock()
+	defer f.RUnlock()
+	items := make([]FirmwareItemConfig, 0, len(f.inventory))
+	for _, item := range f.inventory {
+		if updated, ok := f.updated[item.ID]; ok {
+			item = updated
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+// reset discards every applied update.
+func (f *firmwareState) reset() {
+	f.Lock()
+	defer f.Unlock()
+	f.updated = map[string]FirmwareItemConfig{}
+}
+
+func (f *firmwareState) find(id string) (FirmwareItemConfig, bool) {
+	f.RLock()
+	defer f.RUnlock()
+	return f.findLocked(id)
+}
+
+// findLocked returns an inventory item with its applied updates. The caller
+// must hold the lock.
+func (f *firmwareState) findLocked(id string) (FirmwareItemConfig, bool) {
+	if item, ok := f.updated[id]; ok {
+		return item, true
+	}
+	for _, item := range f.inventory {
+		if item.ID == id {
+			return item, true
+		}
+	}
+	return FirmwareItemConfig{}, false
+}
+
+// apply holds the write lock for the whole update so that updates finishing
+// together do not overwrite each other's changes.
+func (f *firmwareState) apply(update firmwareUpdate) {
+	f.Lock()
+	defer f.Unlock()
+	for _, id := range update.targets {
+		item, ok := f.findLocked(id)
+		if !ok {
+			continue
+		}
+		softwareID := update.softwareID
+		if softwareID == "" {
+			softwareID = item.ID + "-" + update.version
+			if item.Version != "" && strings.HasSuffix(item.SoftwareID, item.Version) {
+				softwareID = strings.TrimSuffix(item.SoftwareID, item.Version) + update.version
+			}
+		}
+		item.Version = update.version
+		item.SoftwareID = softwareID
+		f.updated[item.ID] = item
+	}
+}
+
+// targetIDs converts SimpleUpdate Targets URIs to inventory IDs. It
+// returns the first target that is not an updateable inventory member.
+func (f *firmwareState) targetIDs(targets []string) ([]string, string) {
+	ids := make([]string, 0, len(targets))
+	for _, target := range targets {
+		id := strings.TrimPrefix(strings.TrimSuffix(target, "/"), "/redfish/v1/UpdateService/FirmwareInventory/")
+		item, ok := f.find(id)
+		if !ok || !item.Updateable {
+			return nil, target
+		}
+		ids = append(ids, item.ID)
+	}
+	return ids, ""
+}
+
+func downloadFirmwareImage(ctx context.Context, imageURI, username, password string) (*firmwareMetadata, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, imageURI, nil)
+	if err != nil {
+		return nil, fmt.Errorf("create image request: %w", err)
+	}
+	if username != "" || password != "" {
+		req.SetBasicAuth(username, password)
+	}
+
+	response, err := firmwareHTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("download image: %w", err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode < http.StatusOK || response.StatusCode >= http.StatusMultipleChoices {
+		return nil, fmt.Errorf("download image: server returned %s", response.Status)
+	}
+
+	reader := bufio.NewReader(response.Body)
+	metadata, err := readFirmwareMetadata(reader)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.Copy(io.Discard, reader); err != nil {
+		return nil, fmt.Errorf("download image: %w", err)
+	}
+	return metadata, nil
+}
+
+func readFirmwareMetadata(reader *bufio.Reader) (*firmwareMetadata, error) {
+	prefix, err := reader.Peek(len(firmwareMetadataPrefix))
+	if err != nil || string(prefix) != firmwareMetadataPrefix {
+		return nil, nil
+	}
+	line, err := reader.ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("download image: %w", err)
+	}
+	var metadata firmwareMetadata
+	if err := json.Unmarshal([]byte(strings.TrimPrefix(line, firmwareMetadataPrefix)), &metadata); err != nil {
+		return nil, fmt.Errorf("invalid firmware metadata header: %w", err)
+	}
+	return &metadata, nil
+}
+
+// resolveFirmwareUpdate decides which inventory entries an image updates and
+// to which version. Metadata embedded in the image wins over the configured
+// update_service.images mapping, which wins over the image file name.
+func (b *mockBMC) resolveFirmwareUpdate(imageURI string, requestedTargets []string, metadata *firmwareMetadata) (firmwareUpdate, error) {
+	fileName := imageURI
+	if parsedURI, err := url.Parse(imageURI); err == nil {
+		fileName = path.Base(parsedURI.Path)
+	}
+
+	var update firmwareUpdate
+	if metadata != nil {
+		update = firmwareUpdate{targets: metadata.Targets, version: metadata.Version, softwareID: metadata.SoftwareID}
+	}
+	for _, image := range b.config.UpdateService.Images {
+		if image.Image != imageURI && image.Image != fileName {
+			continue
+		}
+		if update.version == "" {
+			update.version = image.Version
+			update.softwareID = image.SoftwareID
+		}
+		if len(update.targets) == 0 {
+			update.targets = image.Targets
+		}
+		break
+	}
+	if update.version == "" {
+		update.version = firmwareVersionPattern.FindString(fileName)
+	}
+	if update.version == "" {
+		return firmwareUpdate{}, fmt.Errorf("cannot determine firmware version for image %q", fileName)
+	}
+
+	if len(requestedTargets) > 0 {
+		update.targets = requestedTargets
+	}
+	if len(update.targets) == 0 {
+		lowerName := strings.ToLower(fileName)
+		var match string
+		for _, item := range b.firmware.current() {
+			if item.Updateable && strings.Contains(lowerName, strings.ToLower(item.ID)) && len(item.ID) > len(match) {
+				match = item.ID
+			}
+		}
+		if match != "" {
+			update.targets = []string{match}
+		}
+	}
+	if len(update.targets) == 0 {
+		return firmwareUpdate{}, fmt.Errorf("cannot determine firmware target for image %q", fileName)
+	}
+	for _, target := range update.targets {
+		item, ok := b.firmware.find(target)
+		if !ok || !item.Updateable {
+			return firmwareUpdate{}, fmt.Errorf("image %q targets unknown or non-updateable firmware %q", fileName, target)
+		}
+	}
+	return update, nil
+}
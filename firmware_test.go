@@ -0,0 +1,117 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestResolveFirmwareUpdate(t *testing.T) {
+	config := defaultConfig()
+	config.UpdateService.Images = []FirmwareImageConfig{
+		{Image: "vendor-blob.bin", Version: "9.9.9", SoftwareID: "NIC-FW-999", Targets: []string{"NIC"}},
+	}
+	bmc := newTestBMC(t, config)
+
+	tests := []struct {
+		name     string
+		imageURI string
+		targets  []string
+		metadata *firmwareMetadata
+		want     firmwareUpdate
+		wantErr  bool
+	}{
+		{
+			name:     "file name",
+			imageURI: "https://example.com/images/BMC_2.2.0.bin",
+			want:     firmwareUpdate{targets: []string{"BMC"}, version: "2.2.0"},
+		},
+		{
+			name:     "requested targets",
+			imageURI: "https://example.com/images/update-1.2.3.bin",
+			targets:  []string{"BIOS", "NIC"},
+			want:     firmwareUpdate{targets: []string{"BIOS", "NIC"}, version: "1.2.3"},
+		},
+		{
+			name:     "configured mapping",
+			imageURI: "https://example.com/vendor-blob.bin",
+			want:     firmwareUpdate{targets: []string{"NIC"}, version: "9.9.9", softwareID: "NIC-FW-999"},
+		},
+		{
+			name:     "metadata header",
+			imageURI: "https://example.com/vendor-blob.bin",
+			metadata: &firmwareMetadata{Version: "3.0.0", Targets: []string{"BIOS"}},
+			want:     firmwareUpdate{targets: []string{"BIOS"}, version: "3.0.0"},
+		},
+		{name: "unknown version", imageURI: "https://example.com/bios.bin", wantErr: true},
+		{name: "unknown target", imageURI: "https://example.com/update-1.0.bin", wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			update, err := bmc.resolveFirmwareUpdate(test.imageURI, test.targets, test.metadata)
+			if test.wantErr {
+				if err == nil {
+					t.Fatalf("resolveFirmwareUpdate() = %#v, want error", update)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("resolveFirmwareUpdate() error = %v", err)
+			}
+			if strings.Join(update.targets, ",") != strings.Join(test.want.targets, ",") ||
+				update.version != test.want.version || update.softwareID != test.want.softwareID {
+				t.Fatalf("resolveFirmwareUpdate() = %#v, want %#v", update, test.want)
+			}
+		})
+	}
+}
+
+func TestSimpleUpdateChangesFirmwareInventory(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	config := defaultConfig()
+	config.UpdateService.UpdateDurationSeconds = 0
+	bmc := newTestBMC(t, config)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(firmwareMetadataPrefix + `{"version":"1.5.0"}` + "\nbinary payload"))
+	}))
+	defer server.Close()
+	router := newRouter(bmc)
+
+	request := httptest.NewRequest(http.MethodPost, "/redfish/v1/UpdateService/Actions/UpdateService.SimpleUpdate",
+		strings.NewReader(`{"ImageURI":"`+server.URL+`/image.bin","Targets":["/redfish/v1/UpdateService/FirmwareInventory/BIOS"]}`))
+	request.SetBasicAuth(bmc.config.Authentication.Username, bmc.config.Authentication.Password)
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusAccepted {
+		t.Fatalf("SimpleUpdate status = %d, want %d", recorder.Code, http.StatusAccepted)
+	}
+	if task := waitForTask(t, router, recorder.Header().Get("Location")); task.TaskState != "Completed" {
+		t.Fatalf("update task = %#v", task)
+	}
+
+	request = httptest.NewRequest(http.MethodGet, "/redfish/v1/UpdateService/FirmwareInventory/BIOS", nil)
+	request.SetBasicAuth(bmc.config.Authentication.Username, bmc.config.Authentication.Password)
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+	var firmware SoftwareInventory
+	if err := json.Unmarshal(recorder.Body.Bytes(), &firmware); err != nil {
+		t.Fatalf("decode firmware response: %v", err)
+	}
+	if firmware.Version != "1.5.0" || firmware.SoftwareId != "BIOS-1.5.0" {
+		t.Fatalf("updated firmware = %s / %s, want 1.5.0 / BIOS-1.5.0", firmware.Version, firmware.SoftwareId)
+	}
+
+	request = httptest.NewRequest(http.MethodPost, "/redfish/v1/UpdateService/Actions/UpdateService.SimpleUpdate",
+		strings.NewReader(`{"ImageURI":"`+server.URL+`/image.bin","Targets":["/redfish/v1/UpdateService/FirmwareInventory/CPLD"]}`))
+	request.SetBasicAuth(bmc.config.Authentication.Username, bmc.config.Authentication.Password)
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusBadRequest {
+		t.Fatalf("unknown target status = %d, want %d", recorder.Code, http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+)
+
+// FleetConfig describes many mock BMCs served from one process. Either list
+// each BMC under bmcs, or set count and template to clone one config file.
+type FleetConfig struct {
+	Host     string           `json:"host"`
+	Port     int              `json:"port"`
+	Spread   string           `json:"spread"`
+	Count    int              `json:"count"`
+	Template string           `json:"template"`
+	BMCs     []FleetBMCConfig `json:"bmcs"`
+}
+
+type FleetBMCConfig struct {
+	Config string `json:"config"`
+	Host   string `json:"host"`
+	Port   int    `json:"port"`
+}
+
+type fleetMember struct {
+	addr   string
+	config Config
+}
+
+func defaultFleetConfig() FleetConfig {
+	return FleetConfig{Host: "127.0.0.1", Port: 9000, Spread: "port"}
+}
+
+func loadFleet(path string) ([]fleetMember, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	fleet := defaultFleetConfig()
+	decoder := json.NewDecoder(bytes.NewReader(contents))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&fleet); err != nil {
+		return nil, fmt.Errorf("decode fleet: %w", err)
+	}
+	return fleet.members(filepath.Dir(path))
+}
+
+// members loads the config of every BMC in the fleet. Relative config paths
+// are resolved against dir.
+func (f FleetConfig) members(dir string) ([]fleetMember, error) {
+	if f.Spread != "port" && f.Spread != "address" {
+		return nil, errors.New(`fleet spread must be "port" or "address"`)
+	}
+	if f.Port < 1 || f.Port > 65535 {
+		return nil, errors.New("fleet port must be between 1 and 65535")
+	}
+	if (f.Count > 0) == (len(f.BMCs) > 0) {
+		return nil, errors.New("fleet needs either count and template or a bmcs list")
+	}
+
+	var members []fleetMember
+	if f.Count > 0 {
+		if f.Template == "" {
+			return nil, errors.New("fleet template is required with count")
+		}
+		template, err := loadConfig(resolvePath(dir, f.Template))
+		if err != nil {
+			return nil, fmt.Errorf("load fleet template %q: %w", f.Template, err)
+		}
+		for i := 0; i < f.Count; i++ {
+			config, err := personalizeConfig(template, i)
+			if err != nil {
+				return nil, err
+			}
+			addr, err := f.address(i, "", 0)
+			if err != nil {
+				return nil, err
+			}
+			members = append(members, fleetMember{addr: addr, config: config})
+		}
+	}
+	for i, bmc := range f.BMCs {
+		if bmc.Config == "" {
+			return nil, fmt.Errorf("fleet bmcs[%d].config is required", i)
+		}
+		config, err := loadConfig(resolvePath(dir, bmc.Config))
+		if err != nil {
+			return nil, fmt.Errorf("load fleet bmcs[%d] config %q: %w", i, bmc.Config, err)
+		}
+		addr, err := f.address(i, bmc.Host, bmc.Port)
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, fleetMember{addr: addr, config: config})
+	}
+
+	seen := map[string]bool{}
+	for _, member := range members {
+		if seen[member.addr] {
+			return nil, fmt.Errorf("fleet listen address %s is used more than once", member.addr)
+		}
+		seen[member.addr] = true
+	}
+	return members, nil
+}
+
+// address returns the listen address of the index'th BMC. With the "port"
+// spread every BMC shares the host on consecutive ports; with "address" every
+// BMC shares the port on consecutive IP addresses, such as 127.0.0.1,
+// 127.0.0.2, and so on.
+func (f FleetConfig) address(index int, host string, port int) (string, error) {
+	if host == "" {
+		host = f.Host
+		if f.Spread == "address" {
+			ip, err := netip.ParseAddr(f.Host)
+			if err != nil {
+				return "", fmt.Errorf("fleet host must be an IP address with the address spread: %w", err)
+			}
+			for i := 0; i < index; i++ {
+				ip = ip.Next()
+			}
+			if !ip.IsValid() {
+				return "", errors.New("fleet ran out of IP addresses")
+			}
+			host = ip.String()
+		}
+	}
+	if port == 0 {
+		port = f.Port
+		if f.Spread == "port" {
+			port += index
+		}
+	}
+	if port > 65535 {
+		return "", fmt.Errorf("fleet port %d is out of range", port)
+	}
+	return net.JoinHostPort(host, strconv.Itoa(port)), nil
+}
+
+func resolvePath(dir, path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
+
+// personalizeConfig gives the index'th copy of a template its own service
+// root UUID and serial numbers so fleet tools see distinct BMCs.
+func personalizeConfig(template Config, index int) (Config, error) {
+	config := template
+	uuid := template.ServiceRoot.UUID
+	if len(uuid) != 36 || uuid[23] != '-' {
+		return Config{}, fmt.Errorf("fleet template service_root.uuid %q is not a UUID", uuid)
+	}
+	config.ServiceRoot.UUID = uuid[:24] + fmt.Sprintf("%012x", index+1)
+
+	suffix := fmt.Sprintf("-%04d", index+1)
+	config.System.SerialNumber += suffix
+	config.Chassis.SerialNumber += suffix
+	config.Systems = slices.Clone(template.Systems)
+	for i := range config.Systems {
+		config.Systems[i].SerialNumber += suffix
+	}
+	config.ChassisMembers = slices.Clone(template.ChassisMembers)
+	for i := range config.ChassisMembers {
+		config.ChassisMembers[i].SerialNumber += suffix
+	}
+	return config, nil
+}
+
+// runFleet serves every member until one listener fails. With useTLS each
+// member serves HTTPS, using certFile and keyFile or a self-signed
+// certificate for its own listen host.
+func runFleet(members []fleetMember, useTLS bool, certFile, keyFile string) error {
+	errs := make(chan error, len(members))
+	for _, member := range members {
+		bmc, err := newMockBMC(member.config)
+		if err != nil {
+			return fmt.Errorf("%s: %w", member.addr, err)
+		}
+		listen := listenOptions{addr: member.addr}
+		scheme := "http"
+		if useTLS {
+			host, _, _ := net.SplitHostPort(member.addr)
+			certificate, err := serverCertificate(certFile, keyFile, host)
+			if err != nil {
+				return fmt.Errorf("%s: %w", member.addr, err)
+			}
+			bmc.certificates.install(certificate)
+			listen.tlsConfig = bmc.certificates.tlsConfig()
+			scheme = "https"
+		}
+		handler := newRouter(bmc)
+		go func() {
+			errs <- listen.serve(handler)
+		}()
+		log.Printf("Starting %s mock BMC on %s://%s", member.config.OEM, scheme, member.addr)
+	}
+	return <-errs
+}
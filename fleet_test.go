@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func writeFleetFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, contents := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(contents), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestLoadFleetFromTemplate(t *testing.T) {
+	dir := writeFleetFiles(t, map[string]string{
+		"template.json": `{"oem":"supermicro","systems":[{"id":"Node1","serial_number":"TWIN-A"},{"id":"Node2","serial_number":"TWIN-B"}]}`,
+		"fleet.json":    `{"count":3,"template":"template.json","port":9100}`,
+	})
+	members, err := loadFleet(filepath.Join(dir, "fleet.json"))
+	if err != nil {
+		t.Fatalf("loadFleet() error = %v", err)
+	}
+	if len(members) != 3 || members[0].addr != "127.0.0.1:9100" || members[2].addr != "127.0.0.1:9102" {
+		t.Fatalf("members = %#v", members)
+	}
+	uuids := map[string]bool{}
+	serials := map[string]bool{}
+	for _, member := range members {
+		if member.config.OEM != "supermicro" {
+			t.Fatalf("member OEM = %q", member.config.OEM)
+		}
+		uuids[member.config.ServiceRoot.UUID] = true
+		for _, system := range member.config.Systems {
+			serials[system.SerialNumber] = true
+		}
+	}
+	if len(uuids) != 3 || len(serials) != 6 || !serials["TWIN-B-0003"] {
+		t.Fatalf("UUIDs = %v, serials = %v, want distinct values per BMC", uuids, serials)
+	}
+
+	gin.SetMode(gin.TestMode)
+	first, second := newTestBMC(t, members[0].config), newTestBMC(t, members[1].config)
+	request := httptest.NewRequest(http.MethodPost, "/redfish/v1/Systems/Node1/Actions/ComputerSystem.Reset", strings.NewReader(`{"ResetType":"ForceOff"}`))
+	request.SetBasicAuth(first.config.Authentication.Username, first.config.Authentication.Password)
+	recorder := httptest.NewRecorder()
+	newRouter(first).ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusNoContent {
+		t.Fatalf("reset status = %d", recorder.Code)
+	}
+
+	request = httptest.NewRequest(http.MethodGet, "/redfish/v1/Systems/Node1", nil)
+	request.SetBasicAuth(second.config.Authentication.Username, second.config.Authentication.Password)
+	recorder = httptest.NewRecorder()
+	newRouter(second).ServeHTTP(recorder, request)
+	var system ComputerSystem
+	if err := json.Unmarshal(recorder.Body.Bytes(), &system); err != nil {
+		t.Fatalf("decode system: %v", err)
+	}
+	if system.PowerState != "On" || system.SerialNumber != "TWIN-A-0002" {
+		t.Fatalf("second BMC system = %s %s, want its own state", system.PowerState, system.SerialNumber)
+	}
+}
+
+func TestLoadFleetFromList(t *testing.T) {
+	dir := writeFleetFiles(t, map[string]string{
+		"dell.json":  `{"oem":"dell"}`,
+		"cisco.json": `{"oem":"cisco"}`,
+		"fleet.json": `{"spread":"address","port":8443,"bmcs":[{"config":"dell.json"},{"config":"cisco.json"},{"config":"dell.json","port":9443}]}`,
+	})
+	members, err := loadFleet(filepath.Join(dir, "fleet.json"))
+	if err != nil {
+		t.Fatalf("loadFleet() error = %v", err)
+	}
+	want := []struct{ addr, oem string }{
+		{"127.0.0.1:8443", "dell"},
+		{"127.0.0.2:8443", "cisco"},
+		{"127.0.0.3:9443", "dell"},
+	}
+	if len(members) != len(want) {
+		t.Fatalf("members = %#v", members)
+	}
+	for i, member := range members {
+		if member.addr != want[i].addr || member.config.OEM != want[i].oem {
+			t.Fatalf("member %d = %s %s, want %s %s", i, member.addr, member.config.OEM, want[i].addr, want[i].oem)
+		}
+	}
+}
+
+func TestLoadFleetRejectsInvalidDescriptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		fleet   string
+		wantErr string
+	}{
+		{name: "count and list", fleet: `{"count":2,"template":"bmc.json","bmcs":[{"config":"bmc.json"}]}`, wantErr: "either count"},
+		{name: "empty", fleet: `{}`, wantErr: "either count"},
+		{name: "duplicate address", fleet: `{"bmcs":[{"config":"bmc.json","port":9001},{"config":"bmc.json"}]}`, wantErr: "used more than once"},
+		{name: "spread", fleet: `{"count":2,"template":"bmc.json","spread":"random"}`, wantErr: "spread"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := writeFleetFiles(t, map[string]string{"bmc.json": `{}`, "fleet.json": test.fleet})
+			if _, err := loadFleet(filepath.Join(dir, "fleet.json")); err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Fatalf("loadFleet() error = %v, want %q", err, test.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,344 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type LogService struct {
+	ODataContext       string            `json:"@odata.context"`
+	ODataType          string            `json:"@odata.type"`
+	ODataID            string            `json:"@odata.id"`
+	ID                 string            `json:"Id"`
+	Name               string            `json:"Name"`
+	Description        string            `json:"Description"`
+	ServiceEnabled     bool              `json:"ServiceEnabled"`
+	LogEntryType       string            `json:"LogEntryType"`
+	MaxNumberOfRecords int               `json:"MaxNumberOfRecords"`
+	OverWritePolicy    string            `json:"OverWritePolicy"`
+	DateTime           string            `json:"DateTime"`
+	Entries            Link              `json:"Entries"`
+	Actions            LogServiceActions `json:"Actions"`
+	Status             Status            `json:"Status"`
+}
+
+type LogServiceActions struct {
+	ClearLog ClearLogAction `json:"#LogService.ClearLog"`
+}
+
+type ClearLogAction struct {
+	Target string `json:"target"`
+}
+
+type LogEntry struct {
+	ODataContext string        `json:"@odata.context,omitempty"`
+	ODataType    string        `json:"@odata.type"`
+	ODataID      string        `json:"@odata.id"`
+	ID           string        `json:"Id"`
+	Name         string        `json:"Name"`
+	EntryType    string        `json:"EntryType"`
+	Severity     string        `json:"Severity"`
+	Created      string        `json:"Created"`
+	MessageID    string        `json:"MessageId"`
+	Message      string        `json:"Message"`
+	MessageArgs  []string      `json:"MessageArgs"`
+	Links        LogEntryLinks `json:"Links"`
+}
+
+type LogEntryLinks struct {
+	OriginOfCondition *Link `json:"OriginOfCondition,omitempty"`
+}
+
+// LogEntryCollection embeds the entries themselves, as BMCs do, so a
+// collector can read a log page by page without fetching every entry.
+type LogEntryCollection struct {
+	ODataContext string     `json:"@odata.context"`
+	ODataType    string     `json:"@odata.type"`
+	ODataID      string     `json:"@odata.id"`
+	Name         string     `json:"Name"`
+	MembersCount int        `json:"Members@odata.count"`
+	Members      []LogEntry `json:"Members"`
+	NextLink     string     `json:"Members@odata.nextLink,omitempty"`
+}
+
+// maxLogEntries bounds each log; the oldest entries are dropped first.
+const maxLogEntries = 1000
+
+type mockLogEntry struct {
+	id      string
+	message Message
+	origin  string
+	created time.Time
+}
+
+type logService struct {
+	sync.Mutex
+	id          string
+	name        string
+	description string
+	entryType   string
+	nextID      int
+	entries     []mockLogEntry
+}
+
+func newSystemLog() *logService {
+	return &logService{id: "SEL", name: "System Event Log", description: "System power, boot, and installation events", entryType: "SEL", nextID: 1}
+}
+
+func newManagerLog() *logService {
+	return &logService{id: "Lclog", name: "Lifecycle Controller Log", description: "Manager configuration and virtual media events", entryType: "Event", nextID: 1}
+}
+
+func (l *logService) append(message Message, origin string, now time.Time) {
+	l.Lock()
+	defer l.Unlock()
+	l.entries = append(l.entries, mockLogEntry{id: strconv.Itoa(l.nextID), message: message, origin: origin, created: now})
+	l.nextID++
+	if len(l.entries) > maxLogEntries {
+		l.entries = l.entries[len(l.entries)-maxLogEntries:]
+	}
+}
+
+func (l *logService) list() []mockLogEntry {
+	l.Lock()
+	defer l.Unlock()
+	return append([]mockLogEntry(nil), l.entries...)
+}
+
+func (l *logService) get(id string) (mockLogEntry, bool) {
+	l.Lock()
+	defer l.Unlock()
+	for _, entry := range l.entries {
+		if entry.id == id {
+			return entry, true
+		}
+	}
+	return mockLogEntry{}, false
+}
+
+// clear removes every entry. Entry IDs keep counting, so a collector never
+// sees an ID reused for a different entry.
+func (l *logService) clear() {
+	l.Lock()
+	defer l.Unlock()
+	l.entries = nil
+}
+
+// reset empties the log and starts entry IDs over, as on a fresh BMC.
+func (l *logService) reset() {
+	l.Lock()
+	defer l.Unlock()
+	l.entries = nil
+	l.nextID = 1
+}
+
+func newSystemLogs(systems []SystemConfig) map[string]*logService {
+	logs := make(map[string]*logService, len(systems))
+	for _, system := range systems {
+		logs[system.ID] = newSystemLog()
+	}
+	return logs
+}
+
+func newManagerLogs(managers []ManagerConfig) map[string]*logService {
+	logs := make(map[string]*logService, len(managers))
+	for _, manager := range managers {
+		logs[manager.ID] = newManagerLog()
+	}
+	return logs
+}
+
+// logSystemEvent appends message to the system's SEL and sends it to event
+// subscribers.
+func (b *mockBMC) logSystemEvent(systemID string, message Message, origin string, now time.Time) {
+	b.systemLogs[systemID].append(message, origin, now)
+	b.events.publish(message, origin, now)
+}
+
+// logManagerEvent appends message to the manager's lifecycle log and sends it
+// to event subscribers.
+func (b *mockBMC) logManagerEvent(managerID string, message Message, origin string, now time.Time) {
+	b.managerLogs[managerID].append(message, origin, now)
+	b.events.publish(message, origin, now)
+}
+
+// lookupLogServices resolves the system or manager of a LogServices request
+// and returns its log with the URI of its LogServices collection.
+func (b *mockBMC) lookupLogServices(c *gin.Context) (*logService, string, bool) {
+	id := c.Param("id")
+	if strings.HasPrefix(c.FullPath(), "/redfish/v1/Systems/") {
+		if _, ok := b.findSystem(id); !ok {
+			resourceNotFound(c, "ComputerSystem", id)
+			return nil, "", false
+		}
+		return b.systemLogs[id], "/redfish/v1/Systems/" + id + "/LogServices", true
+	}
+	if _, ok := b.findManager(id); !ok {
+		resourceNotFound(c, "Manager", id)
+		return nil, "", false
+	}
+	return b.managerLogs[id], "/redfish/v1/Managers/" + id + "/LogServices", true
+}
+
+func (b *mockBMC) lookupLogService(c *gin.Context) (*logService, string, bool) {
+	log, collectionURI, ok := b.lookupLogServices(c)
+	if !ok {
+		return nil, "", false
+	}
+	if c.Param("logID") != log.id {
+		resourceNotFound(c, "LogService", c.Param("logID"))
+		return nil, "", false
+	}
+	return log, collectionURI + "/" + log.id, true
+}
+
+func logEntryResource(entry mockLogEntry, entryType, serviceURI string) LogEntry {
+	resource := LogEntry{
+		ODataType:   "#LogEntry.v1_15_0.LogEntry",
+		ODataID:     serviceURI + "/Entries/" + entry.id,
+		ID:          entry.id,
+		Name:        "Log Entry " + entry.id,
+		EntryType:   entryType,
+		Severity:    entry.message.MessageSeverity,
+		Created:     entry.created.UTC().Format(time.RFC3339),
+		MessageID:   entry.message.MessageID,
+		Message:     entry.message.Message,
+		MessageArgs: entry.message.MessageArgs,
+	}
+	if entry.origin != "" {
+		resource.Links.OriginOfCondition = &Link{ODataID: entry.origin}
+	}
+	return resource
+}
+
+// pagingParameter reads a non-negative $top or $skip query parameter.
+func pagingParameter(c *gin.Context, name string) (int, bool, bool) {
+	raw, present := c.GetQuery(name)
+	if !present {
+		return 0, false, true
+	}
+	value, err := strconv.Atoi(raw)
+	if err != nil {
+		redfishError(c, http.StatusBadRequest, baseMessage("QueryParameterValueTypeError", raw, name))
+		return 0, false, false
+	}
+	if value < 0 {
+		redfishError(c, http.StatusBadRequest, baseMessage("QueryParameterOutOfRange", raw, name, "0 or greater"))
+		return 0, false, false
+	}
+	return value, true, true
+}
+
+func (b *mockBMC) getLogServicesCollection(c *gin.Context) {
+	c.Header("OData-Version", "4.0")
+	log, collectionURI, ok := b.lookupLogServices(c)
+	if !ok {
+		return
+	}
+	collection := Collection{
+		ODataContext: "/redfish/v1/$metadata#LogServiceCollection.LogServiceCollection",
+		ODataType:    "#LogServiceCollection.LogServiceCollection",
+		ODataID:      collectionURI,
+		Name:         "Log Service Collection",
+		MembersCount: 1,
+		Members:      []Link{{ODataID: collectionURI + "/" + log.id}},
+	}
+	c.JSON(http.StatusOK, collection)
+}
+
+func (b *mockBMC) getLogService(c *gin.Context) {
+	c.Header("OData-Version", "4.0")
+	log, serviceURI, ok := b.lookupLogService(c)
+	if !ok {
+		return
+	}
+	service := LogService{
+		ODataContext:       "/redfish/v1/$metadata#LogService.LogService",
+		ODataType:          "#LogService.v1_5_0.LogService",
+		ODataID:            serviceURI,
+		ID:                 log.id,
+		Name:               log.name,
+		Description:        log.description,
+		ServiceEnabled:     true,
+		LogEntryType:       log.entryType,
+		MaxNumberOfRecords: maxLogEntries,
+		OverWritePolicy:    "WrapsWhenFull",
+		DateTime:           b.now().UTC().Format(time.RFC3339),
+		Entries:            Link{ODataID: serviceURI + "/Entries"},
+		Actions: LogServiceActions{
+			ClearLog: ClearLogAction{Target: serviceURI + "/Actions/LogService.ClearLog"},
+		},
+		Status: Status{State: "Enabled", Health: "OK"},
+	}
+	c.JSON(http.StatusOK, service)
+}
+
+// getLogEntries returns the log oldest entry first. $skip and $top select a
+// page; Members@odata.nextLink points at the next page while one remains.
+func (b *mockBMC) getLogEntries(c *gin.Context) {
+	c.Header("OData-Version", "4.0")
+	log, serviceURI, ok := b.lookupLogService(c)
+	if !ok {
+		return
+	}
+	skip, _, ok := pagingParameter(c, "$skip")
+	if !ok {
+		return
+	}
+	top, limited, ok := pagingParameter(c, "$top")
+	if !ok {
+		return
+	}
+
+	entries := log.list()
+	page := entries[min(skip, len(entries)):]
+	if limited && top < len(page) {
+		page = page[:top]
+	}
+	collection := LogEntryCollection{
+		ODataContext: "/redfish/v1/$metadata#LogEntryCollection.LogEntryCollection",
+		ODataType:    "#LogEntryCollection.LogEntryCollection",
+		ODataID:      serviceURI + "/Entries",
+		Name:         log.name + " Entries",
+		MembersCount: len(entries),
+		Members:      make([]LogEntry, 0, len(page)),
+	}
+	for _, entry := range page {
+		collection.Members = append(collection.Members, logEntryResource(entry, log.entryType, serviceURI))
+	}
+	if next := skip + len(page); limited && top > 0 && next < len(entries) {
+		collection.NextLink = serviceURI + "/Entries?$skip=" + strconv.Itoa(next) + "&$top=" + strconv.Itoa(top)
+	}
+	c.JSON(http.StatusOK, collection)
+}
+
+func (b *mockBMC) getLogEntry(c *gin.Context) {
+	c.Header("OData-Version", "4.0")
+	log, serviceURI, ok := b.lookupLogService(c)
+	if !ok {
+		return
+	}
+	entry, ok := log.get(c.Param("entryID"))
+	if !ok {
+		resourceNotFound(c, "LogEntry", c.Param("entryID"))
+		return
+	}
+	resource := logEntryResource(entry, log.entryType, serviceURI)
+	resource.ODataContext = "/redfish/v1/$metadata#LogEntry.LogEntry"
+	c.JSON(http.StatusOK, resource)
+}
+
+func (b *mockBMC) clearLog(c *gin.Context) {
+	c.Header("OData-Version", "4.0")
+	log, _, ok := b.lookupLogService(c)
+	if !ok {
+		return
+	}
+	log.clear()
+	c.Status(http.StatusNoContent)
+}
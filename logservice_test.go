@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestSystemEventLogRecordsChangesAndPages(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	bmc := newTestBMC(t, defaultConfig())
+	router := newRouter(bmc)
+	admin, password := bmc.config.Authentication.Username, bmc.config.Authentication.Password
+	const entries = "/redfish/v1/Systems/1/LogServices/SEL/Entries"
+
+	if recorder := accountRequest(router, http.MethodPatch, "/redfish/v1/Systems/1", `{"Boot":{"BootSourceOverrideTarget":"Pxe"}}`, admin, password); recorder.Code != http.StatusNoContent {
+		t.Fatalf("patch system status = %d", recorder.Code)
+	}
+	if recorder := accountRequest(router, http.MethodPost, "/redfish/v1/Systems/1/Actions/ComputerSystem.Reset", `{"ResetType":"ForceOff"}`, admin, password); recorder.Code != http.StatusNoContent {
+		t.Fatalf("reset status = %d", recorder.Code)
+	}
+
+	var page LogEntryCollection
+	recorder := accountRequest(router, http.MethodGet, entries+"?$skip=1&$top=1", "", admin, password)
+	if err := json.Unmarshal(recorder.Body.Bytes(), &page); err != nil {
+		t.Fatalf("decode entries: %v (%s)", err, recorder.Body.String())
+	}
+	if page.MembersCount != 3 || len(page.Members) != 1 || page.NextLink != entries+"?$skip=2&$top=1" {
+		t.Fatalf("page = %+v", page)
+	}
+	entry := page.Members[0]
+	if entry.ID != "2" || entry.EntryType != "SEL" || entry.Severity != "OK" || entry.Created == "" ||
+		entry.MessageID != "ResourceEvent.1.3.0.ResourceChanged" || entry.Links.OriginOfCondition.ODataID != "/redfish/v1/Systems/1" {
+		t.Fatalf("entry = %+v", entry)
+	}
+	recorder = accountRequest(router, http.MethodGet, page.NextLink, "", admin, password)
+	page = LogEntryCollection{}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &page); err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Members) != 1 || page.Members[0].MessageID != "ResourceEvent.1.3.0.ResourcePoweredOff" || page.NextLink != "" {
+		t.Fatalf("last page = %+v", page)
+	}
+
+	if recorder := accountRequest(router, http.MethodGet, entries+"?$top=many", "", admin, password); recorder.Code != http.StatusBadRequest ||
+		!strings.Contains(recorder.Body.String(), "QueryParameterValueTypeError") {
+		t.Fatalf("bad $top status = %d, body = %s", recorder.Code, recorder.Body.String())
+	}
+	if recorder := accountRequest(router, http.MethodPost, "/redfish/v1/Systems/1/LogServices/SEL/Actions/LogService.ClearLog", `{}`, admin, password); recorder.Code != http.StatusNoContent {
+		t.Fatalf("ClearLog status = %d", recorder.Code)
+	}
+	recorder = accountRequest(router, http.MethodGet, entries, "", admin, password)
+	if err := json.Unmarshal(recorder.Body.Bytes(), &page); err != nil {
+		t.Fatal(err)
+	}
+	if page.MembersCount != 0 {
+		t.Fatalf("entries after ClearLog = %+v", page)
+	}
+}
+
+func TestManagerLogRecordsVirtualMedia(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	bmc := newTestBMC(t, defaultConfig())
+	router := newRouter(bmc)
+	admin, password := bmc.config.Authentication.Username, bmc.config.Authentication.Password
+
+	if recorder := accountRequest(router, http.MethodPost, "/redfish/v1/Managers/1/VirtualMedia/CD/Actions/VirtualMedia.EjectMedia", `{}`, admin, password); recorder.Code != http.StatusNoContent {
+		t.Fatalf("eject status = %d", recorder.Code)
+	}
+	recorder := accountRequest(router, http.MethodGet, "/redfish/v1/Managers/1/LogServices/Lclog/Entries/1", "", admin, password)
+	var entry LogEntry
+	if err := json.Unmarshal(recorder.Body.Bytes(), &entry); err != nil {
+		t.Fatalf("decode entry: %v (%s)", err, recorder.Body.String())
+	}
+	if entry.EntryType != "Event" || entry.Links.OriginOfCondition == nil || entry.Links.OriginOfCondition.ODataID != "/redfish/v1/Managers/1/VirtualMedia/CD" {
+		t.Fatalf("entry = %+v", entry)
+	}
+	if recorder := accountRequest(router, http.MethodGet, "/redfish/v1/Managers/1/LogServices/SEL", "", admin, password); recorder.Code != http.StatusNotFound {
+		t.Fatalf("unknown log service status = %d", recorder.Code)
+	}
+}
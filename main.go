@@ -9,9 +9,11 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"net"
 	"net/http"
 	"net/url"
 	"os"
+	"strconv"
 	"sync"
 	"time"
 
@@ -19,22 +21,32 @@ import (
 )
 
 type ServiceRoot struct {
-	ODataContext   string                 `json:"@odata.context"`
-	ODataType      string                 `json:"@odata.type"`
-	ODataID        string                 `json:"@odata.id"`
-	ID             string                 `json:"Id"`
-	Name           string                 `json:"Name"`
-	RedfishVersion string                 `json:"RedfishVersion"`
-	UUID           string                 `json:"UUID"`
-	Product        string                 `json:"Product"`
-	Vendor         string                 `json:"Vendor"`
-	Oem            map[string]interface{} `json:"Oem"`
-	Systems        Link                   `json:"Systems"`
-	Chassis        Link                   `json:"Chassis"`
-	Managers       Link                   `json:"Managers"`
-	SessionService Link                   `json:"SessionService"`
-	UpdateService  Link                   `json:"UpdateService"`
-	LicenseService Link                   `json:"LicenseService"`
+	ODataContext       string                 `json:"@odata.context"`
+	ODataType          string                 `json:"@odata.type"`
+	ODataID            string                 `json:"@odata.id"`
+	ID                 string                 `json:"Id"`
+	Name               string                 `json:"Name"`
+	RedfishVersion     string                 `json:"RedfishVersion"`
+	UUID               string                 `json:"UUID"`
+	Product            string                 `json:"Product"`
+	Vendor             string                 `json:"Vendor"`
+	Oem                map[string]interface{} `json:"Oem"`
+	Systems            Link                   `json:"Systems"`
+	Chassis            Link                   `json:"Chassis"`
+	Managers           Link                   `json:"Managers"`
+	SessionService     Link                   `json:"SessionService"`
+	UpdateService      Link                   `json:"UpdateService"`
+	Tasks              Link                   `json:"Tasks"`
+	Registries         Link                   `json:"Registries"`
+	LicenseService     Link                   `json:"LicenseService"`
+	CertificateService Link                   `json:"CertificateService"`
+	AccountService     Link                   `json:"AccountService"`
+	EventService       Link                   `json:"EventService"`
+	Links              ServiceRootLinks       `json:"Links"`
+}
+
+type ServiceRootLinks struct {
+	Sessions Link `json:"Sessions"`
 }
 
 type Link struct {
@@ -65,12 +77,21 @@ type ComputerSystem struct {
 	BiosVersion      string           `json:"BiosVersion"`
 	ProcessorSummary ProcessorSummary `json:"ProcessorSummary"`
 	MemorySummary    MemorySummary    `json:"MemorySummary"`
+	Processors       Link             `json:"Processors"`
+	Memory           Link             `json:"Memory"`
 	Status           Status           `json:"Status"`
 	Boot             Boot             `json:"Boot"`
 	Actions          SystemActions    `json:"Actions"`
+	LogServices      Link             `json:"LogServices"`
+	Links            SystemLinks      `json:"Links"`
 	Oem              map[string]any   `json:"Oem"`
 }
 
+type SystemLinks struct {
+	Chassis   []Link `json:"Chassis"`
+	ManagedBy []Link `json:"ManagedBy"`
+}
+
 type Boot struct {
 	BootSourceOverrideEnabled          string   `json:"BootSourceOverrideEnabled"`
 	BootSourceOverrideTarget           string   `json:"BootSourceOverrideTarget"`
@@ -100,23 +121,67 @@ type MemorySummary struct {
 }
 
 type Status struct {
-	State  string `json:"State"`
-	Health string `json:"Health"`
+	State        string `json:"State"`
+	Health       string `json:"Health"`
+	HealthRollup string `json:"HealthRollup,omitempty"`
 }
 
 type Config struct {
 	OEM            string               `json:"oem"`
 	Authentication AuthenticationConfig `json:"authentication"`
+	AccountService AccountServiceConfig `json:"account_service"`
+	Accounts       []AccountConfig      `json:"accounts"`
+	SessionService SessionServiceConfig `json:"session_service"`
+	EventService   EventServiceConfig   `json:"event_service"`
 	ServiceRoot    ServiceRootConfig    `json:"service_root"`
 	System         SystemConfig         `json:"system"`
 	Chassis        ChassisConfig        `json:"chassis"`
 	Manager        ManagerConfig        `json:"manager"`
+	Systems        []SystemConfig       `json:"systems"`
+	ChassisMembers []ChassisConfig      `json:"chassis_members"`
+	Managers       []ManagerConfig      `json:"managers"`
+	UpdateService  UpdateServiceConfig  `json:"update_service"`
 	Firmware       []FirmwareItemConfig `json:"firmware_inventory"`
+	Faults         []FaultConfig        `json:"faults"`
+	MockAPI        MockAPIConfig        `json:"mock_api"`
+	Clock          ClockConfig          `json:"clock"`
+	Chaos          ChaosConfig          `json:"chaos"`
+	Recording      RecordingConfig      `json:"recording"`
+	Mockup         MockupConfig         `json:"mockup"`
 }
 
 type AuthenticationConfig struct {
+	Username               string `json:"username"`
+	Password               string `json:"password"`
+	PasswordChangeRequired bool   `json:"password_change_required"`
+}
+
+// AccountServiceConfig sets the password and lockout policy. Lockout
+// durations are in seconds; a threshold of 0 disables lockout.
+type AccountServiceConfig struct {
+	MinPasswordLength               int `json:"min_password_length"`
+	MaxPasswordLength               int `json:"max_password_length"`
+	AccountLockoutThreshold         int `json:"account_lockout_threshold"`
+	AccountLockoutDuration          int `json:"account_lockout_duration"`
+	AccountLockoutCounterResetAfter int `json:"account_lockout_counter_reset_after"`
+}
+
+// AccountConfig adds a user next to the Administrator from authentication.
+type AccountConfig struct {
 	Username string `json:"username"`
 	Password string `json:"password"`
+	Role     string `json:"role"`
+}
+
+type SessionServiceConfig struct {
+	SessionTimeout int `json:"session_timeout"`
+}
+
+// EventServiceConfig sets how often a failed event push is retried and how
+// many seconds to wait between tries.
+type EventServiceConfig struct {
+	DeliveryRetryAttempts        int `json:"delivery_retry_attempts"`
+	DeliveryRetryIntervalSeconds int `json:"delivery_retry_interval_seconds"`
 }
 
 type ServiceRootConfig struct {
@@ -127,36 +192,60 @@ type ServiceRootConfig struct {
 }
 
 type SystemConfig struct {
-	Name                     string         `json:"name"`
-	SystemType               string         `json:"system_type"`
-	Manufacturer             string         `json:"manufacturer"`
-	Model                    string         `json:"model"`
-	SerialNumber             string         `json:"serial_number"`
-	PartNumber               string         `json:"part_number"`
-	PowerState               string         `json:"power_state"`
-	BiosVersion              string         `json:"bios_version"`
-	ProcessorCount           int            `json:"processor_count"`
-	ProcessorModel           string         `json:"processor_model"`
-	TotalSystemMemoryGiB     int            `json:"total_system_memory_gib"`
-	Oem                      map[string]any `json:"oem"`
-	InstallationStatusOemKey string         `json:"installation_status_oem_key"`
+	ID                          string                     `json:"id"`
+	ChassisID                   string                     `json:"chassis_id"`
+	ManagerID                   string                     `json:"manager_id"`
+	Name                        string                     `json:"name"`
+	SystemType                  string                     `json:"system_type"`
+	Manufacturer                string                     `json:"manufacturer"`
+	Model                       string                     `json:"model"`
+	SerialNumber                string                     `json:"serial_number"`
+	PartNumber                  string                     `json:"part_number"`
+	PowerState                  string                     `json:"power_state"`
+	PowerOnDelaySeconds         int                        `json:"power_on_delay_seconds"`
+	PowerOffDelaySeconds        int                        `json:"power_off_delay_seconds"`
+	InstallationDurationSeconds int                        `json:"installation_duration_seconds"`
+	BiosVersion                 string                     `json:"bios_version"`
+	ProcessorCount              int                        `json:"processor_count"`
+	ProcessorModel              string                     `json:"processor_model"`
+	TotalSystemMemoryGiB        int                        `json:"total_system_memory_gib"`
+	Oem                         map[string]any             `json:"oem"`
+	InstallationStatusOemKey    string                     `json:"installation_status_oem_key"`
+	InstallationScenario        InstallationScenarioConfig `json:"installation_scenario"`
+	MediaDownload               MediaDownloadConfig        `json:"media_download"`
 }
 
 type ChassisConfig struct {
-	Name         string `json:"name"`
-	ChassisType  string `json:"chassis_type"`
-	Manufacturer string `json:"manufacturer"`
-	Model        string `json:"model"`
-	SerialNumber string `json:"serial_number"`
-	PartNumber   string `json:"part_number"`
+	ID           string          `json:"id"`
+	ManagerID    string          `json:"manager_id"`
+	Name         string          `json:"name"`
+	ChassisType  string          `json:"chassis_type"`
+	Manufacturer string          `json:"manufacturer"`
+	Model        string          `json:"model"`
+	SerialNumber string          `json:"serial_number"`
+	PartNumber   string          `json:"part_number"`
+	Telemetry    TelemetryConfig `json:"telemetry"`
 }
 
 type ManagerConfig struct {
+	ID              string `json:"id"`
 	Name            string `json:"name"`
 	ManagerType     string `json:"manager_type"`
 	FirmwareVersion string `json:"firmware_version"`
 }
 
+type UpdateServiceConfig struct {
+	UpdateDurationSeconds int                   `json:"update_duration_seconds"`
+	Images                []FirmwareImageConfig `json:"images"`
+}
+
+type FirmwareImageConfig struct {
+	Image      string   `json:"image"`
+	Version    string   `json:"version"`
+	SoftwareID string   `json:"software_id"`
+	Targets    []string `json:"targets"`
+}
+
 type FirmwareItemConfig struct {
 	ID         string `json:"id"`
 	Name       string `json:"name"`
@@ -165,7 +254,10 @@ type FirmwareItemConfig struct {
 	SoftwareID string `json:"software_id"`
 }
 
-var config = defaultConfig()
+const (
+	minSessionTimeout = 30
+	maxSessionTimeout = 86400
+)
 
 func defaultConfig() Config {
 	config := Config{
@@ -174,31 +266,52 @@ func defaultConfig() Config {
 			Username: "admin",
 			Password: "password",
 		},
+		AccountService: AccountServiceConfig{
+			MinPasswordLength:               8,
+			MaxPasswordLength:               20,
+			AccountLockoutThreshold:         5,
+			AccountLockoutDuration:          30,
+			AccountLockoutCounterResetAfter: 30,
+		},
+		SessionService: SessionServiceConfig{
+			SessionTimeout: 1800,
+		},
+		EventService: EventServiceConfig{
+			DeliveryRetryAttempts:        3,
+			DeliveryRetryIntervalSeconds: 30,
+		},
 		ServiceRoot: ServiceRootConfig{
 			UUID: "92384634-2938-2342-8820-489239905423",
 		},
 		System: SystemConfig{
-			Name:                 "System",
-			SystemType:           "Physical",
-			SerialNumber:         "MOCK123456789",
-			PartNumber:           "MOCK-SRV-001",
-			PowerState:           "On",
-			BiosVersion:          "1.0.0",
-			ProcessorCount:       2,
-			ProcessorModel:       "Mock CPU X5000",
-			TotalSystemMemoryGiB: 64,
-			Oem:                  map[string]any{},
+			Name:                        "System",
+			SystemType:                  "Physical",
+			SerialNumber:                "MOCK123456789",
+			PartNumber:                  "MOCK-SRV-001",
+			PowerState:                  "On",
+			PowerOnDelaySeconds:         1,
+			PowerOffDelaySeconds:        1,
+			InstallationDurationSeconds: 2,
+			BiosVersion:                 "1.0.0",
+			ProcessorCount:              2,
+			ProcessorModel:              "Mock CPU X5000",
+			TotalSystemMemoryGiB:        64,
+			Oem:                         map[string]any{},
 		},
 		Chassis: ChassisConfig{
 			Name:         "Chassis",
 			ChassisType:  "RackMount",
 			SerialNumber: "MOCK-CHASSIS-123",
 			PartNumber:   "MOCK-CHS-001",
+			Telemetry:    defaultTelemetry(),
 		},
 		Manager: ManagerConfig{
 			ManagerType:     "BMC",
 			FirmwareVersion: "1.0.0",
 		},
+		UpdateService: UpdateServiceConfig{
+			UpdateDurationSeconds: 5,
+		},
 		Firmware: []FirmwareItemConfig{
 			{ID: "BIOS", Name: "System BIOS", Version: "1.0.0", Updateable: true, SoftwareID: "BIOS-1.0.0"},
 			{ID: "BMC", Name: "Baseboard Management Controller", Version: "2.1.0", Updateable: true, SoftwareID: "BMC-2.1.0"},
@@ -206,6 +319,9 @@ func defaultConfig() Config {
 		},
 	}
 	mockOEM{}.applyDefaults(&config)
+	if err := resolveTopology(&config, mockOEM{}.resourceIDs()); err != nil {
+		panic(err)
+	}
 	return config
 }
 
@@ -233,6 +349,12 @@ func loadConfig(path string) (Config, error) {
 	if err := decoder.Decode(&loaded); err != nil {
 		return Config{}, fmt.Errorf("decode config: %w", err)
 	}
+	if err := decodeTopology(contents, &loaded); err != nil {
+		return Config{}, err
+	}
+	if err := resolveTopology(&loaded, behavior.resourceIDs()); err != nil {
+		return Config{}, err
+	}
 	for i, item := range loaded.Firmware {
 		if item.ID == "" {
 			return Config{}, fmt.Errorf("firmware_inventory[%d].id is required", i)
@@ -244,33 +366,135 @@ func loadConfig(path string) (Config, error) {
 	if loaded.Authentication.Username == "" || loaded.Authentication.Password == "" {
 		return Config{}, errors.New("authentication.username and authentication.password are required")
 	}
+	if err := loaded.AccountService.validate(); err != nil {
+		return Config{}, fmt.Errorf("account_service: %w", err)
+	}
+	for _, chassis := range loaded.ChassisMembers {
+		if err := chassis.Telemetry.validate(); err != nil {
+			return Config{}, fmt.Errorf("chassis %q telemetry: %w", chassis.ID, err)
+		}
+	}
+	if (loaded.MockAPI.Username == "") != (loaded.MockAPI.Password == "") {
+		return Config{}, errors.New("mock_api.username and mock_api.password must be set together")
+	}
+	if err := validateFaults(loaded); err != nil {
+		return Config{}, err
+	}
+	if err := loaded.Clock.validate(); err != nil {
+		return Config{}, err
+	}
+	if err := loaded.Chaos.validate(); err != nil {
+		return Config{}, fmt.Errorf("chaos: %w", err)
+	}
+	if err := loaded.Recording.validate(); err != nil {
+		return Config{}, fmt.Errorf("recording: %w", err)
+	}
+	if err := loaded.Mockup.validate(); err != nil {
+		return Config{}, fmt.Errorf("mockup: %w", err)
+	}
+	if loaded.EventService.DeliveryRetryAttempts < 0 || loaded.EventService.DeliveryRetryIntervalSeconds < 0 {
+		return Config{}, errors.New("event_service.delivery_retry_attempts and event_service.delivery_retry_interval_seconds must not be negative")
+	}
+	usernames := map[string]bool{loaded.Authentication.Username: true}
+	for i, account := range loaded.Accounts {
+		if account.Username == "" || account.Password == "" {
+			return Config{}, fmt.Errorf("accounts[%d].username and password are required", i)
+		}
+		if usernames[account.Username] {
+			return Config{}, fmt.Errorf("accounts[%d].username %q is used more than once", i, account.Username)
+		}
+		usernames[account.Username] = true
+		if _, ok := privilegesFor(account.Role); !ok {
+			return Config{}, fmt.Errorf(`accounts[%d].role must be "Administrator", "Operator" or "ReadOnly"`, i)
+		}
+	}
+	if loaded.System.PowerState != "On" && loaded.System.PowerState != "Off" {
+		return Config{}, errors.New(`system.power_state must be "On" or "Off"`)
+	}
+	if loaded.System.PowerOnDelaySeconds < 0 || loaded.System.PowerOffDelaySeconds < 0 {
+		return Config{}, errors.New("system.power_on_delay_seconds and system.power_off_delay_seconds must not be negative")
+	}
+	if loaded.System.InstallationDurationSeconds < 0 {
+		return Config{}, errors.New("system.installation_duration_seconds must not be negative")
+	}
+	if err := loaded.System.validateScenarios(); err != nil {
+		return Config{}, fmt.Errorf("system.%w", err)
+	}
+	for i, system := range loaded.Systems {
+		if system.InstallationStatusOemKey == "" {
+			return Config{}, fmt.Errorf("systems[%d].installation_status_oem_key is required", i)
+		}
+		if system.PowerState != "On" && system.PowerState != "Off" {
+			return Config{}, fmt.Errorf(`systems[%d].power_state must be "On" or "Off"`, i)
+		}
+		if system.PowerOnDelaySeconds < 0 || system.PowerOffDelaySeconds < 0 {
+			return Config{}, fmt.Errorf("systems[%d].power_on_delay_seconds and power_off_delay_seconds must not be negative", i)
+		}
+		if system.InstallationDurationSeconds < 0 {
+			return Config{}, fmt.Errorf("systems[%d].installation_duration_seconds must not be negative", i)
+		}
+		if err := system.validateScenarios(); err != nil {
+			return Config{}, fmt.Errorf("systems[%d].%w", i, err)
+		}
+	}
+	if loaded.UpdateService.UpdateDurationSeconds < 0 {
+		return Config{}, errors.New("update_service.update_duration_seconds must not be negative")
+	}
+	for i, image := range loaded.UpdateService.Images {
+		if image.Image == "" || image.Version == "" {
+			return Config{}, fmt.Errorf("update_service.images[%d].image and version are required", i)
+		}
+	}
+	if loaded.SessionService.SessionTimeout < minSessionTimeout || loaded.SessionService.SessionTimeout > maxSessionTimeout {
+		return Config{}, fmt.Errorf("session_service.session_timeout must be between %d and %d seconds", minSessionTimeout, maxSessionTimeout)
+	}
 	return loaded, nil
 }
 
 type Chassis struct {
-	ODataContext string `json:"@odata.context"`
-	ODataType    string `json:"@odata.type"`
-	ODataID      string `json:"@odata.id"`
-	ID           string `json:"Id"`
-	Name         string `json:"Name"`
-	ChassisType  string `json:"ChassisType"`
-	Manufacturer string `json:"Manufacturer"`
-	Model        string `json:"Model"`
-	SerialNumber string `json:"SerialNumber"`
-	PartNumber   string `json:"PartNumber"`
-	Status       Status `json:"Status"`
+	ODataContext     string       `json:"@odata.context"`
+	ODataType        string       `json:"@odata.type"`
+	ODataID          string       `json:"@odata.id"`
+	ID               string       `json:"Id"`
+	Name             string       `json:"Name"`
+	ChassisType      string       `json:"ChassisType"`
+	Manufacturer     string       `json:"Manufacturer"`
+	Model            string       `json:"Model"`
+	SerialNumber     string       `json:"SerialNumber"`
+	PartNumber       string       `json:"PartNumber"`
+	PowerState       string       `json:"PowerState"`
+	Status           Status       `json:"Status"`
+	Thermal          Link         `json:"Thermal"`
+	Power            Link         `json:"Power"`
+	ThermalSubsystem Link         `json:"ThermalSubsystem"`
+	PowerSubsystem   Link         `json:"PowerSubsystem"`
+	Sensors          Link         `json:"Sensors"`
+	Links            ChassisLinks `json:"Links"`
+}
+
+type ChassisLinks struct {
+	ComputerSystems []Link `json:"ComputerSystems"`
+	ManagedBy       []Link `json:"ManagedBy"`
 }
 
 type Manager struct {
-	ODataContext    string `json:"@odata.context"`
-	ODataType       string `json:"@odata.type"`
-	ODataID         string `json:"@odata.id"`
-	ID              string `json:"Id"`
-	Name            string `json:"Name"`
-	ManagerType     string `json:"ManagerType"`
-	FirmwareVersion string `json:"FirmwareVersion"`
-	Status          Status `json:"Status"`
-	VirtualMedia    Link   `json:"VirtualMedia"`
+	ODataContext    string       `json:"@odata.context"`
+	ODataType       string       `json:"@odata.type"`
+	ODataID         string       `json:"@odata.id"`
+	ID              string       `json:"Id"`
+	Name            string       `json:"Name"`
+	ManagerType     string       `json:"ManagerType"`
+	FirmwareVersion string       `json:"FirmwareVersion"`
+	Status          Status       `json:"Status"`
+	VirtualMedia    *Link        `json:"VirtualMedia,omitempty"`
+	NetworkProtocol Link         `json:"NetworkProtocol"`
+	LogServices     Link         `json:"LogServices"`
+	Links           ManagerLinks `json:"Links"`
+}
+
+type ManagerLinks struct {
+	ManagerForServers []Link `json:"ManagerForServers"`
+	ManagerForChassis []Link `json:"ManagerForChassis"`
 }
 
 type VirtualMedia struct {
@@ -326,19 +550,17 @@ type mockServerState struct {
 	bootSourceOverrideMode    string
 	installationStatus        string
 	installationStartedAt     time.Time
-}
-
-var mockState = mockServerState{
-	writeProtected:            true,
-	bootSourceOverrideEnabled: "Disabled",
-	bootSourceOverrideTarget:  "None",
-	bootSourceOverrideMode:    "UEFI",
-	installationStatus:        "Ready",
+	powerState                string
+	powerTransitions          []powerTransition
+	// powerEvents are the On and Off transitions of the current power
+	// sequence that have not been logged yet.
+	powerEvents []powerTransition
 }
 
 var (
-	errInvalidISO = errors.New("invalid ISO image")
-	isoHTTPClient = &http.Client{Timeout: 30 * time.Minute}
+	errInvalidISO      = errors.New("invalid ISO image")
+	isoHTTPClient      = &http.Client{Timeout: 30 * time.Minute}
+	firmwareHTTPClient = &http.Client{Timeout: 30 * time.Minute}
 )
 
 type UpdateService struct {
@@ -413,100 +635,111 @@ type License struct {
 	Links              struct{} `json:"Links"`
 }
 
-func basicAuth() gin.HandlerFunc {
-	return gin.BasicAuth(gin.Accounts{
-		config.Authentication.Username: config.Authentication.Password,
-	})
-}
-
-func getServiceRoot(c *gin.Context) {
+func (b *mockBMC) getServiceRoot(c *gin.Context) {
 	c.Header("OData-Version", "4.0")
 	serviceRoot := ServiceRoot{
-		ODataContext:   "/redfish/v1/$metadata#ServiceRoot.ServiceRoot",
-		ODataType:      "#ServiceRoot.v1_15_0.ServiceRoot",
-		ODataID:        "/redfish/v1/",
-		ID:             "RootService",
-		Name:           "Root Service",
-		RedfishVersion: "1.18.0",
-		UUID:           config.ServiceRoot.UUID,
-		Product:        config.ServiceRoot.Product,
-		Vendor:         config.ServiceRoot.Vendor,
-		Oem:            config.ServiceRoot.Oem,
-		Systems:        Link{ODataID: "/redfish/v1/Systems"},
-		Chassis:        Link{ODataID: "/redfish/v1/Chassis"},
-		Managers:       Link{ODataID: "/redfish/v1/Managers"},
-		SessionService: Link{ODataID: "/redfish/v1/SessionService"},
-		UpdateService:  Link{ODataID: "/redfish/v1/UpdateService"},
-		LicenseService: Link{ODataID: "/redfish/v1/LicenseService"},
+		ODataContext:       "/redfish/v1/$metadata#ServiceRoot.ServiceRoot",
+		ODataType:          "#ServiceRoot.v1_15_0.ServiceRoot",
+		ODataID:            "/redfish/v1/",
+		ID:                 "RootService",
+		Name:               "Root Service",
+		RedfishVersion:     "1.18.0",
+		UUID:               b.config.ServiceRoot.UUID,
+		Product:            b.config.ServiceRoot.Product,
+		Vendor:             b.config.ServiceRoot.Vendor,
+		Oem:                b.config.ServiceRoot.Oem,
+		Systems:            Link{ODataID: "/redfish/v1/Systems"},
+		Chassis:            Link{ODataID: "/redfish/v1/Chassis"},
+		Managers:           Link{ODataID: "/redfish/v1/Managers"},
+		SessionService:     Link{ODataID: "/redfish/v1/SessionService"},
+		UpdateService:      Link{ODataID: "/redfish/v1/UpdateService"},
+		Tasks:              Link{ODataID: "/redfish/v1/TaskService"},
+		Registries:         Link{ODataID: "/redfish/v1/Registries"},
+		LicenseService:     Link{ODataID: "/redfish/v1/LicenseService"},
+		CertificateService: Link{ODataID: "/redfish/v1/CertificateService"},
+		AccountService:     Link{ODataID: "/redfish/v1/AccountService"},
+		EventService:       Link{ODataID: "/redfish/v1/EventService"},
+		Links: ServiceRootLinks{
+			Sessions: Link{ODataID: "/redfish/v1/SessionService/Sessions"},
+		},
 	}
 	c.JSON(http.StatusOK, serviceRoot)
 }
 
-func getSystemsCollection(c *gin.Context) {
+func (b *mockBMC) getSystemsCollection(c *gin.Context) {
 	c.Header("OData-Version", "4.0")
-	ids := activeOEM().resourceIDs()
+	members := b.systemLinks(func(SystemConfig) bool { return true })
 	collection := Collection{
 		ODataContext: "/redfish/v1/$metadata#ComputerSystemCollection.ComputerSystemCollection",
 		ODataType:    "#ComputerSystemCollection.ComputerSystemCollection",
 		ODataID:      "/redfish/v1/Systems",
 		Name:         "Computer System Collection",
-		MembersCount: 1,
-		Members: []Link{
-			{ODataID: "/redfish/v1/Systems/" + ids.System},
-		},
+		MembersCount: len(members),
+		Members:      members,
 	}
 	c.JSON(http.StatusOK, collection)
 }
 
-func getSystem(c *gin.Context) {
+func (b *mockBMC) getSystem(c *gin.Context) {
 	c.Header("OData-Version", "4.0")
-	systemID := c.Param("id")
-
-	mockState.Lock()
-	if mockState.installationStatus == "Installing" && time.Since(mockState.installationStartedAt) >= 2*time.Second {
-		mockState.installationStatus = "Installed"
-	}
-	bootEnabled := mockState.bootSourceOverrideEnabled
-	bootTarget := mockState.bootSourceOverrideTarget
-	bootMode := mockState.bootSourceOverrideMode
-	installationStatus := mockState.installationStatus
-	mockState.Unlock()
-	oem := make(map[string]any, len(config.System.Oem)+1)
-	for key, value := range config.System.Oem {
+	systemConfig, ok := b.findSystem(c.Param("id"))
+	if !ok {
+		resourceNotFound(c, "ComputerSystem", c.Param("id"))
+		return
+	}
+	systemID := systemConfig.ID
+	systemURI := "/redfish/v1/Systems/" + systemID
+	state := b.systemStates[systemID]
+
+	state.Lock()
+	now := b.now()
+	b.advanceInstallation(state, systemID, now)
+	state.advancePower(now)
+	b.flushPowerEvents(state, systemID, now)
+	powerState := state.powerState
+	bootEnabled := state.bootSourceOverrideEnabled
+	bootTarget := state.bootSourceOverrideTarget
+	bootMode := state.bootSourceOverrideMode
+	installationStatus := state.installationStatus
+	state.Unlock()
+	oem := make(map[string]any, len(systemConfig.Oem)+1)
+	for key, value := range systemConfig.Oem {
 		oem[key] = value
 	}
 	installationOem := map[string]any{}
-	if configuredOem, ok := oem[config.System.InstallationStatusOemKey].(map[string]any); ok {
+	if configuredOem, ok := oem[systemConfig.InstallationStatusOemKey].(map[string]any); ok {
 		for key, value := range configuredOem {
 			installationOem[key] = value
 		}
 	}
 	installationOem["InstallationStatus"] = installationStatus
-	oem[config.System.InstallationStatusOemKey] = installationOem
+	oem[systemConfig.InstallationStatusOemKey] = installationOem
 
 	system := ComputerSystem{
 		ODataContext: "/redfish/v1/$metadata#ComputerSystem.ComputerSystem",
 		ODataType:    "#ComputerSystem.v1_22_0.ComputerSystem",
 		ODataID:      "/redfish/v1/Systems/" + systemID,
 		ID:           systemID,
-		Name:         config.System.Name,
-		SystemType:   config.System.SystemType,
-		Manufacturer: config.System.Manufacturer,
-		Model:        config.System.Model,
-		SerialNumber: config.System.SerialNumber,
-		PartNumber:   config.System.PartNumber,
-		PowerState:   config.System.PowerState,
-		BiosVersion:  config.System.BiosVersion,
+		Name:         systemConfig.Name,
+		SystemType:   systemConfig.SystemType,
+		Manufacturer: systemConfig.Manufacturer,
+		Model:        systemConfig.Model,
+		SerialNumber: systemConfig.SerialNumber,
+		PartNumber:   systemConfig.PartNumber,
+		PowerState:   powerState,
+		BiosVersion:  systemConfig.BiosVersion,
 		ProcessorSummary: ProcessorSummary{
-			Count:  config.System.ProcessorCount,
-			Model:  config.System.ProcessorModel,
-			Status: Status{State: "Enabled", Health: "OK"},
+			Count:  systemConfig.ProcessorCount,
+			Model:  systemConfig.ProcessorModel,
+			Status: Status{State: "Enabled", Health: "OK", HealthRollup: b.faults.rollupUnder(systemURI + "/Processors")},
 		},
 		MemorySummary: MemorySummary{
-			TotalSystemMemoryGiB: config.System.TotalSystemMemoryGiB,
-			Status:               Status{State: "Enabled", Health: "OK"},
+			TotalSystemMemoryGiB: systemConfig.TotalSystemMemoryGiB,
+			Status:               Status{State: "Enabled", Health: "OK", HealthRollup: b.faults.rollupUnder(systemURI + "/Memory")},
 		},
-		Status: Status{State: "Enabled", Health: "OK"},
+		Processors: Link{ODataID: systemURI + "/Processors"},
+		Memory:     Link{ODataID: systemURI + "/Memory"},
+		Status:     Status{State: "Enabled", Health: "OK", HealthRollup: b.systemHealthRollup(systemConfig)},
 		Boot: Boot{
 			BootSourceOverrideEnabled:          bootEnabled,
 			BootSourceOverrideTarget:           bootTarget,
@@ -520,31 +753,44 @@ func getSystem(c *gin.Context) {
 				AllowableValues: []string{"On", "ForceOff", "GracefulShutdown", "GracefulRestart", "ForceRestart", "PowerCycle"},
 			},
 		},
+		LogServices: Link{ODataID: "/redfish/v1/Systems/" + systemID + "/LogServices"},
+		Links: SystemLinks{
+			Chassis:   []Link{{ODataID: "/redfish/v1/Chassis/" + systemConfig.ChassisID}},
+			ManagedBy: []Link{{ODataID: "/redfish/v1/Managers/" + systemConfig.ManagerID}},
+		},
 		Oem: oem,
 	}
 	c.JSON(http.StatusOK, system)
 }
 
-func patchSystem(c *gin.Context) {
+func (b *mockBMC) patchSystem(c *gin.Context) {
 	c.Header("OData-Version", "4.0")
+	state, ok := b.systemStates[c.Param("id")]
+	if !ok {
+		resourceNotFound(c, "ComputerSystem", c.Param("id"))
+		return
+	}
 	var req SystemPatchRequest
-	if err := c.ShouldBindJSON(&req); err != nil || req.Boot == nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "A valid Boot object is required"})
+	if !bindRedfishJSON(c, &req) {
+		return
+	}
+	if req.Boot == nil {
+		redfishError(c, http.StatusBadRequest, baseMessage("PropertyMissing", "Boot"))
 		return
 	}
 
-	mockState.Lock()
-	defer mockState.Unlock()
-	bootEnabled := mockState.bootSourceOverrideEnabled
-	bootTarget := mockState.bootSourceOverrideTarget
-	bootMode := mockState.bootSourceOverrideMode
+	state.Lock()
+	defer state.Unlock()
+	bootEnabled := state.bootSourceOverrideEnabled
+	bootTarget := state.bootSourceOverrideTarget
+	bootMode := state.bootSourceOverrideMode
 
 	if req.Boot.BootSourceOverrideEnabled != nil {
 		switch *req.Boot.BootSourceOverrideEnabled {
 		case "Disabled", "Once", "Continuous":
 			bootEnabled = *req.Boot.BootSourceOverrideEnabled
 		default:
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported BootSourceOverrideEnabled value"})
+			redfishError(c, http.StatusBadRequest, baseMessage("PropertyValueNotInList", *req.Boot.BootSourceOverrideEnabled, "BootSourceOverrideEnabled"))
 			return
 		}
 	}
@@ -553,7 +799,7 @@ func patchSystem(c *gin.Context) {
 		case "None", "Cd", "Hdd", "Pxe", "Usb":
 			bootTarget = *req.Boot.BootSourceOverrideTarget
 		default:
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported BootSourceOverrideTarget value"})
+			redfishError(c, http.StatusBadRequest, baseMessage("PropertyValueNotInList", *req.Boot.BootSourceOverrideTarget, "BootSourceOverrideTarget"))
 			return
 		}
 	}
@@ -562,120 +808,245 @@ func patchSystem(c *gin.Context) {
 		case "UEFI", "Legacy":
 			bootMode = *req.Boot.BootSourceOverrideMode
 		default:
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported BootSourceOverrideMode value"})
+			redfishError(c, http.StatusBadRequest, baseMessage("PropertyValueNotInList", *req.Boot.BootSourceOverrideMode, "BootSourceOverrideMode"))
 			return
 		}
 	}
-	mockState.bootSourceOverrideEnabled = bootEnabled
-	mockState.bootSourceOverrideTarget = bootTarget
-	mockState.bootSourceOverrideMode = bootMode
+	state.bootSourceOverrideEnabled = bootEnabled
+	state.bootSourceOverrideTarget = bootTarget
+	state.bootSourceOverrideMode = bootMode
+	b.logSystemEvent(c.Param("id"), registryMessage("ResourceEvent", "ResourceChanged"), "/redfish/v1/Systems/"+c.Param("id"), b.now())
 
 	c.Status(http.StatusNoContent)
 }
 
-func resetSystem(c *gin.Context) {
+func (b *mockBMC) resetSystem(c *gin.Context) {
 	c.Header("OData-Version", "4.0")
+	systemConfig, ok := b.findSystem(c.Param("id"))
+	if !ok {
+		resourceNotFound(c, "ComputerSystem", c.Param("id"))
+		return
+	}
 	var req ResetRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON"})
+	if !bindRedfishJSON(c, &req) {
 		return
 	}
 
 	switch req.ResetType {
 	case "On", "ForceOff", "GracefulShutdown", "GracefulRestart", "ForceRestart", "PowerCycle":
+	case "":
+		redfishError(c, http.StatusBadRequest, baseMessage("ActionParameterMissing", "ComputerSystem.Reset", "ResetType"))
+		return
 	default:
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported ResetType value"})
+		redfishError(c, http.StatusBadRequest, baseMessage("ActionParameterValueNotInList", req.ResetType, "ResetType", "ComputerSystem.Reset"))
 		return
 	}
 
-	mockState.Lock()
-	defer mockState.Unlock()
+	state := b.systemStates[systemConfig.ID]
+	state.Lock()
+	defer state.Unlock()
+	now := b.now()
+	poweringOn := time.Duration(systemConfig.PowerOnDelaySeconds) * time.Second
+	poweringOff := time.Duration(systemConfig.PowerOffDelaySeconds) * time.Second
+	previousPowerState := state.powerState
+	if err := state.resetPower(req.ResetType, now, poweringOn, poweringOff); err != nil {
+		redfishError(c, http.StatusConflict, powerConflictMessage(req.ResetType, state.powerState))
+		return
+	}
+	systemURI := "/redfish/v1/Systems/" + systemConfig.ID
+	b.logSystemEvent(systemConfig.ID, registryMessage("ResourceEvent", "ResourceChanged"), systemURI, now)
+	b.schedulePowerEvents(state, systemConfig.ID, previousPowerState, now)
 	bootsSystem := req.ResetType == "On" || req.ResetType == "GracefulRestart" || req.ResetType == "ForceRestart" || req.ResetType == "PowerCycle"
-	if bootsSystem && mockState.inserted && mockState.bootSourceOverrideTarget == "Cd" && mockState.bootSourceOverrideEnabled != "Disabled" {
-		mockState.installationStatus = "Installing"
-		mockState.installationStartedAt = time.Now()
-		if mockState.bootSourceOverrideEnabled == "Once" {
-			mockState.bootSourceOverrideEnabled = "Disabled"
+	if bootsSystem && state.inserted && state.bootSourceOverrideTarget == "Cd" && state.bootSourceOverrideEnabled != "Disabled" {
+		state.installationStatus = "Installing"
+		state.installationStartedAt = now
+		if state.bootSourceOverrideEnabled == "Once" {
+			state.bootSourceOverrideEnabled = "Disabled"
 		}
+		b.logSystemEvent(systemConfig.ID, registryMessage("ResourceEvent", "ResourceChanged"), systemURI, now)
+		b.scheduleInstallation(state, systemConfig.ID)
 	}
 
 	c.Status(http.StatusNoContent)
 }
 
-func getChassisCollection(c *gin.Context) {
+// installationDuration is how long a mock OS installation runs on a system
+// after it boots from virtual media.
+func (b *mockBMC) installationDuration(systemID string) time.Duration {
+	system, _ := b.findSystem(systemID)
+	return time.Duration(system.InstallationDurationSeconds) * time.Second
+}
+
+// advanceInstallation finishes an OS installation that has run for the
+// system's installation duration, as its installation scenario scripts it.
+// The caller must hold the state lock.
+func (b *mockBMC) advanceInstallation(state *mockServerState, systemID string, now time.Time) {
+	if state.installationStatus == "Installing" && now.Sub(state.installationStartedAt) >= b.installationDuration(systemID) {
+		system, _ := b.findSystem(systemID)
+		b.finishInstallation(state, system, now)
+	}
+}
+
+// scheduleInstallation finishes the installation that just started once the
+// mock clock has run for the installation duration, even if nobody reads the
+// system.
+func (b *mockBMC) scheduleInstallation(state *mockServerState, systemID string) {
+	b.clock.afterFunc(b.installationDuration(systemID), func() {
+		state.Lock()
+		defer state.Unlock()
+		b.advanceInstallation(state, systemID, b.now())
+	})
+}
+
+// schedulePowerEvents logs ResourcePoweredOn and ResourcePoweredOff as the
+// power sequence a reset just started reaches On or Off. Steps of a sequence
+// that a later reset replaced are not reported. The caller must hold the
+// state lock.
+func (b *mockBMC) schedulePowerEvents(state *mockServerState, systemID, previousPowerState string, now time.Time) {
+	if state.powerState != previousPowerState {
+		b.logPowerEvent(state.powerState, systemID, now)
+	}
+	for _, transition := range state.powerTransitions {
+		if transition.state != "On" && transition.state != "Off" {
+			continue
+		}
+		state.powerEvents = append(state.powerEvents, transition)
+		b.clock.afterFunc(transition.at.Sub(now), func() {
+			state.Lock()
+			defer state.Unlock()
+			b.flushPowerEvents(state, systemID, b.now())
+		})
+	}
+}
+
+// flushPowerEvents logs the pending power events that are due by now. The
+// caller must hold the state lock.
+func (b *mockBMC) flushPowerEvents(state *mockServerState, systemID string, now time.Time) {
+	for len(state.powerEvents) > 0 && !now.Before(state.powerEvents[0].at) {
+		b.logPowerEvent(state.powerEvents[0].state, systemID, state.powerEvents[0].at)
+		state.powerEvents = state.powerEvents[1:]
+	}
+}
+
+func (b *mockBMC) logPowerEvent(powerState, systemID string, at time.Time) {
+	systemURI := "/redfish/v1/Systems/" + systemID
+	switch powerState {
+	case "On":
+		b.logSystemEvent(systemID, registryMessage("ResourceEvent", "ResourcePoweredOn", systemURI), systemURI, at)
+	case "Off":
+		b.logSystemEvent(systemID, registryMessage("ResourceEvent", "ResourcePoweredOff", systemURI), systemURI, at)
+	}
+}
+
+func (b *mockBMC) getChassisCollection(c *gin.Context) {
 	c.Header("OData-Version", "4.0")
-	ids := activeOEM().resourceIDs()
+	members := b.chassisLinks(func(ChassisConfig) bool { return true })
 	collection := Collection{
 		ODataContext: "/redfish/v1/$metadata#ChassisCollection.ChassisCollection",
 		ODataType:    "#ChassisCollection.ChassisCollection",
 		ODataID:      "/redfish/v1/Chassis",
 		Name:         "Chassis Collection",
-		MembersCount: 1,
-		Members: []Link{
-			{ODataID: "/redfish/v1/Chassis/" + ids.Chassis},
-		},
+		MembersCount: len(members),
+		Members:      members,
 	}
 	c.JSON(http.StatusOK, collection)
 }
 
-func getChassis(c *gin.Context) {
+func (b *mockBMC) getChassis(c *gin.Context) {
 	c.Header("OData-Version", "4.0")
-	chassisID := c.Param("id")
+	chassisConfig, ok := b.findChassis(c.Param("id"))
+	if !ok {
+		resourceNotFound(c, "Chassis", c.Param("id"))
+		return
+	}
+	chassisID := chassisConfig.ID
+	chassisURI := "/redfish/v1/Chassis/" + chassisID
+	powerState := "Off"
+	if b.chassisPoweredOn(chassisID, b.now()) {
+		powerState = "On"
+	}
 
 	chassis := Chassis{
-		ODataContext: "/redfish/v1/$metadata#Chassis.Chassis",
-		ODataType:    "#Chassis.v1_25_0.Chassis",
-		ODataID:      "/redfish/v1/Chassis/" + chassisID,
-		ID:           chassisID,
-		Name:         config.Chassis.Name,
-		ChassisType:  config.Chassis.ChassisType,
-		Manufacturer: config.Chassis.Manufacturer,
-		Model:        config.Chassis.Model,
-		SerialNumber: config.Chassis.SerialNumber,
-		PartNumber:   config.Chassis.PartNumber,
-		Status:       Status{State: "Enabled", Health: "OK"},
+		ODataContext:     "/redfish/v1/$metadata#Chassis.Chassis",
+		ODataType:        "#Chassis.v1_25_0.Chassis",
+		ODataID:          "/redfish/v1/Chassis/" + chassisID,
+		ID:               chassisID,
+		Name:             chassisConfig.Name,
+		ChassisType:      chassisConfig.ChassisType,
+		Manufacturer:     chassisConfig.Manufacturer,
+		Model:            chassisConfig.Model,
+		SerialNumber:     chassisConfig.SerialNumber,
+		PartNumber:       chassisConfig.PartNumber,
+		PowerState:       powerState,
+		Status:           Status{State: "Enabled", Health: "OK", HealthRollup: b.chassisHealthRollup(chassisID)},
+		Thermal:          Link{ODataID: chassisURI + "/Thermal"},
+		Power:            Link{ODataID: chassisURI + "/Power"},
+		ThermalSubsystem: Link{ODataID: chassisURI + "/ThermalSubsystem"},
+		PowerSubsystem:   Link{ODataID: chassisURI + "/PowerSubsystem"},
+		Sensors:          Link{ODataID: chassisURI + "/Sensors"},
+		Links: ChassisLinks{
+			ComputerSystems: b.systemLinks(func(system SystemConfig) bool { return system.ChassisID == chassisID }),
+			ManagedBy:       []Link{{ODataID: "/redfish/v1/Managers/" + chassisConfig.ManagerID}},
+		},
 	}
 	c.JSON(http.StatusOK, chassis)
 }
 
-func getManagersCollection(c *gin.Context) {
+func (b *mockBMC) getManagersCollection(c *gin.Context) {
 	c.Header("OData-Version", "4.0")
-	ids := activeOEM().resourceIDs()
+	members := make([]Link, 0, len(b.config.Managers))
+	for _, manager := range b.config.Managers {
+		members = append(members, Link{ODataID: "/redfish/v1/Managers/" + manager.ID})
+	}
 	collection := Collection{
 		ODataContext: "/redfish/v1/$metadata#ManagerCollection.ManagerCollection",
 		ODataType:    "#ManagerCollection.ManagerCollection",
 		ODataID:      "/redfish/v1/Managers",
 		Name:         "Manager Collection",
-		MembersCount: 1,
-		Members: []Link{
-			{ODataID: "/redfish/v1/Managers/" + ids.Manager},
-		},
+		MembersCount: len(members),
+		Members:      members,
 	}
 	c.JSON(http.StatusOK, collection)
 }
 
-func getManager(c *gin.Context) {
+func (b *mockBMC) getManager(c *gin.Context) {
 	c.Header("OData-Version", "4.0")
-	managerID := c.Param("id")
+	managerConfig, ok := b.findManager(c.Param("id"))
+	if !ok {
+		resourceNotFound(c, "Manager", c.Param("id"))
+		return
+	}
+	managerID := managerConfig.ID
 
 	manager := Manager{
 		ODataContext:    "/redfish/v1/$metadata#Manager.Manager",
 		ODataType:       "#Manager.v1_19_0.Manager",
 		ODataID:         "/redfish/v1/Managers/" + managerID,
 		ID:              managerID,
-		Name:            config.Manager.Name,
-		ManagerType:     config.Manager.ManagerType,
-		FirmwareVersion: config.Manager.FirmwareVersion,
+		Name:            managerConfig.Name,
+		ManagerType:     managerConfig.ManagerType,
+		FirmwareVersion: managerConfig.FirmwareVersion,
 		Status:          Status{State: "Enabled", Health: "OK"},
-		VirtualMedia:    Link{ODataID: "/redfish/v1/Managers/" + managerID + "/VirtualMedia"},
+		NetworkProtocol: Link{ODataID: "/redfish/v1/Managers/" + managerID + "/NetworkProtocol"},
+		LogServices:     Link{ODataID: "/redfish/v1/Managers/" + managerID + "/LogServices"},
+		Links: ManagerLinks{
+			ManagerForServers: b.systemLinks(func(system SystemConfig) bool { return system.ManagerID == managerID }),
+			ManagerForChassis: b.chassisLinks(func(chassis ChassisConfig) bool { return chassis.ManagerID == managerID }),
+		},
+	}
+	if _, ok := b.virtualMediaState(managerID); ok {
+		manager.VirtualMedia = &Link{ODataID: "/redfish/v1/Managers/" + managerID + "/VirtualMedia"}
 	}
 	c.JSON(http.StatusOK, manager)
 }
 
-func getVirtualMediaCollection(c *gin.Context) {
+func (b *mockBMC) getVirtualMediaCollection(c *gin.Context) {
 	c.Header("OData-Version", "4.0")
+	if _, ok := b.lookupVirtualMedia(c); !ok {
+		return
+	}
 	managerID := c.Param("id")
-	mediaID := activeOEM().resourceIDs().VirtualMedia
+	mediaID := b.oem.resourceIDs().VirtualMedia
 	collection := Collection{
 		ODataContext: "/redfish/v1/$metadata#VirtualMediaCollection.VirtualMediaCollection",
 		ODataType:    "#VirtualMediaCollection.VirtualMediaCollection",
@@ -689,25 +1060,25 @@ func getVirtualMediaCollection(c *gin.Context) {
 	c.JSON(http.StatusOK, collection)
 }
 
-func getVirtualMedia(c *gin.Context) {
+func (b *mockBMC) getVirtualMedia(c *gin.Context) {
 	c.Header("OData-Version", "4.0")
-	mediaID := activeOEM().resourceIDs().VirtualMedia
-	if c.Param("mediaID") != mediaID {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Virtual media device not found"})
+	state, ok := b.lookupVirtualMedia(c)
+	if !ok {
 		return
 	}
 
 	managerID := c.Param("id")
+	mediaID := c.Param("mediaID")
 	baseURI := "/redfish/v1/Managers/" + managerID + "/VirtualMedia/" + mediaID
-	mockState.RLock()
+	state.RLock()
 	var image *string
-	if mockState.image != "" {
-		imageValue := mockState.image
+	if state.image != "" {
+		imageValue := state.image
 		image = &imageValue
 	}
-	inserted := mockState.inserted
-	writeProtected := mockState.writeProtected
-	mockState.RUnlock()
+	inserted := state.inserted
+	writeProtected := state.writeProtected
+	state.RUnlock()
 
 	connectedVia := "NotConnected"
 	if inserted {
@@ -732,7 +1103,7 @@ func getVirtualMedia(c *gin.Context) {
 	c.JSON(http.StatusOK, media)
 }
 
-func downloadAndValidateISO(ctx context.Context, imageURL, username, password string) error {
+func downloadAndValidateISO(ctx context.Context, clock timeSource, imageURL, username, password string, download MediaDownloadConfig) error {
 	parsedURL, err := url.ParseRequestURI(imageURL)
 	if err != nil || (parsedURL.Scheme != "http" && parsedURL.Scheme != "https") || parsedURL.Host == "" {
 		return fmt.Errorf("%w: Image must be an HTTP or HTTPS URL", errInvalidISO)
@@ -762,7 +1133,11 @@ func downloadAndValidateISO(ctx context.Context, imageURL, username, password st
 	defer os.Remove(image.Name())
 	defer image.Close()
 
-	size, err := io.Copy(image, response.Body)
+	body, err := simulateDownload(ctx, clock, download, response.Body)
+	if err != nil {
+		return fmt.Errorf("download image: %w", err)
+	}
+	size, err := io.Copy(image, body)
 	if err != nil {
 		return fmt.Errorf("download image: %w", err)
 	}
@@ -798,16 +1173,19 @@ func validateISO(image *os.File, size int64) error {
 	return fmt.Errorf("%w: image is too small or has no primary volume descriptor", errInvalidISO)
 }
 
-func insertMedia(c *gin.Context) {
+func (b *mockBMC) insertMedia(c *gin.Context) {
 	c.Header("OData-Version", "4.0")
-	if c.Param("mediaID") != activeOEM().resourceIDs().VirtualMedia {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Virtual media device not found"})
+	state, ok := b.lookupVirtualMedia(c)
+	if !ok {
 		return
 	}
 
 	var req InsertMediaRequest
-	if err := c.ShouldBindJSON(&req); err != nil || req.Image == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Image is required"})
+	if !bindRedfishJSON(c, &req) {
+		return
+	}
+	if req.Image == "" {
+		redfishError(c, http.StatusBadRequest, baseMessage("ActionParameterMissing", "VirtualMedia.InsertMedia", "Image"))
 		return
 	}
 
@@ -819,42 +1197,51 @@ func insertMedia(c *gin.Context) {
 	if req.WriteProtected != nil {
 		writeProtected = *req.WriteProtected
 	}
-	if err := downloadAndValidateISO(c.Request.Context(), req.Image, req.UserName, req.Password); err != nil {
-		status := http.StatusBadGateway
+	system, _ := b.virtualMediaSystem(c.Param("id"))
+	if err := downloadAndValidateISO(c.Request.Context(), b.clock, req.Image, req.UserName, req.Password, system.MediaDownload); err != nil {
 		if errors.Is(err, errInvalidISO) {
-			status = http.StatusBadRequest
+			c.JSON(http.StatusBadRequest, errorWithDetail(err.Error(),
+				baseMessage("ActionParameterValueFormatError", req.Image, "Image", "VirtualMedia.InsertMedia")))
+			return
 		}
-		c.JSON(status, gin.H{"error": err.Error()})
+		if errors.Is(err, errClockReset) {
+			// The mock was reset while the download waited.
+			c.JSON(http.StatusServiceUnavailable, errorWithDetail(err.Error(), baseMessage("ServiceTemporarilyUnavailable", "0")))
+			return
+		}
+		c.JSON(http.StatusBadGateway, errorWithDetail(err.Error(), baseMessage("CouldNotEstablishConnection", req.Image)))
 		return
 	}
 
-	mockState.Lock()
-	mockState.image = req.Image
-	mockState.inserted = inserted
-	mockState.writeProtected = writeProtected
+	state.Lock()
+	state.image = req.Image
+	state.inserted = inserted
+	state.writeProtected = writeProtected
 	if inserted {
-		mockState.installationStatus = "MediaMounted"
+		state.installationStatus = "MediaMounted"
 	}
-	mockState.Unlock()
+	state.Unlock()
+	b.logManagerEvent(c.Param("id"), registryMessage("ResourceEvent", "ResourceChanged"), b.virtualMediaURI(c.Param("id")), b.now())
 
 	c.Status(http.StatusNoContent)
 }
 
-func ejectMedia(c *gin.Context) {
+func (b *mockBMC) ejectMedia(c *gin.Context) {
 	c.Header("OData-Version", "4.0")
-	if c.Param("mediaID") != activeOEM().resourceIDs().VirtualMedia {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Virtual media device not found"})
+	state, ok := b.lookupVirtualMedia(c)
+	if !ok {
 		return
 	}
 
-	mockState.Lock()
-	mockState.image = ""
-	mockState.inserted = false
-	mockState.writeProtected = true
-	if mockState.installationStatus != "Installed" {
-		mockState.installationStatus = "Ready"
+	state.Lock()
+	state.image = ""
+	state.inserted = false
+	state.writeProtected = true
+	if state.installationStatus != "Installed" {
+		state.installationStatus = "Ready"
 	}
-	mockState.Unlock()
+	state.Unlock()
+	b.logManagerEvent(c.Param("id"), registryMessage("ResourceEvent", "ResourceChanged"), b.virtualMediaURI(c.Param("id")), b.now())
 
 	c.Status(http.StatusNoContent)
 }
@@ -880,10 +1267,11 @@ func getUpdateService(c *gin.Context) {
 	c.JSON(http.StatusOK, updateService)
 }
 
-func getFirmwareInventoryCollection(c *gin.Context) {
+func (b *mockBMC) getFirmwareInventoryCollection(c *gin.Context) {
 	c.Header("OData-Version", "4.0")
-	members := make([]Link, 0, len(config.Firmware))
-	for _, item := range config.Firmware {
+	items := b.firmware.current()
+	members := make([]Link, 0, len(items))
+	for _, item := range items {
 		members = append(members, Link{ODataID: "/redfish/v1/UpdateService/FirmwareInventory/" + item.ID})
 	}
 	collection := Collection{
@@ -897,11 +1285,12 @@ func getFirmwareInventoryCollection(c *gin.Context) {
 	c.JSON(http.StatusOK, collection)
 }
 
-func getFirmwareInventoryItem(c *gin.Context) {
+func (b *mockBMC) getFirmwareInventoryItem(c *gin.Context) {
 	c.Header("OData-Version", "4.0")
 	itemID := c.Param("id")
 
-	for _, item := range config.Firmware {
+	b.tasks.advance(b.now())
+	for _, item := range b.firmware.current() {
 		if item.ID == itemID {
 			c.JSON(http.StatusOK, SoftwareInventory{
 				ODataContext: "/redfish/v1/$metadata#SoftwareInventory.SoftwareInventory",
@@ -917,35 +1306,80 @@ func getFirmwareInventoryItem(c *gin.Context) {
 			return
 		}
 	}
-	c.JSON(http.StatusNotFound, gin.H{"error": "Not found"})
+	resourceNotFound(c, "SoftwareInventory", itemID)
 }
 
-func simpleUpdate(c *gin.Context) {
+func (b *mockBMC) simpleUpdate(c *gin.Context) {
 	c.Header("OData-Version", "4.0")
 	var req SimpleUpdateRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON"})
+	if !bindRedfishJSON(c, &req) {
 		return
 	}
 
+	const action = "UpdateService.SimpleUpdate"
 	if req.ImageURI == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "ImageURI is required"})
+		redfishError(c, http.StatusBadRequest, baseMessage("ActionParameterMissing", action, "ImageURI"))
 		return
 	}
-
-	// Mock response - in a real implementation, this would start an update task
-	response := map[string]any{
-		"@Message.ExtendedInfo": []map[string]any{
-			{
-				"MessageId": "Update.1.0.0.UpdateInProgress",
-				"Message":   "The update operation has been started and is in progress.",
-				"Severity":  "OK",
-			},
-		},
+	parsedURI, err := url.ParseRequestURI(req.ImageURI)
+	if err != nil || (parsedURI.Scheme != "http" && parsedURI.Scheme != "https") || parsedURI.Host == "" {
+		redfishError(c, http.StatusBadRequest, baseMessage("ActionParameterValueFormatError", req.ImageURI, "ImageURI", action))
+		return
+	}
+	switch req.TransferProtocol {
+	case "", "HTTP", "HTTPS":
+	default:
+		redfishError(c, http.StatusBadRequest, baseMessage("ActionParameterValueNotInList", req.TransferProtocol, "TransferProtocol", action))
+		return
 	}
+	targets, invalidTarget := b.firmware.targetIDs(req.Targets)
+	if invalidTarget != "" {
+		redfishError(c, http.StatusBadRequest, baseMessage("ActionParameterValueNotInList", invalidTarget, "Targets", action))
+		return
+	}
+
+	now := b.now()
+	applyDuration := time.Duration(b.config.UpdateService.UpdateDurationSeconds) * time.Second
+	taskID := b.tasks.start("Firmware Update", applyDuration, now, func(ctx context.Context) (func(), error) {
+		metadata, err := downloadFirmwareImage(ctx, req.ImageURI, req.Username, req.Password)
+		if err != nil {
+			// A reset cancels the download, and the reset EventService
+			// has no use for its failure.
+			if ctx.Err() == nil {
+				b.publishTransferFailed(req.ImageURI, targets)
+			}
+			return nil, err
+		}
+		update, err := b.resolveFirmwareUpdate(req.ImageURI, targets, metadata)
+		if err != nil {
+			b.publishTransferFailed(req.ImageURI, targets)
+			return nil, err
+		}
+		return func() {
+			b.firmware.apply(update)
+			for _, target := range update.targets {
+				b.events.publish(registryMessage("Update", "UpdateSuccessful", target, req.ImageURI),
+					"/redfish/v1/UpdateService/FirmwareInventory/"+target, b.now())
+			}
+		}, nil
+	})
+	task, _ := b.tasks.get(taskID, now)
 
-	c.Header("Location", "/redfish/v1/TaskService/Tasks/1")
-	c.JSON(http.StatusAccepted, response)
+	c.Header("Location", task.TaskMonitor)
+	c.JSON(http.StatusAccepted, task)
+}
+
+// publishTransferFailed reports a failed SimpleUpdate transfer. Targets are
+// empty when the image was to pick its own.
+func (b *mockBMC) publishTransferFailed(imageURI string, targets []string) {
+	if len(targets) == 0 {
+		b.events.publish(registryMessage("Update", "TransferFailed", imageURI, "UpdateService"), "/redfish/v1/UpdateService", b.now())
+		return
+	}
+	for _, target := range targets {
+		b.events.publish(registryMessage("Update", "TransferFailed", imageURI, target),
+			"/redfish/v1/UpdateService/FirmwareInventory/"+target, b.now())
+	}
 }
 
 func getLicenseService(c *gin.Context) {
@@ -978,7 +1412,7 @@ func getLicensesCollection(c *gin.Context) {
 	c.JSON(http.StatusOK, collection)
 }
 
-func getLicense(c *gin.Context) {
+func (b *mockBMC) getLicense(c *gin.Context) {
 	c.Header("OData-Version", "4.0")
 	licenseID := c.Param("id")
 
@@ -999,7 +1433,7 @@ func getLicense(c *gin.Context) {
 			MaxAuthorizedCount: 1,
 			RemainingUseCount:  1,
 			Status:             Status{State: "Enabled", Health: "OK"},
-			Manufacturer:       config.ServiceRoot.Vendor,
+			Manufacturer:       b.config.ServiceRoot.Vendor,
 			PartNumber:         "BMC-LIC-001",
 			SerialNumber:       "BMC123456789",
 			SKU:                "BMC-PROD-LIC",
@@ -1016,81 +1450,267 @@ func getLicense(c *gin.Context) {
 			LicenseOrigin: "BuiltIn",
 			InstallDate:   "2024-01-15T08:00:00Z",
 			Status:        Status{State: "Enabled", Health: "OK"},
-			Manufacturer:  config.ServiceRoot.Vendor,
+			Manufacturer:  b.config.ServiceRoot.Vendor,
 			PartNumber:    "BIOS-LIC-001",
 			SerialNumber:  "BIOS123456789",
 			SKU:           "BIOS-PROD-LIC",
 			Links:         struct{}{},
 		}
 	default:
-		c.JSON(http.StatusNotFound, gin.H{"error": "Not found"})
+		resourceNotFound(c, "License", licenseID)
 		return
 	}
 
 	c.JSON(http.StatusOK, license)
 }
 
-func main() {
-	port := flag.String("port", "8080", "Port to listen on")
-	host := flag.String("host", "localhost", "Host to listen on")
-	configPath := flag.String("config", "config.json", "Path to mock data config file")
-	flag.Parse()
-
-	loadedConfig, err := loadConfig(*configPath)
-	if err != nil {
-		log.Fatalf("load config %q: %v. Might need to copy config.json.default to config.json", *configPath, err)
-	}
-	config = loadedConfig
-
-	r := gin.Default()
+func newRouter(b *mockBMC) *gin.Engine {
+	// Chaos injection runs outside the recovery middleware so that it can
+	// abort connections. The mockup answers whatever the built-in handlers
+	// do not know.
+	r := gin.New()
+	r.Use(gin.Logger(), b.injectChaos(), gin.Recovery(), b.recordOrReplay(), b.serveMockup())
+	r.NoRoute(notFoundRoute)
+	// A recording proxy passes every path on as the client sent it.
+	r.RedirectTrailingSlash = b.recorder.config.Mode != recordingRecord
 
 	// Public endpoints (no auth required)
-	r.GET("/redfish/v1/", getServiceRoot)
-	r.GET("/redfish/v1", getServiceRoot)
-	r.GET("/redfish/v1/Managers", getManagersCollection)
-	r.GET("/redfish/v1/Managers/", getManagersCollection)
-
-	// Protected endpoints (require auth)
+	r.GET("/redfish/v1/", b.getServiceRoot)
+	r.GET("/redfish/v1", b.getServiceRoot)
+	r.GET("/redfish/v1/$metadata", getMetadata)
+	r.GET("/redfish/v1/odata", getODataServiceDocument(r))
+	r.GET("/redfish/v1/Managers", b.getManagersCollection)
+	r.GET("/redfish/v1/Managers/", b.getManagersCollection)
+	r.POST("/redfish/v1/SessionService/Sessions", b.createSession)
+	r.POST("/redfish/v1/SessionService/Sessions/", b.createSession)
+
+	// Protected endpoints (require Basic auth or an X-Auth-Token session).
+	// Routes that change state also check the caller's role privileges.
 	protected := r.Group("/redfish/v1")
-	protected.Use(basicAuth())
+	protected.Use(b.requireAuth())
+
+	// SessionService endpoints
+	protected.GET("/SessionService", b.getSessionService)
+	protected.GET("/SessionService/", b.getSessionService)
+	protected.PATCH("/SessionService", requirePrivilege(privilegeConfigureManager), b.patchSessionService)
+	protected.GET("/SessionService/Sessions", b.getSessionsCollection)
+	protected.GET("/SessionService/Sessions/", b.getSessionsCollection)
+	protected.GET("/SessionService/Sessions/:id", b.getSession)
+	protected.DELETE("/SessionService/Sessions/:id", b.deleteSession)
+
+	// AccountService endpoints
+	protected.GET("/AccountService", b.getAccountService)
+	protected.GET("/AccountService/", b.getAccountService)
+	protected.PATCH("/AccountService", requirePrivilege(privilegeConfigureUsers), b.patchAccountService)
+	protected.GET("/AccountService/Accounts", b.getAccountsCollection)
+	protected.GET("/AccountService/Accounts/", b.getAccountsCollection)
+	protected.POST("/AccountService/Accounts", requirePrivilege(privilegeConfigureUsers), b.createAccount)
+	protected.GET("/AccountService/Accounts/:id", b.getAccount)
+	protected.PATCH("/AccountService/Accounts/:id", b.patchAccount)
+	protected.DELETE("/AccountService/Accounts/:id", requirePrivilege(privilegeConfigureUsers), b.deleteAccount)
+	protected.GET("/AccountService/Roles", getRolesCollection)
+	protected.GET("/AccountService/Roles/", getRolesCollection)
+	protected.GET("/AccountService/Roles/:id", getRole)
 
 	// Systems endpoints
-	protected.GET("/Systems", getSystemsCollection)
-	protected.GET("/Systems/", getSystemsCollection)
-	protected.GET("/Systems/:id", getSystem)
-	protected.PATCH("/Systems/:id", patchSystem)
-	protected.POST("/Systems/:id/Actions/ComputerSystem.Reset", resetSystem)
+	protected.GET("/Systems", b.getSystemsCollection)
+	protected.GET("/Systems/", b.getSystemsCollection)
+	protected.GET("/Systems/:id", b.getSystem)
+	protected.PATCH("/Systems/:id", requirePrivilege(privilegeConfigureComponents), b.patchSystem)
+	protected.POST("/Systems/:id/Actions/ComputerSystem.Reset", requirePrivilege(privilegeConfigureComponents), b.resetSystem)
+	protected.GET("/Systems/:id/Processors", b.getProcessorsCollection)
+	protected.GET("/Systems/:id/Processors/", b.getProcessorsCollection)
+	protected.GET("/Systems/:id/Processors/:componentID", b.getProcessor)
+	protected.GET("/Systems/:id/Memory", b.getMemoryCollection)
+	protected.GET("/Systems/:id/Memory/", b.getMemoryCollection)
+	protected.GET("/Systems/:id/Memory/:componentID", b.getMemory)
+	protected.GET("/Systems/:id/LogServices", b.getLogServicesCollection)
+	protected.GET("/Systems/:id/LogServices/", b.getLogServicesCollection)
+	protected.GET("/Systems/:id/LogServices/:logID", b.getLogService)
+	protected.GET("/Systems/:id/LogServices/:logID/Entries", b.getLogEntries)
+	protected.GET("/Systems/:id/LogServices/:logID/Entries/", b.getLogEntries)
+	protected.GET("/Systems/:id/LogServices/:logID/Entries/:entryID", b.getLogEntry)
+	protected.POST("/Systems/:id/LogServices/:logID/Actions/LogService.ClearLog", requirePrivilege(privilegeConfigureComponents), b.clearLog)
 
 	// Chassis endpoints
-	protected.GET("/Chassis", getChassisCollection)
-	protected.GET("/Chassis/", getChassisCollection)
-	protected.GET("/Chassis/:id", getChassis)
+	protected.GET("/Chassis", b.getChassisCollection)
+	protected.GET("/Chassis/", b.getChassisCollection)
+	protected.GET("/Chassis/:id", b.getChassis)
+	protected.GET("/Chassis/:id/Thermal", b.getThermal)
+	protected.GET("/Chassis/:id/Power", b.getPower)
+	protected.GET("/Chassis/:id/ThermalSubsystem", b.getThermalSubsystem)
+	protected.GET("/Chassis/:id/ThermalSubsystem/Fans", b.getFansCollection)
+	protected.GET("/Chassis/:id/ThermalSubsystem/Fans/", b.getFansCollection)
+	protected.GET("/Chassis/:id/ThermalSubsystem/Fans/:sensorID", b.getFan)
+	protected.GET("/Chassis/:id/PowerSubsystem", b.getPowerSubsystem)
+	protected.GET("/Chassis/:id/PowerSubsystem/PowerSupplies", b.getPowerSuppliesCollection)
+	protected.GET("/Chassis/:id/PowerSubsystem/PowerSupplies/", b.getPowerSuppliesCollection)
+	protected.GET("/Chassis/:id/PowerSubsystem/PowerSupplies/:sensorID", b.getPowerSupply)
+	protected.GET("/Chassis/:id/Sensors", b.getSensorsCollection)
+	protected.GET("/Chassis/:id/Sensors/", b.getSensorsCollection)
+	protected.GET("/Chassis/:id/Sensors/:sensorID", b.getSensor)
 
 	// Manager individual endpoints (still protected)
-	protected.GET("/Managers/:id", getManager)
-	protected.GET("/Managers/:id/VirtualMedia", getVirtualMediaCollection)
-	protected.GET("/Managers/:id/VirtualMedia/", getVirtualMediaCollection)
-	protected.GET("/Managers/:id/VirtualMedia/:mediaID", getVirtualMedia)
-	protected.POST("/Managers/:id/VirtualMedia/:mediaID/Actions/VirtualMedia.InsertMedia", insertMedia)
-	protected.POST("/Managers/:id/VirtualMedia/:mediaID/Actions/VirtualMedia.EjectMedia", ejectMedia)
+	protected.GET("/Managers/:id", b.getManager)
+	protected.GET("/Managers/:id/VirtualMedia", b.getVirtualMediaCollection)
+	protected.GET("/Managers/:id/VirtualMedia/", b.getVirtualMediaCollection)
+	protected.GET("/Managers/:id/VirtualMedia/:mediaID", b.getVirtualMedia)
+	protected.POST("/Managers/:id/VirtualMedia/:mediaID/Actions/VirtualMedia.InsertMedia", requirePrivilege(privilegeConfigureComponents), b.insertMedia)
+	protected.POST("/Managers/:id/VirtualMedia/:mediaID/Actions/VirtualMedia.EjectMedia", requirePrivilege(privilegeConfigureComponents), b.ejectMedia)
+	protected.GET("/Managers/:id/NetworkProtocol", b.getManagerNetworkProtocol)
+	protected.GET("/Managers/:id/LogServices", b.getLogServicesCollection)
+	protected.GET("/Managers/:id/LogServices/", b.getLogServicesCollection)
+	protected.GET("/Managers/:id/LogServices/:logID", b.getLogService)
+	protected.GET("/Managers/:id/LogServices/:logID/Entries", b.getLogEntries)
+	protected.GET("/Managers/:id/LogServices/:logID/Entries/", b.getLogEntries)
+	protected.GET("/Managers/:id/LogServices/:logID/Entries/:entryID", b.getLogEntry)
+	protected.POST("/Managers/:id/LogServices/:logID/Actions/LogService.ClearLog", requirePrivilege(privilegeConfigureManager), b.clearLog)
+	protected.GET("/Managers/:id/NetworkProtocol/HTTPS/Certificates", b.getManagerCertificatesCollection)
+	protected.GET("/Managers/:id/NetworkProtocol/HTTPS/Certificates/", b.getManagerCertificatesCollection)
+	protected.GET("/Managers/:id/NetworkProtocol/HTTPS/Certificates/:certID", b.getManagerCertificate)
+
+	// CertificateService endpoints
+	protected.GET("/CertificateService", getCertificateService)
+	protected.GET("/CertificateService/", getCertificateService)
+	protected.GET("/CertificateService/CertificateLocations", b.getCertificateLocations)
+	protected.POST("/CertificateService/Actions/CertificateService.GenerateCSR", requirePrivilege(privilegeConfigureManager), b.generateCSR)
+	protected.POST("/CertificateService/Actions/CertificateService.ReplaceCertificate", requirePrivilege(privilegeConfigureManager), b.replaceCertificate)
+
+	// EventService endpoints
+	protected.GET("/EventService", b.getEventService)
+	protected.GET("/EventService/", b.getEventService)
+	protected.PATCH("/EventService", requirePrivilege(privilegeConfigureManager), b.patchEventService)
+	protected.GET("/EventService/SSE", b.streamEvents)
+	protected.GET("/EventService/Subscriptions", b.getSubscriptionsCollection)
+	protected.GET("/EventService/Subscriptions/", b.getSubscriptionsCollection)
+	protected.POST("/EventService/Subscriptions", requirePrivilege(privilegeConfigureManager), b.createSubscription)
+	protected.GET("/EventService/Subscriptions/:id", b.getSubscription)
+	protected.DELETE("/EventService/Subscriptions/:id", requirePrivilege(privilegeConfigureManager), b.deleteSubscription)
+	protected.POST("/EventService/Actions/EventService.SubmitTestEvent", requirePrivilege(privilegeConfigureManager), b.submitTestEvent)
 
 	// UpdateService endpoints
 	protected.GET("/UpdateService", getUpdateService)
 	protected.GET("/UpdateService/", getUpdateService)
-	protected.GET("/UpdateService/FirmwareInventory", getFirmwareInventoryCollection)
-	protected.GET("/UpdateService/FirmwareInventory/", getFirmwareInventoryCollection)
-	protected.GET("/UpdateService/FirmwareInventory/:id", getFirmwareInventoryItem)
-	protected.POST("/UpdateService/Actions/UpdateService.SimpleUpdate", simpleUpdate)
+	protected.GET("/UpdateService/FirmwareInventory", b.getFirmwareInventoryCollection)
+	protected.GET("/UpdateService/FirmwareInventory/", b.getFirmwareInventoryCollection)
+	protected.GET("/UpdateService/FirmwareInventory/:id", b.getFirmwareInventoryItem)
+	protected.POST("/UpdateService/Actions/UpdateService.SimpleUpdate", requirePrivilege(privilegeConfigureComponents), b.simpleUpdate)
+
+	// TaskService endpoints
+	protected.GET("/TaskService", b.getTaskService)
+	protected.GET("/TaskService/", b.getTaskService)
+	protected.GET("/TaskService/Tasks", b.getTasksCollection)
+	protected.GET("/TaskService/Tasks/", b.getTasksCollection)
+	protected.GET("/TaskService/Tasks/:id", b.getTask)
+	protected.GET("/TaskService/TaskMonitors/:id", b.getTaskMonitor)
+
+	// Registries endpoints
+	protected.GET("/Registries", getRegistriesCollection)
+	protected.GET("/Registries/", getRegistriesCollection)
+	protected.GET("/Registries/:id", getRegistryFile)
+	protected.GET("/Registries/:id/:file", getRegistry)
 
 	// LicenseService endpoints
 	protected.GET("/LicenseService", getLicenseService)
 	protected.GET("/LicenseService/", getLicenseService)
 	protected.GET("/LicenseService/Licenses", getLicensesCollection)
 	protected.GET("/LicenseService/Licenses/", getLicensesCollection)
-	protected.GET("/LicenseService/Licenses/:id", getLicense)
+	protected.GET("/LicenseService/Licenses/:id", b.getLicense)
+
+	// Mock control endpoints. They are not part of Redfish; tests use them to
+	// inject hardware faults and network chaos and to inspect, override, and
+	// reset state.
+	mock := r.Group("/mock")
+	mock.Use(b.requireMockAPI())
+	mock.GET("/faults", b.getFaults)
+	mock.POST("/faults", requirePrivilege(privilegeConfigureManager), b.createFault)
+	mock.GET("/faults/:id", b.getFault)
+	mock.DELETE("/faults/:id", requirePrivilege(privilegeConfigureManager), b.deleteFault)
+	mock.GET("/chaos", b.getChaos)
+	mock.PUT("/chaos", requirePrivilege(privilegeConfigureManager), b.putChaos)
+	admin := mock.Group("/admin", requirePrivilege(privilegeConfigureManager))
+	admin.GET("/state", b.getAdminState)
+	admin.PATCH("/state", b.patchAdminState)
+	admin.POST("/reset", b.resetAdminState)
+	admin.GET("/clock", b.getAdminClock)
+	admin.POST("/clock", b.setAdminClock)
+
+	return r
+}
 
-	addr := *host + ":" + *port
-	log.Printf("\nStarting RedFish Mock Server on %s", addr)
-	log.Printf("\nBMC username: %s", config.Authentication.Username)
-	log.Fatal(r.Run(addr))
+func main() {
+	port := flag.String("port", "8080", "Port to listen on")
+	host := flag.String("host", "localhost", "Host to listen on")
+	configPath := flag.String("config", "config.json", "Path to mock data config file")
+	fleetPath := flag.String("fleet", "", "Path to a fleet description; starts one mock BMC per entry")
+	count := flag.Int("count", 0, "Start this many mock BMCs from -config on consecutive ports from -port")
+	useTLS := flag.Bool("tls", false, "Serve HTTPS with a self-signed certificate for -host unless -tls-cert and -tls-key are given")
+	certFile := flag.String("tls-cert", "", "PEM certificate file for HTTPS; implies -tls")
+	keyFile := flag.String("tls-key", "", "PEM private key file for HTTPS; implies -tls")
+	httpPort := flag.String("http-port", "", "With -tls, also listen for plain HTTP on this port")
+	httpRedirect := flag.Bool("http-redirect", false, "Redirect the -http-port listener to HTTPS instead of serving the API")
+	flag.Parse()
+
+	serveTLS := *useTLS || *certFile != "" || *keyFile != ""
+	if !serveTLS && (*httpPort != "" || *httpRedirect) {
+		log.Fatal("-http-port and -http-redirect require -tls")
+	}
+	if *httpRedirect && *httpPort == "" {
+		log.Fatal("-http-redirect requires -http-port")
+	}
+
+	if *fleetPath != "" || *count > 0 {
+		var members []fleetMember
+		var err error
+		if *fleetPath != "" {
+			members, err = loadFleet(*fleetPath)
+		} else {
+			fleet := defaultFleetConfig()
+			fleet.Host, fleet.Count, fleet.Template = *host, *count, *configPath
+			fleet.Port, err = strconv.Atoi(*port)
+			if err == nil {
+				members, err = fleet.members(".")
+			}
+		}
+		if err != nil {
+			log.Fatalf("load fleet: %v", err)
+		}
+		if *httpPort != "" {
+			log.Fatal("-http-port is not supported with fleets")
+		}
+		log.Fatal(runFleet(members, serveTLS, *certFile, *keyFile))
+	}
+
+	loadedConfig, err := loadConfig(*configPath)
+	if err != nil {
+		log.Fatalf("load config %q: %v. Might need to copy config.json.default to config.json", *configPath, err)
+	}
+	bmc, err := newMockBMC(loadedConfig)
+	if err != nil {
+		log.Fatalf("start mock BMC: %v", err)
+	}
+	r := newRouter(bmc)
+
+	listen := listenOptions{addr: net.JoinHostPort(*host, *port), redirect: *httpRedirect}
+	scheme := "http"
+	if serveTLS {
+		certificate, err := serverCertificate(*certFile, *keyFile, *host)
+		if err != nil {
+			log.Fatalf("configure TLS: %v", err)
+		}
+		bmc.certificates.install(certificate)
+		listen.tlsConfig = bmc.certificates.tlsConfig()
+		scheme = "https"
+	}
+	log.Printf("\nStarting RedFish Mock Server on %s://%s", scheme, listen.addr)
+	if *httpPort != "" {
+		listen.httpAddr = net.JoinHostPort(*host, *httpPort)
+		if listen.redirect {
+			log.Printf("\nRedirecting http://%s to HTTPS", listen.httpAddr)
+		} else {
+			log.Printf("\nAlso serving plain HTTP on http://%s", listen.httpAddr)
+		}
+	}
+	log.Printf("\nBMC username: %s", loadedConfig.Authentication.Username)
+	log.Fatal(listen.serve(r))
 }
@@ -13,6 +13,16 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
+// newTestBMC builds a BMC and fails the test if the config cannot be used.
+func newTestBMC(t testing.TB, config Config) *mockBMC {
+	t.Helper()
+	bmc, err := newMockBMC(config)
+	if err != nil {
+		t.Fatalf("newMockBMC: %v", err)
+	}
+	return bmc
+}
+
 func TestOEMProfiles(t *testing.T) {
 	tests := []struct {
 		name           string
@@ -95,15 +105,13 @@ func TestLoadConfigAndConfiguredResponses(t *testing.T) {
 	if err != nil {
 		t.Fatalf("loadConfig() error = %v", err)
 	}
-	previousConfig := config
-	config = loaded
-	t.Cleanup(func() { config = previousConfig })
+	bmc := newTestBMC(t, loaded)
 
 	gin.SetMode(gin.TestMode)
 	recorder := httptest.NewRecorder()
 	ctx, _ := gin.CreateTestContext(recorder)
 	ctx.Params = gin.Params{{Key: "id", Value: "1"}}
-	getSystem(ctx)
+	bmc.getSystem(ctx)
 
 	var system ComputerSystem
 	if err := json.Unmarshal(recorder.Body.Bytes(), &system); err != nil {
@@ -120,7 +128,7 @@ func TestLoadConfigAndConfiguredResponses(t *testing.T) {
 	recorder = httptest.NewRecorder()
 	ctx, _ = gin.CreateTestContext(recorder)
 	ctx.Params = gin.Params{{Key: "id", Value: "CPLD"}}
-	getFirmwareInventoryItem(ctx)
+	bmc.getFirmwareInventoryItem(ctx)
 	var firmware SoftwareInventory
 	if err := json.Unmarshal(recorder.Body.Bytes(), &firmware); err != nil {
 		t.Fatalf("decode firmware response: %v", err)
@@ -130,7 +138,7 @@ func TestLoadConfigAndConfiguredResponses(t *testing.T) {
 	}
 
 	router := gin.New()
-	router.Use(basicAuth())
+	router.Use(bmc.requireAuth())
 	router.GET("/protected", func(c *gin.Context) { c.Status(http.StatusNoContent) })
 	request := httptest.NewRequest(http.MethodGet, "/protected", nil)
 	request.SetBasicAuth("bmc-user", "bmc-secret")
@@ -155,7 +163,7 @@ func TestDownloadAndValidateISO(t *testing.T) {
 	}))
 	defer server.Close()
 
-	if err := downloadAndValidateISO(context.Background(), server.URL+"/installer.iso", "iso-user", "iso-password"); err != nil {
+	if err := downloadAndValidateISO(context.Background(), newMockClock(ClockConfig{}), server.URL+"/installer.iso", "iso-user", "iso-password", MediaDownloadConfig{}); err != nil {
 		t.Fatalf("downloadAndValidateISO() error = %v", err)
 	}
 	if receivedBytes != len(image) {
@@ -169,7 +177,7 @@ func TestDownloadAndValidateISORejectsInvalidImage(t *testing.T) {
 	}))
 	defer server.Close()
 
-	err := downloadAndValidateISO(context.Background(), server.URL+"/not-an-iso", "", "")
+	err := downloadAndValidateISO(context.Background(), newMockClock(ClockConfig{}), server.URL+"/not-an-iso", "", "", MediaDownloadConfig{})
 	if !errors.Is(err, errInvalidISO) {
 		t.Fatalf("downloadAndValidateISO() error = %v, want errInvalidISO", err)
 	}
@@ -181,7 +189,7 @@ func TestDownloadAndValidateISORejectsDownloadFailure(t *testing.T) {
 	}))
 	defer server.Close()
 
-	err := downloadAndValidateISO(context.Background(), server.URL+"/installer.iso", "", "")
+	err := downloadAndValidateISO(context.Background(), newMockClock(ClockConfig{}), server.URL+"/installer.iso", "", "", MediaDownloadConfig{})
 	if err == nil || errors.Is(err, errInvalidISO) {
 		t.Fatalf("downloadAndValidateISO() error = %v, want non-validation download error", err)
 	}
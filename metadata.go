@@ -0,0 +1,190 @@
+package main
+
+import (
+	"encoding/xml"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+type odataSchema struct {
+	Namespace string
+	Versions  []string
+}
+
+// metadataSchemas lists every DMTF schema the mock serves. Add an entry here
+// when a handler starts returning a new @odata.type.
+var metadataSchemas = []odataSchema{
+	{Namespace: "AccountService", Versions: []string{"v1_13_0"}},
+	{Namespace: "Certificate", Versions: []string{"v1_5_0"}},
+	{Namespace: "CertificateCollection"},
+	{Namespace: "CertificateLocations", Versions: []string{"v1_0_2"}},
+	{Namespace: "CertificateService", Versions: []string{"v1_0_4"}},
+	{Namespace: "Chassis", Versions: []string{"v1_25_0"}},
+	{Namespace: "ChassisCollection"},
+	{Namespace: "ComputerSystem", Versions: []string{"v1_22_0"}},
+	{Namespace: "ComputerSystemCollection"},
+	{Namespace: "Event", Versions: []string{"v1_7_0"}},
+	{Namespace: "EventDestination", Versions: []string{"v1_13_0"}},
+	{Namespace: "EventDestinationCollection"},
+	{Namespace: "EventService", Versions: []string{"v1_10_0"}},
+	{Namespace: "Fan", Versions: []string{"v1_5_0"}},
+	{Namespace: "FanCollection"},
+	{Namespace: "License", Versions: []string{"v1_1_0"}},
+	{Namespace: "LicenseCollection"},
+	{Namespace: "LicenseService", Versions: []string{"v1_1_0"}},
+	{Namespace: "LogEntry", Versions: []string{"v1_15_0"}},
+	{Namespace: "LogEntryCollection"},
+	{Namespace: "LogService", Versions: []string{"v1_5_0"}},
+	{Namespace: "LogServiceCollection"},
+	{Namespace: "Manager", Versions: []string{"v1_19_0"}},
+	{Namespace: "ManagerAccount", Versions: []string{"v1_10_0"}},
+	{Namespace: "ManagerAccountCollection"},
+	{Namespace: "ManagerCollection"},
+	{Namespace: "ManagerNetworkProtocol", Versions: []string{"v1_9_0"}},
+	{Namespace: "Memory", Versions: []string{"v1_17_0"}},
+	{Namespace: "MemoryCollection"},
+	{Namespace: "Message", Versions: []string{"v1_1_2"}},
+	{Namespace: "MessageRegistry", Versions: []string{"v1_6_0"}},
+	{Namespace: "MessageRegistryFile", Versions: []string{"v1_1_3"}},
+	{Namespace: "MessageRegistryFileCollection"},
+	{Namespace: "Power", Versions: []string{"v1_7_1"}},
+	{Namespace: "PowerSubsystem", Versions: []string{"v1_1_0"}},
+	{Namespace: "PowerSupply", Versions: []string{"v1_5_0"}},
+	{Namespace: "PowerSupplyCollection"},
+	{Namespace: "Processor", Versions: []string{"v1_18_0"}},
+	{Namespace: "ProcessorCollection"},
+	{Namespace: "Resource", Versions: []string{"v1_0_0"}},
+	{Namespace: "Role", Versions: []string{"v1_3_1"}},
+	{Namespace: "RoleCollection"},
+	{Namespace: "Sensor", Versions: []string{"v1_7_0"}},
+	{Namespace: "SensorCollection"},
+	{Namespace: "ServiceRoot", Versions: []string{"v1_15_0"}},
+	{Namespace: "Session", Versions: []string{"v1_7_0"}},
+	{Namespace: "SessionCollection"},
+	{Namespace: "SessionService", Versions: []string{"v1_1_9"}},
+	{Namespace: "SoftwareInventory", Versions: []string{"v1_10_0"}},
+	{Namespace: "SoftwareInventoryCollection"},
+	{Namespace: "Task", Versions: []string{"v1_7_3"}},
+	{Namespace: "TaskCollection"},
+	{Namespace: "TaskService", Versions: []string{"v1_2_1"}},
+	{Namespace: "Thermal", Versions: []string{"v1_7_1"}},
+	{Namespace: "ThermalSubsystem", Versions: []string{"v1_3_0"}},
+	{Namespace: "UpdateService", Versions: []string{"v1_12_0"}},
+	{Namespace: "VirtualMedia", Versions: []string{"v1_6_0"}},
+	{Namespace: "VirtualMediaCollection"},
+}
+
+type csdlDocument struct {
+	XMLName      xml.Name        `xml:"edmx:Edmx"`
+	XMLNS        string          `xml:"xmlns:edmx,attr"`
+	Version      string          `xml:"Version,attr"`
+	References   []csdlReference `xml:"edmx:Reference"`
+	DataServices csdlServices    `xml:"edmx:DataServices"`
+}
+
+type csdlReference struct {
+	URI      string        `xml:"Uri,attr"`
+	Includes []csdlInclude `xml:"edmx:Include"`
+}
+
+type csdlInclude struct {
+	Namespace string `xml:"Namespace,attr"`
+	Alias     string `xml:"Alias,attr,omitempty"`
+}
+
+type csdlServices struct {
+	Schema csdlSchema `xml:"Schema"`
+}
+
+type csdlSchema struct {
+	XMLNS     string        `xml:"xmlns,attr"`
+	Namespace string        `xml:"Namespace,attr"`
+	Container csdlContainer `xml:"EntityContainer"`
+}
+
+type csdlContainer struct {
+	Name    string `xml:"Name,attr"`
+	Extends string `xml:"Extends,attr"`
+}
+
+type ODataServiceDocument struct {
+	ODataContext string         `json:"@odata.context"`
+	Value        []ODataService `json:"value"`
+}
+
+type ODataService struct {
+	Name string `json:"name"`
+	Kind string `json:"kind"`
+	URL  string `json:"url"`
+}
+
+func metadataDocument() csdlDocument {
+	document := csdlDocument{
+		XMLNS:   "http://docs.oasis-open.org/odata/ns/edmx",
+		Version: "4.0",
+		References: []csdlReference{
+			{
+				URI: "http://redfish.dmtf.org/schemas/v1/RedfishExtensions_v1.xml",
+				Includes: []csdlInclude{
+					{Namespace: "RedfishExtensions.v1_0_0", Alias: "Redfish"},
+				},
+			},
+		},
+		DataServices: csdlServices{
+			Schema: csdlSchema{
+				XMLNS:     "http://docs.oasis-open.org/odata/ns/edm",
+				Namespace: "Service",
+				Container: csdlContainer{Name: "Service", Extends: "ServiceRoot.v1_15_0.ServiceContainer"},
+			},
+		},
+	}
+	for _, schema := range metadataSchemas {
+		reference := csdlReference{
+			URI:      "http://redfish.dmtf.org/schemas/v1/" + schema.Namespace + "_v1.xml",
+			Includes: []csdlInclude{{Namespace: schema.Namespace}},
+		}
+		for _, version := range schema.Versions {
+			reference.Includes = append(reference.Includes, csdlInclude{Namespace: schema.Namespace + "." + version})
+		}
+		document.References = append(document.References, reference)
+	}
+	return document
+}
+
+func getMetadata(c *gin.Context) {
+	c.Header("OData-Version", "4.0")
+	body, err := xml.MarshalIndent(metadataDocument(), "", "  ")
+	if err != nil {
+		redfishError(c, http.StatusInternalServerError, baseMessage("InternalError"))
+		return
+	}
+	c.Data(http.StatusOK, "application/xml; charset=utf-8", append([]byte(xml.Header), body...))
+}
+
+// getODataServiceDocument lists the top-level resources registered on r, so
+// new services show up without editing a separate table.
+func getODataServiceDocument(r *gin.Engine) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("OData-Version", "4.0")
+		services := []ODataService{{Name: "Service", Kind: "Singleton", URL: "/redfish/v1/"}}
+		seen := map[string]bool{}
+		for _, route := range r.Routes() {
+			name, ok := strings.CutPrefix(route.Path, "/redfish/v1/")
+			if route.Method != http.MethodGet || !ok || name == "" || strings.ContainsAny(name, "/:$") || name == "odata" || seen[name] {
+				continue
+			}
+			seen[name] = true
+			services = append(services, ODataService{Name: name, Kind: "Singleton", URL: route.Path})
+		}
+		sort.Slice(services[1:], func(i, j int) bool {
+			return services[i+1].Name < services[j+1].Name
+		})
+		c.JSON(http.StatusOK, ODataServiceDocument{
+			ODataContext: "/redfish/v1/$metadata",
+			Value:        services,
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestMetadataCoversServedTypes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	bmc := newTestBMC(t, defaultConfig())
+	router := newRouter(bmc)
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/redfish/v1/$metadata", nil))
+	if recorder.Code != http.StatusOK || !strings.HasPrefix(recorder.Header().Get("Content-Type"), "application/xml") {
+		t.Fatalf("metadata status = %d, content type = %q", recorder.Code, recorder.Header().Get("Content-Type"))
+	}
+	var document struct {
+		References []struct {
+			Includes []struct {
+				Namespace string `xml:"Namespace,attr"`
+			} `xml:"Include"`
+		} `xml:"Reference"`
+	}
+	if err := xml.Unmarshal(recorder.Body.Bytes(), &document); err != nil {
+		t.Fatalf("decode metadata: %v", err)
+	}
+	namespaces := map[string]bool{}
+	for _, reference := range document.References {
+		for _, include := range reference.Includes {
+			namespaces[include.Namespace] = true
+		}
+	}
+
+	// Crawl every resource reachable from the service root and check that its
+	// type and context resolve against the metadata document.
+	queue := []string{"/redfish/v1/"}
+	visited := map[string]bool{}
+	for len(queue) > 0 {
+		// Members of arrays such as Thermal Fans link to "#/Fans/0" fragments
+		// of their parent resource.
+		path, _, _ := strings.Cut(queue[0], "#")
+		queue = queue[1:]
+		if visited[path] || strings.Contains(path, "/Actions/") {
+			continue
+		}
+		visited[path] = true
+
+		request := httptest.NewRequest(http.MethodGet, path, nil)
+		request.SetBasicAuth(bmc.config.Authentication.Username, bmc.config.Authentication.Password)
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+		if recorder.Code != http.StatusOK {
+			t.Errorf("GET %s status = %d", path, recorder.Code)
+			continue
+		}
+		var resource map[string]any
+		if err := json.Unmarshal(recorder.Body.Bytes(), &resource); err != nil {
+			t.Fatalf("decode %s: %v", path, err)
+		}
+
+		odataType, _ := resource["@odata.type"].(string)
+		namespace := strings.TrimPrefix(odataType[:strings.LastIndex(odataType, ".")], "#")
+		if !namespaces[namespace] {
+			t.Errorf("%s type %s is not in $metadata", path, odataType)
+		}
+		if context, ok := resource["@odata.context"].(string); ok {
+			base, _, _ := strings.Cut(strings.TrimPrefix(context, "/redfish/v1/$metadata#"), ".")
+			if !strings.HasPrefix(context, "/redfish/v1/$metadata#") || !namespaces[base] {
+				t.Errorf("%s context %s does not resolve against $metadata", path, context)
+			}
+		}
+		queue = append(queue, odataLinks(resource)...)
+	}
+	if len(visited) < 10 {
+		t.Fatalf("crawled only %d resources", len(visited))
+	}
+}
+
+func odataLinks(value any) []string {
+	var links []string
+	switch value := value.(type) {
+	case map[string]any:
+		for key, child := range value {
+			if link, ok := child.(string); ok && key == "@odata.id" {
+				links = append(links, link)
+			}
+			if key != "Oem" {
+				links = append(links, odataLinks(child)...)
+			}
+		}
+	case []any:
+		for _, child := range value {
+			links = append(links, odataLinks(child)...)
+		}
+	}
+	return links
+}
+
+func TestODataServiceDocumentMatchesRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	recorder := httptest.NewRecorder()
+	newRouter(newTestBMC(t, defaultConfig())).ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/redfish/v1/odata", nil))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", recorder.Code, http.StatusOK)
+	}
+	var document ODataServiceDocument
+	if err := json.Unmarshal(recorder.Body.Bytes(), &document); err != nil {
+		t.Fatalf("decode service document: %v", err)
+	}
+	urls := map[string]bool{}
+	for _, service := range document.Value {
+		urls[service.URL] = true
+	}
+	for _, url := range []string{"/redfish/v1/", "/redfish/v1/Systems", "/redfish/v1/SessionService", "/redfish/v1/Registries"} {
+		if !urls[url] {
+			t.Errorf("service document %#v is missing %s", document.Value, url)
+		}
+	}
+	if urls["/redfish/v1/odata"] || urls["/redfish/v1/$metadata"] {
+		t.Errorf("service document lists itself: %#v", document.Value)
+	}
+}
@@ -0,0 +1,328 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MockupConfig points the BMC at a static Redfish mockup: a directory tree
+// with an index.json per resource, as DMTF and vendors publish them. The
+// tree may start at the service root or contain redfish/v1 itself.
+type MockupConfig struct {
+	Directory string `json:"directory"`
+}
+
+func (c MockupConfig) validate() error {
+	if c.Directory == "" {
+		return nil
+	}
+	_, err := mockupRoot(c.Directory)
+	return err
+}
+
+// mockupRoot returns the directory of a mockup that holds the service root.
+func mockupRoot(dir string) (string, error) {
+	if _, err := os.Stat(filepath.Join(dir, "redfish", "v1", "index.json")); err == nil {
+		return filepath.Join(dir, "redfish", "v1"), nil
+	}
+	if _, err := os.Stat(filepath.Join(dir, "index.json")); err != nil {
+		return "", fmt.Errorf("directory %s has no index.json for the service root", dir)
+	}
+	return dir, nil
+}
+
+// loadMockup reads every index.json of a mockup, keyed by resource URI.
+func loadMockup(dir string) (map[string][]byte, error) {
+	root, err := mockupRoot(dir)
+	if err != nil {
+		return nil, err
+	}
+	resources := map[string][]byte{}
+	err = filepath.WalkDir(root, func(file string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() || entry.Name() != "index.json" {
+			return err
+		}
+		contents, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		var resource map[string]any
+		if err := json.Unmarshal(contents, &resource); err != nil {
+			return fmt.Errorf("decode %s: %w", file, err)
+		}
+		relative, err := filepath.Rel(root, filepath.Dir(file))
+		if err != nil {
+			return err
+		}
+		resources[mockupURI(path.Join("/redfish/v1", filepath.ToSlash(relative)))] = contents
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resources, nil
+}
+
+// mockupURI normalizes a request path to the URI a mockup resource is
+// stored under.
+func mockupURI(requestPath string) string {
+	return strings.TrimSuffix(path.Clean("/"+requestPath), "/")
+}
+
+// mockupStore is the in-memory copy of a mockup that PATCH and POST change.
+type mockupStore struct {
+	sync.Mutex
+	original  map[string][]byte
+	resources map[string]map[string]any
+}
+
+func newMockupStore(config MockupConfig) (*mockupStore, error) {
+	s := &mockupStore{}
+	if config.Directory == "" {
+		return s, nil
+	}
+	original, err := loadMockup(config.Directory)
+	if err != nil {
+		return nil, err
+	}
+	s.original = original
+	s.reset()
+	return s, nil
+}
+
+// reset drops every change made since the mockup was loaded.
+func (s *mockupStore) reset() {
+	s.Lock()
+	defer s.Unlock()
+	s.resources = make(map[string]map[string]any, len(s.original))
+	for uri, contents := range s.original {
+		var resource map[string]any
+		json.Unmarshal(contents, &resource)
+		s.resources[uri] = resource
+	}
+}
+
+func (s *mockupStore) has(uri string) bool {
+	s.Lock()
+	defer s.Unlock()
+	_, ok := s.resources[uri]
+	return ok
+}
+
+// actionTarget reports whether uri is an action of a mockup resource.
+func (s *mockupStore) actionTarget(uri string) bool {
+	resourceURI, _, ok := strings.Cut(uri, "/Actions/")
+	return ok && s.has(resourceURI)
+}
+
+// get returns a copy of the resource at uri.
+func (s *mockupStore) get(uri string) ([]byte, bool) {
+	s.Lock()
+	defer s.Unlock()
+	resource, ok := s.resources[uri]
+	if !ok {
+		return nil, false
+	}
+	contents, _ := json.Marshal(resource)
+	return contents, true
+}
+
+// patch merges properties into the resource at uri. Nested objects are
+// merged property by property; OData annotations cannot be changed.
+func (s *mockupStore) patch(uri string, properties map[string]any) ([]byte, bool) {
+	s.Lock()
+	defer s.Unlock()
+	resource, ok := s.resources[uri]
+	if !ok {
+		return nil, false
+	}
+	mergeProperties(resource, properties)
+	contents, _ := json.Marshal(resource)
+	return contents, true
+}
+
+func mergeProperties(resource, properties map[string]any) {
+	for name, value := range properties {
+		if strings.HasPrefix(name, "@odata.") {
+			continue
+		}
+		patch, isObject := value.(map[string]any)
+		current, wasObject := resource[name].(map[string]any)
+		if isObject && wasObject {
+			mergeProperties(current, patch)
+			continue
+		}
+		resource[name] = value
+	}
+}
+
+var errNotMockupCollection = errors.New("not a collection")
+
+// create adds a member to the collection at uri and returns its URI and
+// contents. The member gets the lowest numeric Id the collection does not
+// use yet.
+func (s *mockupStore) create(uri string, properties map[string]any) (string, []byte, error) {
+	s.Lock()
+	defer s.Unlock()
+	collection, ok := s.resources[uri]
+	if !ok {
+		return "", nil, errNotMockupCollection
+	}
+	members, ok := collection["Members"].([]any)
+	if !ok {
+		return "", nil, errNotMockupCollection
+	}
+	id := 1
+	for s.resources[uri+"/"+strconv.Itoa(id)] != nil {
+		id++
+	}
+	memberURI := uri + "/" + strconv.Itoa(id)
+	member := map[string]any{}
+	mergeProperties(member, properties)
+	member["@odata.id"] = memberURI
+	member["Id"] = strconv.Itoa(id)
+	if _, ok := member["Name"]; !ok {
+		member["Name"] = strconv.Itoa(id)
+	}
+	s.resources[memberURI] = member
+	collection["Members"] = append(members, map[string]any{"@odata.id": memberURI})
+	collection["Members@odata.count"] = len(members) + 1
+	contents, _ := json.Marshal(member)
+	return memberURI, contents, nil
+}
+
+// serveMockup is the middleware that serves the mockup. A request reaches
+// the mockup when no built-in route matches its path, or when the built-in
+// handler does not know the resource and answers 404. Mockup resources need
+// the same authentication as the built-in ones, except for the service root,
+// and changing them needs the ConfigureComponents privilege.
+func (b *mockBMC) serveMockup() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if b.mockup.original == nil || strings.HasPrefix(c.Request.URL.Path, "/mock/") {
+			return
+		}
+		uri := mockupURI(c.Request.URL.Path)
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodPatch:
+			if !b.mockup.has(uri) {
+				return
+			}
+		case http.MethodPost:
+			if !b.mockup.has(uri) && !b.mockup.actionTarget(uri) {
+				return
+			}
+		default:
+			return
+		}
+		if c.FullPath() != "" {
+			// Let the built-in handler answer and fall back to the mockup
+			// only if it does not know the resource.
+			header := c.Writer.Header().Clone()
+			writer := &notFoundWriter{ResponseWriter: c.Writer}
+			c.Writer = writer
+			c.Next()
+			c.Writer = writer.ResponseWriter
+			if !writer.notFound {
+				return
+			}
+			maps.DeleteFunc(c.Writer.Header(), func(string, []string) bool { return true })
+			maps.Copy(c.Writer.Header(), header)
+			c.Errors = c.Errors[:0]
+		}
+		// The built-in handler has aborted the context when it answered 404,
+		// so whether a check failed shows in the response instead.
+		c.Header("OData-Version", "4.0")
+		if uri != "/redfish/v1" {
+			if b.requireAuth()(c); c.Writer.Written() {
+				return
+			}
+			if c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead {
+				if requirePrivilege(privilegeConfigureComponents)(c); c.Writer.Written() {
+					return
+				}
+			}
+		}
+		b.handleMockupRequest(c, uri)
+		c.Abort()
+	}
+}
+
+func (b *mockBMC) handleMockupRequest(c *gin.Context, uri string) {
+	switch c.Request.Method {
+	case http.MethodGet, http.MethodHead:
+		contents, _ := b.mockup.get(uri)
+		c.Data(http.StatusOK, "application/json", contents)
+	case http.MethodPatch:
+		var properties map[string]any
+		if !bindRedfishJSON(c, &properties) {
+			return
+		}
+		contents, _ := b.mockup.patch(uri, properties)
+		c.Data(http.StatusOK, "application/json", contents)
+	case http.MethodPost:
+		if b.mockup.actionTarget(uri) {
+			c.Status(http.StatusNoContent)
+			return
+		}
+		var properties map[string]any
+		if !bindRedfishJSON(c, &properties) {
+			return
+		}
+		memberURI, contents, err := b.mockup.create(uri, properties)
+		if err != nil {
+			c.Header("Allow", strings.Join([]string{http.MethodGet, http.MethodPatch}, ", "))
+			redfishError(c, http.StatusMethodNotAllowed, baseMessage("ActionNotSupported", http.MethodPost))
+			return
+		}
+		c.Header("Location", memberURI)
+		c.Data(http.StatusCreated, "application/json", contents)
+	}
+}
+
+// notFoundWriter passes a response through unless its status is 404 Not
+// Found, in which case it is dropped so that the mockup can answer instead.
+type notFoundWriter struct {
+	gin.ResponseWriter
+	notFound bool
+}
+
+func (w *notFoundWriter) WriteHeader(status int) {
+	if status == http.StatusNotFound {
+		w.notFound = true
+		return
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *notFoundWriter) WriteHeaderNow() {
+	if !w.notFound {
+		w.ResponseWriter.WriteHeaderNow()
+	}
+}
+
+func (w *notFoundWriter) Write(data []byte) (int, error) {
+	if w.notFound {
+		return len(data), nil
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *notFoundWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *notFoundWriter) Written() bool {
+	return w.notFound || w.ResponseWriter.Written()
+}
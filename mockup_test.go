@@ -0,0 +1,132 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func writeMockupResource(t *testing.T, dir, uri string, resource map[string]any) {
+	t.Helper()
+	resource["@odata.id"] = uri
+	contents, err := json.Marshal(resource)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resourceDir := filepath.Join(dir, filepath.FromSlash(uri))
+	if err := os.MkdirAll(resourceDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(resourceDir, "index.json"), contents, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStaticMockup(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	dir := t.TempDir()
+	writeMockupResource(t, dir, "/redfish/v1", map[string]any{"Id": "RootService"})
+	writeMockupResource(t, dir, "/redfish/v1/Systems/437XR1138R2", map[string]any{
+		"Id":         "437XR1138R2",
+		"HostName":   "web483",
+		"Boot":       map[string]any{"BootSourceOverrideTarget": "Pxe", "BootSourceOverrideEnabled": "Once"},
+		"Processors": map[string]any{"@odata.id": "/redfish/v1/Systems/437XR1138R2/Processors"},
+	})
+	writeMockupResource(t, dir, "/redfish/v1/Systems/437XR1138R2/Processors", map[string]any{
+		"Members":             []any{map[string]any{"@odata.id": "/redfish/v1/Systems/437XR1138R2/Processors/CPU1"}},
+		"Members@odata.count": 1,
+	})
+	writeMockupResource(t, dir, "/redfish/v1/Systems/437XR1138R2/Processors/CPU1", map[string]any{"Id": "CPU1", "TotalCores": 8})
+
+	config := defaultConfig()
+	config.Mockup = MockupConfig{Directory: dir}
+	if err := config.Mockup.validate(); err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+	bmc := newTestBMC(t, config)
+	router := newRouter(bmc)
+	admin, password := bmc.config.Authentication.Username, bmc.config.Authentication.Password
+	resource := func(body []byte) map[string]any {
+		t.Helper()
+		var decoded map[string]any
+		if err := json.Unmarshal(body, &decoded); err != nil {
+			t.Fatalf("decode %s: %v", body, err)
+		}
+		return decoded
+	}
+
+	recorder := accountRequest(router, http.MethodGet, "/redfish/v1/Systems/437XR1138R2/Processors/CPU1", "", admin, password)
+	if cpu := resource(recorder.Body.Bytes()); recorder.Code != http.StatusOK || cpu["TotalCores"] != 8.0 {
+		t.Fatalf("unrouted resource = %d %v", recorder.Code, cpu)
+	}
+	recorder = accountRequest(router, http.MethodGet, "/redfish/v1/Systems/437XR1138R2", "", admin, password)
+	if system := resource(recorder.Body.Bytes()); recorder.Code != http.StatusOK || system["HostName"] != "web483" ||
+		recorder.Header().Get("OData-Version") != "4.0" {
+		t.Fatalf("fallback from built-in handler = %d %v", recorder.Code, system)
+	}
+	if recorder := accountRequest(router, http.MethodGet, "/redfish/v1/Systems/1", "", admin, password); recorder.Code != http.StatusOK ||
+		resource(recorder.Body.Bytes())["Id"] != "1" {
+		t.Fatalf("built-in system = %d %s", recorder.Code, recorder.Body.String())
+	}
+	if recorder := accountRequest(router, http.MethodGet, "/redfish/v1/Systems/437XR1138R2", "", admin, "wrong"); recorder.Code != http.StatusUnauthorized {
+		t.Fatalf("unauthenticated status = %d", recorder.Code)
+	}
+	if recorder := accountRequest(router, http.MethodGet, "/redfish/v1/Chassis/Missing", "", admin, password); recorder.Code != http.StatusNotFound {
+		t.Fatalf("missing resource status = %d", recorder.Code)
+	}
+
+	recorder = accountRequest(router, http.MethodPatch, "/redfish/v1/Systems/437XR1138R2", `{"@odata.id":"/elsewhere","Boot":{"BootSourceOverrideTarget":"Hdd"}}`, admin, password)
+	system := resource(recorder.Body.Bytes())
+	boot, _ := system["Boot"].(map[string]any)
+	if recorder.Code != http.StatusOK || system["@odata.id"] != "/redfish/v1/Systems/437XR1138R2" ||
+		boot["BootSourceOverrideTarget"] != "Hdd" || boot["BootSourceOverrideEnabled"] != "Once" {
+		t.Fatalf("PATCH = %d %v", recorder.Code, system)
+	}
+	if recorder := accountRequest(router, http.MethodPatch, "/redfish/v1/Systems/437XR1138R2", `{"Boot":`, admin, password); recorder.Code != http.StatusBadRequest {
+		t.Fatalf("malformed PATCH status = %d", recorder.Code)
+	}
+
+	recorder = accountRequest(router, http.MethodPost, "/redfish/v1/Systems/437XR1138R2/Processors", `{"TotalCores":16}`, admin, password)
+	if recorder.Code != http.StatusCreated || recorder.Header().Get("Location") != "/redfish/v1/Systems/437XR1138R2/Processors/1" {
+		t.Fatalf("POST = %d %s %s", recorder.Code, recorder.Header().Get("Location"), recorder.Body.String())
+	}
+	recorder = accountRequest(router, http.MethodGet, "/redfish/v1/Systems/437XR1138R2/Processors", "", admin, password)
+	if collection := resource(recorder.Body.Bytes()); collection["Members@odata.count"] != 2.0 {
+		t.Fatalf("collection after POST = %v", collection)
+	}
+	if recorder := accountRequest(router, http.MethodPost, "/redfish/v1/Systems/437XR1138R2/Processors/CPU1", `{}`, admin, password); recorder.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("POST to a member status = %d", recorder.Code)
+	}
+	if recorder := accountRequest(router, http.MethodPost, "/redfish/v1/Systems/437XR1138R2/Processors/CPU1/Actions/Processor.Reset", `{}`, admin, password); recorder.Code != http.StatusNoContent {
+		t.Fatalf("action status = %d", recorder.Code)
+	}
+
+	if recorder := accountRequest(router, http.MethodPost, "/mock/admin/reset", "", admin, password); recorder.Code != http.StatusNoContent {
+		t.Fatalf("reset status = %d", recorder.Code)
+	}
+	recorder = accountRequest(router, http.MethodGet, "/redfish/v1/Systems/437XR1138R2", "", admin, password)
+	boot, _ = resource(recorder.Body.Bytes())["Boot"].(map[string]any)
+	if boot["BootSourceOverrideTarget"] != "Pxe" {
+		t.Fatalf("boot after reset = %v", boot)
+	}
+	if recorder := accountRequest(router, http.MethodGet, "/redfish/v1/Systems/437XR1138R2/Processors/1", "", admin, password); recorder.Code != http.StatusNotFound {
+		t.Fatalf("created member after reset status = %d", recorder.Code)
+	}
+
+	if err := (MockupConfig{Directory: t.TempDir()}).validate(); err == nil {
+		t.Fatal("validate accepted a directory without a service root")
+	}
+	broken := t.TempDir()
+	if err := os.WriteFile(filepath.Join(broken, "index.json"), []byte(`{"Id":`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	config.Mockup = MockupConfig{Directory: broken}
+	if _, err := newMockBMC(config); err == nil || !strings.Contains(err.Error(), "mockup") {
+		t.Fatalf("newMockBMC with a broken mockup = %v", err)
+	}
+}
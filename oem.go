@@ -32,11 +32,3 @@ func oemBehaviorFor(name string) (oemBehavior, error) {
 		return nil, fmt.Errorf("unsupported oem %q (supported: mock, supermicro, dell, cisco)", name)
 	}
 }
-
-func activeOEM() oemBehavior {
-	behavior, err := oemBehaviorFor(config.OEM)
-	if err != nil {
-		panic(err)
-	}
-	return behavior
-}
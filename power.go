@@ -0,0 +1,89 @@
+package main
+
+import (
+	"errors"
+	"time"
+)
+
+var errPowerStateConflict = errors.New("reset type is not allowed in the current power state")
+
+type powerTransition struct {
+	state string
+	at    time.Time
+}
+
+type powerStep struct {
+	state    string
+	duration time.Duration
+}
+
+func (s *mockServerState) advancePower(now time.Time) {
+	for len(s.powerTransitions) > 0 && !now.Before(s.powerTransitions[0].at) {
+		s.powerState = s.powerTransitions[0].state
+		s.powerTransitions = s.powerTransitions[1:]
+	}
+}
+
+// startPowerSequence replaces any pending transitions with steps. Each step's
+// state is entered once the durations of all earlier steps have elapsed.
+func (s *mockServerState) startPowerSequence(now time.Time, steps ...powerStep) {
+	s.powerTransitions = s.powerTransitions[:0]
+	s.powerEvents = nil
+	at := now
+	for _, step := range steps {
+		s.powerTransitions = append(s.powerTransitions, powerTransition{state: step.state, at: at})
+		at = at.Add(step.duration)
+	}
+	s.advancePower(now)
+}
+
+// resetPower applies a ComputerSystem.Reset to the power state machine. The
+// caller must hold the state lock.
+func (s *mockServerState) resetPower(resetType string, now time.Time, poweringOn, poweringOff time.Duration) error {
+	s.advancePower(now)
+	switch resetType {
+	case "On":
+		if s.powerState != "Off" {
+			return errPowerStateConflict
+		}
+		s.startPowerSequence(now, powerStep{"PoweringOn", poweringOn}, powerStep{"On", 0})
+	case "ForceOff":
+		if s.powerState == "Off" {
+			return errPowerStateConflict
+		}
+		s.startPowerSequence(now, powerStep{"Off", 0})
+	case "GracefulShutdown":
+		if s.powerState != "On" {
+			return errPowerStateConflict
+		}
+		s.startPowerSequence(now, powerStep{"PoweringOff", poweringOff}, powerStep{"Off", 0})
+	case "GracefulRestart":
+		if s.powerState != "On" {
+			return errPowerStateConflict
+		}
+		s.startPowerSequence(now, powerStep{"PoweringOff", poweringOff}, powerStep{"PoweringOn", poweringOn}, powerStep{"On", 0})
+	case "ForceRestart":
+		if s.powerState != "On" {
+			return errPowerStateConflict
+		}
+		s.startPowerSequence(now, powerStep{"Off", poweringOff}, powerStep{"PoweringOn", poweringOn}, powerStep{"On", 0})
+	case "PowerCycle":
+		if s.powerState != "On" {
+			return errPowerStateConflict
+		}
+		s.startPowerSequence(now, powerStep{"Off", poweringOff}, powerStep{"PoweringOn", poweringOn}, powerStep{"On", 0})
+	}
+	return nil
+}
+
+func powerConflictMessage(resetType, powerState string) Message {
+	switch {
+	case powerState == "PoweringOn" || powerState == "PoweringOff":
+		return baseMessage("ResourceInUse")
+	case powerState == "On" && resetType == "On",
+		powerState == "Off" && (resetType == "ForceOff" || resetType == "GracefulShutdown"):
+		return baseMessage("NoOperation")
+	default:
+		return baseMessage("ResourceInStandby")
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestResetPowerTransitions(t *testing.T) {
+	const delay = 10 * time.Second
+	tests := []struct {
+		resetType string
+		initial   string
+		states    []string
+	}{
+		{resetType: "On", initial: "Off", states: []string{"PoweringOn", "On", "On"}},
+		{resetType: "ForceOff", initial: "On", states: []string{"Off", "Off", "Off"}},
+		{resetType: "GracefulShutdown", initial: "On", states: []string{"PoweringOff", "Off", "Off"}},
+		{resetType: "GracefulRestart", initial: "On", states: []string{"PoweringOff", "PoweringOn", "On"}},
+		{resetType: "ForceRestart", initial: "On", states: []string{"Off", "PoweringOn", "On"}},
+		{resetType: "PowerCycle", initial: "On", states: []string{"Off", "PoweringOn", "On"}},
+	}
+	for _, test := range tests {
+		t.Run(test.resetType, func(t *testing.T) {
+			start := time.Now()
+			state := mockServerState{powerState: test.initial}
+			if err := state.resetPower(test.resetType, start, delay, delay); err != nil {
+				t.Fatalf("resetPower() error = %v", err)
+			}
+			for i, want := range test.states {
+				state.advancePower(start.Add(time.Duration(i) * delay))
+				if state.powerState != want {
+					t.Fatalf("PowerState after %v = %s, want %s", time.Duration(i)*delay, state.powerState, want)
+				}
+			}
+		})
+	}
+}
+
+func TestResetPowerRejectsInvalidTransitions(t *testing.T) {
+	tests := []struct {
+		resetType string
+		initial   string
+	}{
+		{resetType: "On", initial: "On"},
+		{resetType: "On", initial: "PoweringOff"},
+		{resetType: "ForceOff", initial: "Off"},
+		{resetType: "GracefulShutdown", initial: "Off"},
+		{resetType: "GracefulRestart", initial: "PoweringOn"},
+		{resetType: "ForceRestart", initial: "Off"},
+		{resetType: "PowerCycle", initial: "Off"},
+	}
+	for _, test := range tests {
+		state := mockServerState{powerState: test.initial}
+		if err := state.resetPower(test.resetType, time.Now(), 0, 0); !errors.Is(err, errPowerStateConflict) {
+			t.Fatalf("resetPower(%s) from %s error = %v, want errPowerStateConflict", test.resetType, test.initial, err)
+		}
+		if state.powerState != test.initial {
+			t.Fatalf("rejected %s changed PowerState to %s", test.resetType, state.powerState)
+		}
+	}
+}
+
+func TestResetSystemConflictStatus(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	bmc := newTestBMC(t, defaultConfig())
+
+	request := httptest.NewRequest(http.MethodPost, "/redfish/v1/Systems/1/Actions/ComputerSystem.Reset",
+		strings.NewReader(`{"ResetType":"On"}`))
+	request.SetBasicAuth(bmc.config.Authentication.Username, bmc.config.Authentication.Password)
+	recorder := httptest.NewRecorder()
+	newRouter(bmc).ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusConflict {
+		t.Fatalf("On while powered on status = %d, want %d", recorder.Code, http.StatusConflict)
+	}
+}
@@ -0,0 +1,418 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Recording modes.
+const (
+	recordingRecord = "record"
+	recordingReplay = "replay"
+)
+
+// recordedHostPlaceholder stands for the upstream BMC's host in recordings.
+// Replay puts the host the client connected to in its place.
+const recordedHostPlaceholder = "{{host}}"
+
+// RecordingConfig captures traffic from a real BMC or serves it back. In
+// record mode every Redfish request is proxied to Upstream and the exchange
+// is stored in Directory; in replay mode requests that match a stored
+// exchange by method and path get its response, and everything else reaches
+// the built-in handlers. In stored bodies, the password the client used and
+// JSON values that are its username become the mock's credentials, then
+// Rewrites replace strings and the upstream host becomes a placeholder.
+type RecordingConfig struct {
+	Mode               string             `json:"mode"`
+	Directory          string             `json:"directory"`
+	Upstream           string             `json:"upstream"`
+	InsecureSkipVerify bool               `json:"insecure_skip_verify"`
+	Rewrites           []RecordingRewrite `json:"rewrites"`
+}
+
+type RecordingRewrite struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// Recording is one stored request/response pair.
+type Recording struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+type RecordedRequest struct {
+	Method string          `json:"method"`
+	Path   string          `json:"path"`
+	Query  string          `json:"query,omitempty"`
+	Header http.Header     `json:"header,omitempty"`
+	Body   json.RawMessage `json:"body,omitempty"`
+	Text   string          `json:"text,omitempty"`
+}
+
+// RecordedResponse keeps a JSON body as JSON so that recordings can be read
+// and edited; any other body is kept as Text.
+type RecordedResponse struct {
+	Status int             `json:"status"`
+	Header http.Header     `json:"header,omitempty"`
+	Body   json.RawMessage `json:"body,omitempty"`
+	Text   string          `json:"text,omitempty"`
+}
+
+// publicReplayPaths are replayed without authentication, like the built-in
+// handlers for them.
+var publicReplayPaths = []string{"/redfish/v1", "/redfish/v1/$metadata", "/redfish/v1/odata"}
+
+// unrecordedHeaders are not stored: they carry credentials or describe the
+// connection rather than the resource.
+var unrecordedHeaders = []string{
+	"Authorization", "Connection", "Content-Encoding", "Content-Length", "Cookie", "Date",
+	"Keep-Alive", "Set-Cookie", "Transfer-Encoding", "X-Auth-Token",
+}
+
+func (c RecordingConfig) validate() error {
+	switch c.Mode {
+	case "":
+		return nil
+	case recordingRecord:
+		upstream, err := url.Parse(c.Upstream)
+		if err != nil || (upstream.Scheme != "http" && upstream.Scheme != "https") || upstream.Host == "" {
+			return errors.New("upstream must be an HTTP or HTTPS URL in record mode")
+		}
+	case recordingReplay:
+	default:
+		return errors.New(`mode must be "record" or "replay"`)
+	}
+	if c.Directory == "" {
+		return errors.New("directory is required")
+	}
+	for i, rewrite := range c.Rewrites {
+		if rewrite.From == "" {
+			return fmt.Errorf("rewrites[%d].from is required", i)
+		}
+	}
+	if c.Mode == recordingReplay {
+		if _, err := loadRecordings(c.Directory); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// recordingKey identifies the exchanges replay treats as the same: the
+// method and the path without a trailing slash.
+func recordingKey(method, requestPath string) string {
+	if requestPath != "/" {
+		requestPath = strings.TrimSuffix(requestPath, "/")
+	}
+	return method + " " + requestPath
+}
+
+// recordingFile names the file an exchange is stored in. Later exchanges
+// with the same key replace earlier ones.
+func recordingFile(method, requestPath string) string {
+	sum := sha256.Sum256([]byte(recordingKey(method, requestPath)))
+	name := strings.Trim(strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '.' {
+			return r
+		}
+		return '-'
+	}, requestPath), "-.")
+	return method + "-" + name + "-" + hex.EncodeToString(sum[:4]) + ".json"
+}
+
+// loadRecordings reads every recording in dir, keyed by recordingKey.
+func loadRecordings(dir string) (map[string]Recording, error) {
+	if _, err := os.Stat(dir); err != nil {
+		return nil, err
+	}
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	recordings := make(map[string]Recording, len(files))
+	for _, file := range files {
+		contents, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		var recording Recording
+		if err := json.Unmarshal(contents, &recording); err != nil {
+			return nil, fmt.Errorf("decode recording %s: %w", file, err)
+		}
+		if recording.Request.Method == "" || recording.Request.Path == "" || recording.Response.Status == 0 {
+			return nil, fmt.Errorf("recording %s needs request.method, request.path, and response.status", file)
+		}
+		recordings[recordingKey(recording.Request.Method, recording.Request.Path)] = recording
+	}
+	return recordings, nil
+}
+
+// recorder proxies requests to a real BMC and stores or replays exchanges.
+type recorder struct {
+	config      RecordingConfig
+	credentials AuthenticationConfig
+	proxy       *httputil.ReverseProxy
+	upstream    *url.URL
+	recordings  map[string]Recording
+	writeMu     sync.Mutex
+}
+
+func newRecorder(config RecordingConfig, credentials AuthenticationConfig) (*recorder, error) {
+	r := &recorder{config: config, credentials: credentials}
+	switch config.Mode {
+	case recordingRecord:
+		upstream, err := url.Parse(config.Upstream)
+		if err != nil {
+			return nil, err
+		}
+		r.upstream = upstream
+		r.proxy = &httputil.ReverseProxy{
+			Rewrite: func(proxied *httputil.ProxyRequest) {
+				proxied.SetURL(upstream)
+				// Let the transport negotiate compression so that stored
+				// bodies are plain.
+				proxied.Out.Header.Del("Accept-Encoding")
+			},
+			Transport: &http.Transport{
+				Proxy:           http.ProxyFromEnvironment,
+				TLSClientConfig: &tls.Config{InsecureSkipVerify: config.InsecureSkipVerify},
+			},
+			ModifyResponse: r.store,
+		}
+	case recordingReplay:
+		recordings, err := loadRecordings(config.Directory)
+		if err != nil {
+			return nil, err
+		}
+		r.recordings = recordings
+	}
+	return r, nil
+}
+
+// recordOrReplay is the middleware for record and replay modes. Session
+// creation and the /mock control API are never replayed, so that clients
+// log in to the mock itself. Replayed responses need the same
+// authentication as the built-in ones, except for the service root and the
+// OData documents, which are public.
+func (b *mockBMC) recordOrReplay() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestPath := c.Request.URL.Path
+		if strings.HasPrefix(requestPath, "/mock/") {
+			return
+		}
+		switch b.recorder.config.Mode {
+		case recordingRecord:
+			// Keep the request body for the recording; the proxy consumes
+			// the original.
+			body, err := io.ReadAll(c.Request.Body)
+			if err != nil {
+				c.AbortWithStatus(http.StatusBadRequest)
+				return
+			}
+			c.Request.Body = io.NopCloser(bytes.NewReader(body))
+			c.Request.GetBody = func() (io.ReadCloser, error) { return io.NopCloser(bytes.NewReader(body)), nil }
+			b.recorder.proxy.ServeHTTP(c.Writer, c.Request)
+			c.Abort()
+		case recordingReplay:
+			recording, ok := b.recorder.recordings[recordingKey(c.Request.Method, requestPath)]
+			if !ok || strings.HasPrefix(requestPath, "/redfish/v1/SessionService/Sessions") {
+				return
+			}
+			if !slices.Contains(publicReplayPaths, strings.TrimSuffix(requestPath, "/")) {
+				b.requireAuth()(c)
+				if c.IsAborted() {
+					return
+				}
+			}
+			b.recorder.replay(c, recording)
+		}
+	}
+}
+
+func (r *recorder) replay(c *gin.Context, recording Recording) {
+	for name, values := range recording.Response.Header {
+		for _, value := range values {
+			c.Writer.Header().Add(name, strings.ReplaceAll(value, recordedHostPlaceholder, c.Request.Host))
+		}
+	}
+	body := []byte(recording.Response.Text)
+	if len(recording.Response.Body) > 0 {
+		body = recording.Response.Body
+	}
+	c.Status(recording.Response.Status)
+	c.Writer.Write(bytes.ReplaceAll(body, []byte(recordedHostPlaceholder), []byte(c.Request.Host)))
+	c.Abort()
+}
+
+// store saves the exchange behind an upstream response and hands the
+// response on unchanged. Event streams are passed through without being
+// stored.
+func (r *recorder) store(response *http.Response) error {
+	if strings.HasPrefix(response.Header.Get("Content-Type"), "text/event-stream") {
+		return nil
+	}
+	responseBody, err := io.ReadAll(response.Body)
+	response.Body.Close()
+	if err != nil {
+		return err
+	}
+	response.Body = io.NopCloser(bytes.NewReader(responseBody))
+
+	request := response.Request
+	var requestBody []byte
+	if request.GetBody != nil {
+		if body, err := request.GetBody(); err == nil {
+			requestBody, _ = io.ReadAll(body)
+			body.Close()
+		}
+	}
+	rewrite := r.rewriter()
+	recording := Recording{
+		Request: RecordedRequest{
+			Method: request.Method,
+			Path:   request.URL.Path,
+			Query:  rewrite(request.URL.RawQuery),
+			Header: rewriteHeader(request.Header, rewrite),
+		},
+		Response: RecordedResponse{
+			Status: response.StatusCode,
+			Header: rewriteHeader(response.Header, rewrite),
+		},
+	}
+	recording.Request.Body, recording.Request.Text = recordedBody(rewrite(r.replaceCredentials(request, requestBody, string(requestBody))))
+	recording.Response.Body, recording.Response.Text = recordedBody(rewrite(r.replaceCredentials(request, requestBody, string(responseBody))))
+	if err := r.write(recording); err != nil {
+		log.Printf("record %s %s: %v", request.Method, request.URL.Path, err)
+	}
+	return nil
+}
+
+// rewriter returns the replacements applied to a stored exchange: the
+// configured rewrites and the upstream host. The client's credentials are
+// replaced separately by replaceCredentials.
+func (r *recorder) rewriter() func(string) string {
+	var pairs []string
+	for _, rewrite := range r.config.Rewrites {
+		pairs = append(pairs, rewrite.From, rewrite.To)
+	}
+	pairs = append(pairs, r.upstream.Host, recordedHostPlaceholder, r.upstream.Hostname(), recordedHostPlaceholder)
+	return strings.NewReplacer(pairs...).Replace
+}
+
+// jsonString matches a string literal in a JSON document.
+var jsonString = regexp.MustCompile(`"(?:[^"\\]|\\.)*"`)
+
+// replaceCredentials replaces the credentials the client sent, as Basic auth
+// or in a session login, with the mock's. A password is replaced wherever it
+// appears in body. A username is only replaced in JSON string values that are
+// exactly the username, so that a value such as a path that merely contains
+// it survives.
+func (r *recorder) replaceCredentials(request *http.Request, requestBody []byte, body string) string {
+	var login struct {
+		UserName string `json:"UserName"`
+		Password string `json:"Password"`
+	}
+	json.Unmarshal(requestBody, &login)
+	username, password, _ := request.BasicAuth()
+	var passwords []string
+	usernames := map[string]string{}
+	for _, credentials := range [][2]string{{username, password}, {login.UserName, login.Password}} {
+		if credentials[1] != "" {
+			passwords = append(passwords, credentials[1], r.credentials.Password)
+		}
+		if credentials[0] != "" {
+			usernames[credentials[0]] = r.credentials.Username
+		}
+	}
+	if len(passwords) > 0 {
+		body = strings.NewReplacer(passwords...).Replace(body)
+	}
+	if len(usernames) == 0 || !json.Valid([]byte(body)) {
+		return body
+	}
+	var replaced strings.Builder
+	last := 0
+	for _, match := range jsonString.FindAllStringIndex(body, -1) {
+		if strings.HasPrefix(strings.TrimLeft(body[match[1]:], " \t\r\n"), ":") {
+			continue
+		}
+		var value string
+		if json.Unmarshal([]byte(body[match[0]:match[1]]), &value) != nil {
+			continue
+		}
+		replacement, ok := usernames[value]
+		if !ok {
+			continue
+		}
+		quoted, _ := json.Marshal(replacement)
+		replaced.WriteString(body[last:match[0]])
+		replaced.Write(quoted)
+		last = match[1]
+	}
+	replaced.WriteString(body[last:])
+	return replaced.String()
+}
+
+func rewriteHeader(header http.Header, rewrite func(string) string) http.Header {
+	stored := http.Header{}
+	for name, values := range header {
+		if slices.Contains(unrecordedHeaders, name) {
+			continue
+		}
+		for _, value := range values {
+			stored.Add(name, rewrite(value))
+		}
+	}
+	if len(stored) == 0 {
+		return nil
+	}
+	return stored
+}
+
+// recordedBody keeps a JSON body as JSON and anything else as text.
+func recordedBody(body string) (json.RawMessage, string) {
+	if body == "" {
+		return nil, ""
+	}
+	if json.Valid([]byte(body)) {
+		return json.RawMessage(body), ""
+	}
+	return nil, body
+}
+
+func (r *recorder) write(recording Recording) error {
+	contents, err := json.MarshalIndent(recording, "", "  ")
+	if err != nil {
+		return err
+	}
+	r.writeMu.Lock()
+	defer r.writeMu.Unlock()
+	if err := os.MkdirAll(r.config.Directory, 0o755); err != nil {
+		return err
+	}
+	file := filepath.Join(r.config.Directory, recordingFile(recording.Request.Method, recording.Request.Path))
+	temporary := file + ".tmp"
+	if err := os.WriteFile(temporary, append(contents, '\n'), 0o600); err != nil {
+		return err
+	}
+	return os.Rename(temporary, file)
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRecordAndReplayBMCTraffic(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var upstreamHost string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		username, password, _ := r.BasicAuth()
+		if username != "root" || password != "calvin" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Location", "http://"+upstreamHost+"/redfish/v1/Systems/System.Embedded.1")
+		w.Header().Set("X-Auth-Token", "upstream-token")
+		if r.Method == http.MethodPatch {
+			w.Write(body)
+			return
+		}
+		if r.URL.Path == "/redfish/v1/Oem/Error" {
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte("<p>root:calvin is not allowed here</p>"))
+			return
+		}
+		w.Write([]byte(`{"@odata.id":"` + r.URL.Path + `","HostName":"idrac-rack1.example.com","Url":"https://` + upstreamHost + `/console","UserName":"root","Path":"/home/root","Description":"set by root with calvin"}`))
+	}))
+	defer upstream.Close()
+	parsed, _ := url.Parse(upstream.URL)
+	upstreamHost = parsed.Host
+
+	dir := t.TempDir()
+	config := defaultConfig()
+	config.Recording = RecordingConfig{
+		Mode:      recordingRecord,
+		Directory: dir,
+		Upstream:  upstream.URL,
+		Rewrites:  []RecordingRewrite{{From: "idrac-rack1.example.com", To: "mock-bmc"}},
+	}
+	if err := config.Recording.validate(); err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+	proxy := httptest.NewServer(newRouter(newTestBMC(t, config)))
+	defer proxy.Close()
+	proxied := func(method, path, body string) (int, string) {
+		t.Helper()
+		request, _ := http.NewRequest(method, proxy.URL+path, strings.NewReader(body))
+		request.SetBasicAuth("root", "calvin")
+		response, err := proxy.Client().Do(request)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer response.Body.Close()
+		contents, _ := io.ReadAll(response.Body)
+		return response.StatusCode, string(contents)
+	}
+	if status, body := proxied(http.MethodGet, "/redfish/v1/Systems/System.Embedded.1", ""); status != http.StatusOK || !strings.Contains(body, "idrac-rack1.example.com") {
+		t.Fatalf("proxied response = %d %s", status, body)
+	}
+	if status, _ := proxied(http.MethodPatch, "/redfish/v1/Systems/System.Embedded.1/", `{"AssetTag":"calvin"}`); status != http.StatusOK {
+		t.Fatalf("proxied PATCH status = %d", status)
+	}
+	if status, _ := proxied(http.MethodGet, "/redfish/v1/Oem/Error", ""); status != http.StatusOK {
+		t.Fatalf("proxied text status = %d", status)
+	}
+	contents, err := os.ReadFile(filepath.Join(dir, recordingFile(http.MethodGet, "/redfish/v1/Oem/Error")))
+	if err != nil || strings.Contains(string(contents), "calvin") {
+		t.Fatalf("text recording = %v %s", err, contents)
+	}
+
+	contents, err = os.ReadFile(filepath.Join(dir, recordingFile(http.MethodGet, "/redfish/v1/Systems/System.Embedded.1")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{"calvin", `"root"`, upstreamHost, "upstream-token", "Authorization"} {
+		if strings.Contains(string(contents), secret) {
+			t.Fatalf("recording contains %q:\n%s", secret, contents)
+		}
+	}
+	var recording Recording
+	if err := json.Unmarshal(contents, &recording); err != nil {
+		t.Fatal(err)
+	}
+	if recording.Response.Status != http.StatusOK || recording.Response.Header.Get("Location") != "http://{{host}}/redfish/v1/Systems/System.Embedded.1" {
+		t.Fatalf("recording = %+v", recording)
+	}
+
+	config = defaultConfig()
+	config.Recording = RecordingConfig{Mode: recordingReplay, Directory: dir}
+	if err := config.Recording.validate(); err != nil {
+		t.Fatalf("validate replay: %v", err)
+	}
+	bmc := newTestBMC(t, config)
+	router := newRouter(bmc)
+	admin, password := bmc.config.Authentication.Username, bmc.config.Authentication.Password
+	recorder := accountRequest(router, http.MethodGet, "/redfish/v1/Systems/System.Embedded.1", "", admin, password)
+	var replayed map[string]string
+	if err := json.Unmarshal(recorder.Body.Bytes(), &replayed); err != nil {
+		t.Fatalf("decode replay: %v (%s)", err, recorder.Body.String())
+	}
+	if recorder.Code != http.StatusOK || replayed["HostName"] != "mock-bmc" || replayed["Url"] != "https://example.com/console" || replayed["UserName"] != admin || replayed["Path"] != "/home/root" ||
+		replayed["Description"] != "set by root with "+password {
+		t.Fatalf("replayed = %d %v", recorder.Code, replayed)
+	}
+	recorder = accountRequest(router, http.MethodPatch, "/redfish/v1/Systems/System.Embedded.1", `{}`, admin, password)
+	if err := json.Unmarshal(recorder.Body.Bytes(), &replayed); err != nil || recorder.Code != http.StatusOK || replayed["AssetTag"] != password {
+		t.Fatalf("replayed PATCH = %d %s", recorder.Code, recorder.Body.String())
+	}
+	if recorder := accountRequest(router, http.MethodGet, "/redfish/v1/Systems/System.Embedded.1", "", admin, "wrong"); recorder.Code != http.StatusUnauthorized {
+		t.Fatalf("unauthenticated replay status = %d", recorder.Code)
+	}
+	if recorder := accountRequest(router, http.MethodGet, "/redfish/v1/Systems/1", "", admin, password); recorder.Code != http.StatusOK ||
+		!strings.Contains(recorder.Body.String(), "#ComputerSystem.") {
+		t.Fatalf("built-in fallback = %d %s", recorder.Code, recorder.Body.String())
+	}
+}
+
+func TestReplayRejectsBrokenRecordings(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "broken.json"), []byte(`{"request":`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	config := defaultConfig()
+	config.Recording = RecordingConfig{Mode: recordingReplay, Directory: dir}
+	if _, err := newMockBMC(config); err == nil || !strings.Contains(err.Error(), "broken.json") {
+		t.Fatalf("newMockBMC with a broken recording = %v", err)
+	}
+}
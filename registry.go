@@ -0,0 +1,179 @@
+package main
+
+import (
+	"embed"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+//go:embed registries/*.json
+var registryFiles embed.FS
+
+type MessageRegistryFile struct {
+	ODataContext string                    `json:"@odata.context"`
+	ODataType    string                    `json:"@odata.type"`
+	ODataID      string                    `json:"@odata.id"`
+	ID           string                    `json:"Id"`
+	Name         string                    `json:"Name"`
+	Description  string                    `json:"Description"`
+	Languages    []string                  `json:"Languages"`
+	Registry     string                    `json:"Registry"`
+	Location     []MessageRegistryLocation `json:"Location"`
+}
+
+type MessageRegistryLocation struct {
+	Language       string `json:"Language"`
+	URI            string `json:"Uri"`
+	PublicationURI string `json:"PublicationUri"`
+}
+
+type messageRegistry struct {
+	ID              string                               `json:"Id"`
+	Name            string                               `json:"Name"`
+	Language        string                               `json:"Language"`
+	RegistryPrefix  string                               `json:"RegistryPrefix"`
+	RegistryVersion string                               `json:"RegistryVersion"`
+	Messages        map[string]messageRegistryDefinition `json:"Messages"`
+	contents        []byte
+}
+
+type messageRegistryDefinition struct {
+	Message         string `json:"Message"`
+	MessageSeverity string `json:"MessageSeverity"`
+	NumberOfArgs    int    `json:"NumberOfArgs"`
+	Resolution      string `json:"Resolution"`
+}
+
+var messageRegistries = loadMessageRegistries()
+
+func loadMessageRegistries() map[string]*messageRegistry {
+	entries, err := registryFiles.ReadDir("registries")
+	if err != nil {
+		panic(err)
+	}
+	registries := make(map[string]*messageRegistry, len(entries))
+	for _, entry := range entries {
+		contents, err := registryFiles.ReadFile("registries/" + entry.Name())
+		if err != nil {
+			panic(err)
+		}
+		registry := &messageRegistry{contents: contents}
+		if err := json.Unmarshal(contents, registry); err != nil {
+			panic("decode registry " + entry.Name() + ": " + err.Error())
+		}
+		registries[registry.RegistryPrefix] = registry
+	}
+	return registries
+}
+
+func registryMessage(prefix, id string, args ...string) Message {
+	registry, ok := messageRegistries[prefix]
+	if !ok {
+		panic("unknown message registry " + prefix)
+	}
+	definition, ok := registry.Messages[id]
+	if !ok {
+		panic("unknown message " + prefix + "." + id)
+	}
+	text := definition.Message
+	for i := len(args); i > 0; i-- {
+		text = strings.ReplaceAll(text, "%"+strconv.Itoa(i), args[i-1])
+	}
+	if args == nil {
+		args = []string{}
+	}
+	return Message{
+		ODataType:       "#Message.v1_1_2.Message",
+		MessageID:       registry.ID + "." + id,
+		Message:         text,
+		MessageArgs:     args,
+		Severity:        definition.MessageSeverity,
+		MessageSeverity: definition.MessageSeverity,
+		Resolution:      definition.Resolution,
+	}
+}
+
+func baseMessage(id string, args ...string) Message {
+	return registryMessage("Base", id, args...)
+}
+
+func registryByID(id string) (*messageRegistry, bool) {
+	for _, registry := range messageRegistries {
+		if registry.ID == id {
+			return registry, true
+		}
+	}
+	return nil, false
+}
+
+func getRegistriesCollection(c *gin.Context) {
+	c.Header("OData-Version", "4.0")
+	ids := make([]string, 0, len(messageRegistries))
+	for _, registry := range messageRegistries {
+		ids = append(ids, registry.ID)
+	}
+	sort.Strings(ids)
+	members := make([]Link, 0, len(ids))
+	for _, id := range ids {
+		members = append(members, Link{ODataID: "/redfish/v1/Registries/" + id})
+	}
+	collection := Collection{
+		ODataContext: "/redfish/v1/$metadata#MessageRegistryFileCollection.MessageRegistryFileCollection",
+		ODataType:    "#MessageRegistryFileCollection.MessageRegistryFileCollection",
+		ODataID:      "/redfish/v1/Registries",
+		Name:         "Registry File Collection",
+		MembersCount: len(members),
+		Members:      members,
+	}
+	c.JSON(http.StatusOK, collection)
+}
+
+func getRegistryFile(c *gin.Context) {
+	c.Header("OData-Version", "4.0")
+	registry, ok := registryByID(c.Param("id"))
+	if !ok {
+		resourceNotFound(c, "MessageRegistryFile", c.Param("id"))
+		return
+	}
+	registryFile := MessageRegistryFile{
+		ODataContext: "/redfish/v1/$metadata#MessageRegistryFile.MessageRegistryFile",
+		ODataType:    "#MessageRegistryFile.v1_1_3.MessageRegistryFile",
+		ODataID:      "/redfish/v1/Registries/" + registry.ID,
+		ID:           registry.ID,
+		Name:         registry.Name + " File",
+		Description:  registry.Name + " File locations",
+		Languages:    []string{registry.Language},
+		Registry:     registry.RegistryPrefix + "." + majorMinor(registry.RegistryVersion),
+		Location: []MessageRegistryLocation{
+			{
+				Language:       registry.Language,
+				URI:            "/redfish/v1/Registries/" + registry.ID + "/" + registry.ID + ".json",
+				PublicationURI: "https://redfish.dmtf.org/registries/" + registry.ID + ".json",
+			},
+		},
+	}
+	c.JSON(http.StatusOK, registryFile)
+}
+
+func getRegistry(c *gin.Context) {
+	c.Header("OData-Version", "4.0")
+	registry, ok := registryByID(c.Param("id"))
+	if !ok || c.Param("file") != registry.ID+".json" {
+		resourceNotFound(c, "MessageRegistry", c.Param("file"))
+		return
+	}
+	c.Data(http.StatusOK, "application/json; charset=utf-8", registry.contents)
+}
+
+func majorMinor(version string) string {
+	parts := strings.SplitN(version, ".", 3)
+	if len(parts) < 2 {
+		return version
+	}
+	return parts[0] + "." + parts[1]
+}
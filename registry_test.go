@@ -0,0 +1,93 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRegistriesResolveMessageIDs(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	bmc := newTestBMC(t, defaultConfig())
+	router := newRouter(bmc)
+	get := func(path string, response any) {
+		t.Helper()
+		request := httptest.NewRequest(http.MethodGet, path, nil)
+		request.SetBasicAuth(bmc.config.Authentication.Username, bmc.config.Authentication.Password)
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("GET %s status = %d, want %d", path, recorder.Code, http.StatusOK)
+		}
+		if err := json.Unmarshal(recorder.Body.Bytes(), response); err != nil {
+			t.Fatalf("decode %s: %v", path, err)
+		}
+	}
+
+	var collection Collection
+	get("/redfish/v1/Registries", &collection)
+	if collection.MembersCount != 4 {
+		t.Fatalf("registry members = %#v, want Base, TaskEvent, Update, and ResourceEvent", collection.Members)
+	}
+
+	message := baseMessage("PropertyMissing", "Boot")
+	resolved := false
+	for _, member := range collection.Members {
+		var file MessageRegistryFile
+		get(member.ODataID, &file)
+		if len(file.Location) != 1 {
+			t.Fatalf("registry file %s locations = %#v", file.ID, file.Location)
+		}
+		var registry struct {
+			RegistryPrefix  string
+			RegistryVersion string
+			Messages        map[string]struct{ Message string }
+		}
+		get(file.Location[0].URI, &registry)
+		prefix := registry.RegistryPrefix + "." + registry.RegistryVersion + "."
+		if id, ok := strings.CutPrefix(message.MessageID, prefix); ok {
+			resolved = registry.Messages[id].Message == "The property %1 is a required property and must be included in the request."
+		}
+	}
+	if !resolved {
+		t.Fatalf("MessageId %s was not resolved through the served registries", message.MessageID)
+	}
+}
+
+func TestReferencedMessagesExist(t *testing.T) {
+	pattern := regexp.MustCompile(`(?:baseMessage\(|registryMessage\("(\w+)", )"(\w+)"`)
+	sources, err := filepath.Glob("*.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, source := range sources {
+		if strings.HasSuffix(source, "_test.go") {
+			continue
+		}
+		contents, err := os.ReadFile(source)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, match := range pattern.FindAllStringSubmatch(string(contents), -1) {
+			prefix := match[1]
+			if prefix == "" {
+				prefix = "Base"
+			}
+			registry, ok := messageRegistries[prefix]
+			if !ok {
+				t.Errorf("%s references unknown registry %s", source, prefix)
+				continue
+			}
+			if _, ok := registry.Messages[match[2]]; !ok {
+				t.Errorf("%s references unknown message %s.%s", source, prefix, match[2])
+			}
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"slices"
+	"time"
+)
+
+// Installation outcomes a system's installation scenario can script.
+const (
+	installationInstalled = "installed"
+	installationFailed    = "failed"
+	installationHang      = "hang"
+	installationReboot    = "reboot"
+)
+
+// Media download behaviors a system's virtual media can simulate.
+const (
+	mediaDownloadNormal = "normal"
+	mediaDownloadSlow   = "slow"
+	mediaDownloadStall  = "stall"
+)
+
+// InstallationScenarioConfig scripts how an OS installation from virtual
+// media ends once installation_duration_seconds have passed: Installed,
+// InstallFailed, never (hang), or with the system rebooting into BootTarget
+// instead of the installed OS (reboot).
+type InstallationScenarioConfig struct {
+	Outcome    string `json:"outcome"`
+	BootTarget string `json:"boot_target"`
+}
+
+// MediaDownloadConfig simulates a slow or stalled image server for
+// VirtualMedia.InsertMedia. A slow download starts after DelaySeconds and
+// reads at most BytesPerSecond; a stalled one stops after the response
+// headers and fails after DelaySeconds, or waits until the client gives up
+// when DelaySeconds is 0.
+type MediaDownloadConfig struct {
+	Behavior       string `json:"behavior"`
+	DelaySeconds   int    `json:"delay_seconds"`
+	BytesPerSecond int    `json:"bytes_per_second"`
+}
+
+var errDownloadStalled = errors.New("transfer stalled")
+
+// validateScenarios checks a system's installation scenario and media
+// download settings.
+func (s SystemConfig) validateScenarios() error {
+	switch s.InstallationScenario.Outcome {
+	case "", installationInstalled, installationFailed, installationHang:
+		if s.InstallationScenario.BootTarget != "" {
+			return errors.New(`installation_scenario.boot_target is only used by the "reboot" outcome`)
+		}
+	case installationReboot:
+		if target := s.InstallationScenario.BootTarget; target != "" && !slices.Contains([]string{"Hdd", "Pxe", "Usb"}, target) {
+			return errors.New(`installation_scenario.boot_target must be "Hdd", "Pxe" or "Usb"`)
+		}
+	default:
+		return errors.New(`installation_scenario.outcome must be "installed", "failed", "hang" or "reboot"`)
+	}
+	switch s.MediaDownload.Behavior {
+	case "", mediaDownloadNormal, mediaDownloadSlow, mediaDownloadStall:
+	default:
+		return errors.New(`media_download.behavior must be "normal", "slow" or "stall"`)
+	}
+	if s.MediaDownload.DelaySeconds < 0 || s.MediaDownload.BytesPerSecond < 0 {
+		return errors.New("media_download.delay_seconds and media_download.bytes_per_second must not be negative")
+	}
+	return nil
+}
+
+// finishInstallation ends an installation that has run its course the way
+// the system's scenario scripts it. The caller must hold the state lock.
+func (b *mockBMC) finishInstallation(state *mockServerState, system SystemConfig, now time.Time) {
+	systemURI := "/redfish/v1/Systems/" + system.ID
+	switch system.InstallationScenario.Outcome {
+	case installationHang:
+		return
+	case installationFailed:
+		state.installationStatus = "InstallFailed"
+	case installationReboot:
+		state.installationStatus = "Ready"
+		if state.inserted {
+			state.installationStatus = "MediaMounted"
+		}
+		state.bootSourceOverrideTarget = system.InstallationScenario.BootTarget
+		if state.bootSourceOverrideTarget == "" {
+			state.bootSourceOverrideTarget = "Hdd"
+		}
+		previousPowerState := state.powerState
+		poweringOn := time.Duration(system.PowerOnDelaySeconds) * time.Second
+		poweringOff := time.Duration(system.PowerOffDelaySeconds) * time.Second
+		if state.resetPower("ForceRestart", now, poweringOn, poweringOff) == nil {
+			b.schedulePowerEvents(state, system.ID, previousPowerState, now)
+		}
+	default:
+		state.installationStatus = "Installed"
+	}
+	b.logSystemEvent(system.ID, registryMessage("ResourceEvent", "ResourceChanged"), systemURI, now)
+}
+
+// simulateDownload applies a media download scenario to an image response
+// body. It returns the reader to download from, or an error once a stall has
+// run its course. Delays and throttling wait on clock.
+func simulateDownload(ctx context.Context, clock timeSource, download MediaDownloadConfig, body io.Reader) (io.Reader, error) {
+	delay := time.Duration(download.DelaySeconds) * time.Second
+	switch download.Behavior {
+	case mediaDownloadSlow:
+		if err := sleep(ctx, clock, delay); err != nil {
+			return nil, err
+		}
+		if download.BytesPerSecond > 0 {
+			return &throttledReader{ctx: ctx, clock: clock, reader: body, bytesPerSecond: download.BytesPerSecond}, nil
+		}
+	case mediaDownloadStall:
+		if delay == 0 {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}
+		if err := sleep(ctx, clock, delay); err != nil {
+			return nil, err
+		}
+		return nil, errDownloadStalled
+	}
+	return body, nil
+}
+
+// throttledReader limits reads from reader to bytesPerSecond.
+type throttledReader struct {
+	ctx            context.Context
+	clock          timeSource
+	reader         io.Reader
+	bytesPerSecond int
+}
+
+func (r *throttledReader) Read(p []byte) (int, error) {
+	if len(p) > r.bytesPerSecond {
+		p = p[:r.bytesPerSecond]
+	}
+	n, err := r.reader.Read(p)
+	if n > 0 {
+		if sleepErr := sleep(r.ctx, r.clock, time.Duration(n)*time.Second/time.Duration(r.bytesPerSecond)); sleepErr != nil {
+			return n, sleepErr
+		}
+	}
+	return n, err
+}
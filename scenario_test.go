@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestInstallationScenarios(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		scenario InstallationScenarioConfig
+		status   string
+		target   string
+		power    string
+	}{
+		{InstallationScenarioConfig{}, "Installed", "Cd", "On"},
+		{InstallationScenarioConfig{Outcome: installationFailed}, "InstallFailed", "Cd", "On"},
+		{InstallationScenarioConfig{Outcome: installationHang}, "Installing", "Cd", "On"},
+		{InstallationScenarioConfig{Outcome: installationReboot, BootTarget: "Pxe"}, "MediaMounted", "Pxe", "Off"},
+	}
+	for _, test := range tests {
+		config := defaultConfig()
+		config.Clock = ClockConfig{Frozen: true}
+		config.Systems[0].InstallationDurationSeconds = 300
+		config.Systems[0].InstallationScenario = test.scenario
+		if err := config.Systems[0].validateScenarios(); err != nil {
+			t.Fatalf("validateScenarios(%+v): %v", test.scenario, err)
+		}
+		bmc := newTestBMC(t, config)
+		router := newRouter(bmc)
+		admin, password := bmc.config.Authentication.Username, bmc.config.Authentication.Password
+		adminState := func() AdminSystemState {
+			t.Helper()
+			var state AdminState
+			recorder := accountRequest(router, http.MethodGet, "/mock/admin/state", "", admin, password)
+			if err := json.Unmarshal(recorder.Body.Bytes(), &state); err != nil {
+				t.Fatal(err)
+			}
+			return state.Systems["1"]
+		}
+
+		patch := `{"systems":{"1":{"boot_source_override_enabled":"Once","boot_source_override_target":"Cd","installation_status":"MediaMounted","virtual_media":{"image":"http://example.test/os.iso","inserted":true}}}}`
+		if recorder := accountRequest(router, http.MethodPatch, "/mock/admin/state", patch, admin, password); recorder.Code != http.StatusOK {
+			t.Fatalf("patch status = %d, body = %s", recorder.Code, recorder.Body.String())
+		}
+		if recorder := accountRequest(router, http.MethodPost, "/redfish/v1/Systems/1/Actions/ComputerSystem.Reset", `{"ResetType":"ForceRestart"}`, admin, password); recorder.Code != http.StatusNoContent {
+			t.Fatalf("reset status = %d", recorder.Code)
+		}
+		bmc.clock.advance(2 * time.Second)
+		if state := adminState(); state.InstallationStatus != "Installing" || state.PowerState != "On" {
+			t.Fatalf("%s: state while installing = %+v", test.scenario.Outcome, state)
+		}
+		bmc.clock.advance(300 * time.Second)
+		state := adminState()
+		if state.InstallationStatus != test.status || state.BootSourceOverrideTarget != test.target || state.PowerState != test.power {
+			t.Fatalf("%s: state after installing = %+v", test.scenario.Outcome, state)
+		}
+	}
+
+	invalid := SystemConfig{InstallationScenario: InstallationScenarioConfig{Outcome: installationReboot, BootTarget: "Cd"}}
+	if err := invalid.validateScenarios(); err == nil {
+		t.Fatal("validateScenarios accepted a reboot into the virtual CD")
+	}
+}
+
+func TestMediaDownloadScenarios(t *testing.T) {
+	image := make([]byte, 18*2048)
+	copy(image[16*2048:], []byte{1, 'C', 'D', '0', '0', '1', 1})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write(image)
+	}))
+	defer server.Close()
+
+	clock := newMockClock(ClockConfig{Frozen: true})
+	slow := MediaDownloadConfig{Behavior: mediaDownloadSlow, DelaySeconds: 60, BytesPerSecond: 4 * len(image)}
+	done := make(chan error)
+	go func() {
+		done <- downloadAndValidateISO(context.Background(), clock, server.URL+"/installer.iso", "", "", slow)
+	}()
+	select {
+	case err := <-done:
+		t.Fatalf("slow download finished on a frozen clock: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	for advanced := time.Duration(0); ; advanced += time.Second {
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Fatalf("slow download error = %v", err)
+			}
+			if advanced < 60*time.Second {
+				t.Fatalf("slow download finished after %s", advanced)
+			}
+		case <-time.After(time.Millisecond):
+			clock.advance(time.Second)
+			continue
+		}
+		break
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	stall := MediaDownloadConfig{Behavior: mediaDownloadStall}
+	err := downloadAndValidateISO(ctx, clock, server.URL+"/installer.iso", "", "", stall)
+	if !errors.Is(err, context.DeadlineExceeded) || errors.Is(err, errInvalidISO) {
+		t.Fatalf("stalled download error = %v", err)
+	}
+}
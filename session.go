@@ -0,0 +1,369 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SessionService struct {
+	ODataContext   string `json:"@odata.context"`
+	ODataType      string `json:"@odata.type"`
+	ODataID        string `json:"@odata.id"`
+	ID             string `json:"Id"`
+	Name           string `json:"Name"`
+	ServiceEnabled bool   `json:"ServiceEnabled"`
+	SessionTimeout int    `json:"SessionTimeout"`
+	Sessions       Link   `json:"Sessions"`
+	Status         Status `json:"Status"`
+}
+
+type Session struct {
+	ODataContext string    `json:"@odata.context"`
+	ODataType    string    `json:"@odata.type"`
+	ODataID      string    `json:"@odata.id"`
+	ID           string    `json:"Id"`
+	Name         string    `json:"Name"`
+	UserName     string    `json:"UserName"`
+	Password     *string   `json:"Password"`
+	CreatedTime  string    `json:"CreatedTime"`
+	SessionType  string    `json:"SessionType"`
+	ExtendedInfo []Message `json:"@Message.ExtendedInfo,omitempty"`
+}
+
+type SessionCreateRequest struct {
+	UserName string `json:"UserName"`
+	Password string `json:"Password"`
+}
+
+type SessionServicePatchRequest struct {
+	SessionTimeout *int `json:"SessionTimeout"`
+}
+
+type mockSession struct {
+	id        string
+	token     string
+	username  string
+	createdAt time.Time
+	lastUsed  time.Time
+}
+
+type sessionStore struct {
+	sync.Mutex
+	nextID   int
+	timeout  time.Duration
+	sessions map[string]*mockSession
+}
+
+func newSessionStore(timeoutSeconds int) *sessionStore {
+	return &sessionStore{
+		nextID:   1,
+		timeout:  time.Duration(timeoutSeconds) * time.Second,
+		sessions: map[string]*mockSession{},
+	}
+}
+
+func (s *sessionStore) create(username string, now time.Time) (*mockSession, error) {
+	token := make([]byte, 32)
+	if _, err := rand.Read(token); err != nil {
+		return nil, err
+	}
+
+	s.Lock()
+	defer s.Unlock()
+	session := &mockSession{
+		id:        strconv.Itoa(s.nextID),
+		token:     hex.EncodeToString(token),
+		username:  username,
+		createdAt: now,
+		lastUsed:  now,
+	}
+	s.nextID++
+	s.sessions[session.id] = session
+	return session, nil
+}
+
+func (s *sessionStore) authenticate(token string, now time.Time) (mockSession, bool) {
+	s.Lock()
+	defer s.Unlock()
+	s.expire(now)
+	for _, session := range s.sessions {
+		if subtle.ConstantTimeCompare([]byte(session.token), []byte(token)) == 1 {
+			session.lastUsed = now
+			return *session, true
+		}
+	}
+	return mockSession{}, false
+}
+
+func (s *sessionStore) get(id string, now time.Time) (mockSession, bool) {
+	s.Lock()
+	defer s.Unlock()
+	s.expire(now)
+	session, ok := s.sessions[id]
+	if !ok {
+		return mockSession{}, false
+	}
+	return *session, true
+}
+
+func (s *sessionStore) list(now time.Time) []mockSession {
+	s.Lock()
+	defer s.Unlock()
+	s.expire(now)
+	list := make([]mockSession, 0, len(s.sessions))
+	for _, session := range s.sessions {
+		list = append(list, *session)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		left, _ := strconv.Atoi(list[i].id)
+		right, _ := strconv.Atoi(list[j].id)
+		return left < right
+	})
+	return list
+}
+
+func (s *sessionStore) delete(id string) bool {
+	s.Lock()
+	defer s.Unlock()
+	if _, ok := s.sessions[id]; !ok {
+		return false
+	}
+	delete(s.sessions, id)
+	return true
+}
+
+// reset logs every session out and restores the configured timeout.
+func (s *sessionStore) reset(timeoutSeconds int) {
+	s.Lock()
+	defer s.Unlock()
+	s.nextID = 1
+	s.timeout = time.Duration(timeoutSeconds) * time.Second
+	s.sessions = map[string]*mockSession{}
+}
+
+func (s *sessionStore) timeoutSeconds() int {
+	s.Lock()
+	defer s.Unlock()
+	return int(s.timeout / time.Second)
+}
+
+func (s *sessionStore) setTimeout(seconds int) {
+	s.Lock()
+	defer s.Unlock()
+	s.timeout = time.Duration(seconds) * time.Second
+}
+
+func (s *sessionStore) expire(now time.Time) {
+	for id, session := range s.sessions {
+		if now.Sub(session.lastUsed) > s.timeout {
+			delete(s.sessions, id)
+		}
+	}
+}
+
+func (b *mockBMC) requireAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if token := c.GetHeader("X-Auth-Token"); token != "" {
+			session, ok := b.sessions.authenticate(token, b.now())
+			if !ok {
+				redfishError(c, http.StatusUnauthorized, baseMessage("NoValidSession"))
+				return
+			}
+			account, ok := b.accounts.active(session.username, b.now())
+			if !ok {
+				redfishError(c, http.StatusUnauthorized, baseMessage("NoValidSession"))
+				return
+			}
+			b.authorizeAccount(c, account)
+			return
+		}
+
+		username, password, ok := c.Request.BasicAuth()
+		if !ok {
+			c.Header("WWW-Authenticate", `Basic realm="Authorization Required"`)
+			redfishError(c, http.StatusUnauthorized, baseMessage("NoValidSession"))
+			return
+		}
+		account, err := b.accounts.authenticate(username, password, b.now())
+		if err != nil {
+			c.Header("WWW-Authenticate", `Basic realm="Authorization Required"`)
+			authenticationFailed(c, err)
+			return
+		}
+		b.authorizeAccount(c, account)
+	}
+}
+
+// authorizeAccount records the caller for later privilege checks. An account
+// that must change its password may only read and PATCH itself and delete its
+// own sessions.
+func (b *mockBMC) authorizeAccount(c *gin.Context, account mockAccount) {
+	c.Set(gin.AuthUserKey, account.username)
+	c.Set(accountRoleKey, account.roleID)
+	if !account.passwordChangeRequired {
+		return
+	}
+	ownAccount := c.FullPath() == "/redfish/v1/AccountService/Accounts/:id" && c.Param("id") == account.id &&
+		(c.Request.Method == http.MethodGet || c.Request.Method == http.MethodPatch)
+	logout := false
+	if c.FullPath() == "/redfish/v1/SessionService/Sessions/:id" && c.Request.Method == http.MethodDelete {
+		session, ok := b.sessions.get(c.Param("id"), b.now())
+		logout = ok && session.username == account.username
+	}
+	if !ownAccount && !logout {
+		redfishError(c, http.StatusForbidden, baseMessage("PasswordChangeRequired", accountURI(account.id)))
+	}
+}
+
+func sessionResource(session mockSession) Session {
+	return Session{
+		ODataContext: "/redfish/v1/$metadata#Session.Session",
+		ODataType:    "#Session.v1_7_0.Session",
+		ODataID:      "/redfish/v1/SessionService/Sessions/" + session.id,
+		ID:           session.id,
+		Name:         "User Session",
+		UserName:     session.username,
+		CreatedTime:  session.createdAt.UTC().Format(time.RFC3339),
+		SessionType:  "Redfish",
+	}
+}
+
+func (b *mockBMC) getSessionService(c *gin.Context) {
+	c.Header("OData-Version", "4.0")
+	sessionService := SessionService{
+		ODataContext:   "/redfish/v1/$metadata#SessionService.SessionService",
+		ODataType:      "#SessionService.v1_1_9.SessionService",
+		ODataID:        "/redfish/v1/SessionService",
+		ID:             "SessionService",
+		Name:           "Session Service",
+		ServiceEnabled: true,
+		SessionTimeout: b.sessions.timeoutSeconds(),
+		Sessions:       Link{ODataID: "/redfish/v1/SessionService/Sessions"},
+		Status:         Status{State: "Enabled", Health: "OK"},
+	}
+	c.JSON(http.StatusOK, sessionService)
+}
+
+func (b *mockBMC) patchSessionService(c *gin.Context) {
+	c.Header("OData-Version", "4.0")
+	var req SessionServicePatchRequest
+	if !bindRedfishJSON(c, &req) {
+		return
+	}
+	if req.SessionTimeout == nil {
+		redfishError(c, http.StatusBadRequest, baseMessage("PropertyMissing", "SessionTimeout"))
+		return
+	}
+	if *req.SessionTimeout < minSessionTimeout || *req.SessionTimeout > maxSessionTimeout {
+		redfishError(c, http.StatusBadRequest, baseMessage("PropertyValueOutOfRange", strconv.Itoa(*req.SessionTimeout), "SessionTimeout"))
+		return
+	}
+	b.sessions.setTimeout(*req.SessionTimeout)
+
+	c.Status(http.StatusNoContent)
+}
+
+func (b *mockBMC) getSessionsCollection(c *gin.Context) {
+	c.Header("OData-Version", "4.0")
+	active := b.sessions.list(b.now())
+	members := make([]Link, 0, len(active))
+	for _, session := range active {
+		if mayManageSession(c, session) {
+			members = append(members, Link{ODataID: "/redfish/v1/SessionService/Sessions/" + session.id})
+		}
+	}
+	collection := Collection{
+		ODataContext: "/redfish/v1/$metadata#SessionCollection.SessionCollection",
+		ODataType:    "#SessionCollection.SessionCollection",
+		ODataID:      "/redfish/v1/SessionService/Sessions",
+		Name:         "Session Collection",
+		MembersCount: len(members),
+		Members:      members,
+	}
+	c.JSON(http.StatusOK, collection)
+}
+
+func (b *mockBMC) createSession(c *gin.Context) {
+	c.Header("OData-Version", "4.0")
+	var req SessionCreateRequest
+	if !bindRedfishJSON(c, &req) {
+		return
+	}
+	if req.UserName == "" {
+		redfishError(c, http.StatusBadRequest, baseMessage("PropertyMissing", "UserName"))
+		return
+	}
+	if req.Password == "" {
+		redfishError(c, http.StatusBadRequest, baseMessage("PropertyMissing", "Password"))
+		return
+	}
+	account, err := b.accounts.authenticate(req.UserName, req.Password, b.now())
+	if errors.Is(err, errAccountLocked) {
+		authenticationFailed(c, err)
+		return
+	} else if err != nil {
+		redfishError(c, http.StatusUnauthorized, baseMessage("ResourceAtUriUnauthorized", c.Request.URL.Path, "Invalid username or password"))
+		return
+	}
+
+	session, err := b.sessions.create(req.UserName, b.now())
+	if err != nil {
+		redfishError(c, http.StatusInternalServerError, baseMessage("InternalError"))
+		return
+	}
+	resource := sessionResource(*session)
+	if account.passwordChangeRequired {
+		resource.ExtendedInfo = []Message{baseMessage("PasswordChangeRequired", accountURI(account.id))}
+	}
+	c.Header("X-Auth-Token", session.token)
+	c.Header("Location", resource.ODataID)
+	c.JSON(http.StatusCreated, resource)
+}
+
+func (b *mockBMC) getSession(c *gin.Context) {
+	c.Header("OData-Version", "4.0")
+	session, ok := b.sessions.get(c.Param("id"), b.now())
+	if !ok {
+		resourceNotFound(c, "Session", c.Param("id"))
+		return
+	}
+	if !mayManageSession(c, session) {
+		redfishError(c, http.StatusForbidden, baseMessage("InsufficientPrivilege"))
+		return
+	}
+	c.JSON(http.StatusOK, sessionResource(session))
+}
+
+func (b *mockBMC) deleteSession(c *gin.Context) {
+	c.Header("OData-Version", "4.0")
+	session, ok := b.sessions.get(c.Param("id"), b.now())
+	if !ok {
+		resourceNotFound(c, "Session", c.Param("id"))
+		return
+	}
+	if !mayManageSession(c, session) {
+		redfishError(c, http.StatusForbidden, baseMessage("InsufficientPrivilege"))
+		return
+	}
+	if !b.sessions.delete(session.id) {
+		resourceNotFound(c, "Session", c.Param("id"))
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// mayManageSession reports whether the caller may read or delete a session:
+// their own sessions always, anyone's with the ConfigureManager privilege.
+func mayManageSession(c *gin.Context, session mockSession) bool {
+	return session.username == c.GetString(gin.AuthUserKey) ||
+		roleHasPrivilege(c.GetString(accountRoleKey), privilegeConfigureManager)
+}
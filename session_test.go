@@ -0,0 +1,133 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestSessionLifecycle(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	bmc := newTestBMC(t, defaultConfig())
+	router := newRouter(bmc)
+
+	request := httptest.NewRequest(http.MethodPost, "/redfish/v1/SessionService/Sessions",
+		strings.NewReader(`{"UserName":"admin","Password":"password"}`))
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusCreated {
+		t.Fatalf("create session status = %d, want %d", recorder.Code, http.StatusCreated)
+	}
+	token := recorder.Header().Get("X-Auth-Token")
+	location := recorder.Header().Get("Location")
+	if token == "" || location != "/redfish/v1/SessionService/Sessions/1" {
+		t.Fatalf("session token = %q, location = %q", token, location)
+	}
+
+	request = httptest.NewRequest(http.MethodGet, "/redfish/v1/Systems", nil)
+	request.Header.Set("X-Auth-Token", token)
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("token authenticated status = %d, want %d", recorder.Code, http.StatusOK)
+	}
+
+	request = httptest.NewRequest(http.MethodDelete, location, nil)
+	request.Header.Set("X-Auth-Token", token)
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusNoContent {
+		t.Fatalf("delete session status = %d, want %d", recorder.Code, http.StatusNoContent)
+	}
+
+	request = httptest.NewRequest(http.MethodGet, "/redfish/v1/Systems", nil)
+	request.Header.Set("X-Auth-Token", token)
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusUnauthorized {
+		t.Fatalf("deleted session status = %d, want %d", recorder.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestSessionTimeout(t *testing.T) {
+	store := newSessionStore(60)
+	start := time.Now()
+	session, err := store.create("admin", start)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := store.authenticate(session.token, start.Add(45*time.Second)); !ok {
+		t.Fatal("session expired before its timeout")
+	}
+	if _, ok := store.authenticate(session.token, start.Add(90*time.Second)); !ok {
+		t.Fatal("session activity did not extend its timeout")
+	}
+	if _, ok := store.authenticate(session.token, start.Add(151*time.Second)); ok {
+		t.Fatal("idle session was not expired")
+	}
+	if len(store.list(start.Add(151*time.Second))) != 0 {
+		t.Fatal("expired session is still listed")
+	}
+}
+
+func TestSessionsAreLimitedToTheirOwner(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	config := defaultConfig()
+	config.Accounts = []AccountConfig{{Username: "viewer", Password: "viewer-pass", Role: "ReadOnly"}}
+	bmc := newTestBMC(t, config)
+	router := newRouter(bmc)
+	admin, password := bmc.config.Authentication.Username, bmc.config.Authentication.Password
+	login := func(username, password string) string {
+		t.Helper()
+		recorder := accountRequest(router, http.MethodPost, "/redfish/v1/SessionService/Sessions",
+			`{"UserName":"`+username+`","Password":"`+password+`"}`, "", "")
+		if recorder.Code != http.StatusCreated {
+			t.Fatalf("login %s status = %d", username, recorder.Code)
+		}
+		return recorder.Header().Get("Location")
+	}
+	adminSession := login(admin, password)
+	viewerSession := login("viewer", "viewer-pass")
+
+	recorder := accountRequest(router, http.MethodGet, "/redfish/v1/SessionService/Sessions", "", "viewer", "viewer-pass")
+	if strings.Contains(recorder.Body.String(), adminSession) || !strings.Contains(recorder.Body.String(), viewerSession) {
+		t.Fatalf("viewer's session collection = %s", recorder.Body.String())
+	}
+	for _, method := range []string{http.MethodGet, http.MethodDelete} {
+		if recorder := accountRequest(router, method, adminSession, "", "viewer", "viewer-pass"); recorder.Code != http.StatusForbidden ||
+			!strings.Contains(recorder.Body.String(), "InsufficientPrivilege") {
+			t.Fatalf("viewer %s of another session = %d %s", method, recorder.Code, recorder.Body.String())
+		}
+	}
+	if recorder := accountRequest(router, http.MethodGet, viewerSession, "", admin, password); recorder.Code != http.StatusOK {
+		t.Fatalf("administrator GET of another session = %d", recorder.Code)
+	}
+	if recorder := accountRequest(router, http.MethodDelete, viewerSession, "", "viewer", "viewer-pass"); recorder.Code != http.StatusNoContent {
+		t.Fatalf("viewer logout = %d", recorder.Code)
+	}
+}
+
+func TestPasswordChangeRequiredOnlyLogsOutOwnSessions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	config := defaultConfig()
+	config.Authentication.PasswordChangeRequired = true
+	config.Accounts = []AccountConfig{{Username: "operator", Password: "operator-pass", Role: "Operator"}}
+	bmc := newTestBMC(t, config)
+	router := newRouter(bmc)
+	admin, password := bmc.config.Authentication.Username, bmc.config.Authentication.Password
+	operatorSession, _ := bmc.sessions.create("operator", bmc.now())
+	adminSession, _ := bmc.sessions.create(admin, bmc.now())
+
+	if recorder := accountRequest(router, http.MethodDelete, "/redfish/v1/SessionService/Sessions/"+operatorSession.id, "", admin, password); recorder.Code != http.StatusForbidden ||
+		!strings.Contains(recorder.Body.String(), "PasswordChangeRequired") {
+		t.Fatalf("delete of another session = %d %s", recorder.Code, recorder.Body.String())
+	}
+	if recorder := accountRequest(router, http.MethodDelete, "/redfish/v1/SessionService/Sessions/"+adminSession.id, "", admin, password); recorder.Code != http.StatusNoContent {
+		t.Fatalf("logout = %d %s", recorder.Code, recorder.Body.String())
+	}
+}
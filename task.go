@@ -0,0 +1,253 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TaskService struct {
+	ODataContext                 string `json:"@odata.context"`
+	ODataType                    string `json:"@odata.type"`
+	ODataID                      string `json:"@odata.id"`
+	ID                           string `json:"Id"`
+	Name                         string `json:"Name"`
+	ServiceEnabled               bool   `json:"ServiceEnabled"`
+	DateTime                     string `json:"DateTime"`
+	CompletedTaskOverWritePolicy string `json:"CompletedTaskOverWritePolicy"`
+	Tasks                        Link   `json:"Tasks"`
+	Status                       Status `json:"Status"`
+}
+
+type Task struct {
+	ODataContext    string    `json:"@odata.context"`
+	ODataType       string    `json:"@odata.type"`
+	ODataID         string    `json:"@odata.id"`
+	ID              string    `json:"Id"`
+	Name            string    `json:"Name"`
+	TaskState       string    `json:"TaskState"`
+	TaskStatus      string    `json:"TaskStatus"`
+	PercentComplete int       `json:"PercentComplete"`
+	StartTime       string    `json:"StartTime"`
+	EndTime         string    `json:"EndTime,omitempty"`
+	TaskMonitor     string    `json:"TaskMonitor"`
+	Messages        []Message `json:"Messages"`
+}
+
+type mockTask struct {
+	id            string
+	name          string
+	startedAt     time.Time
+	applyDuration time.Duration
+	transferred   bool
+	transferredAt time.Time
+	err           error
+	apply         func()
+}
+
+type taskStore struct {
+	sync.Mutex
+	nextID int
+	tasks  map[string]*mockTask
+	clock  timeSource
+	// ctx is passed to every transfer and cancelled by reset.
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// newTaskStore returns an empty store that times its tasks with clock.
+func newTaskStore(clock timeSource) *taskStore {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &taskStore{nextID: 1, tasks: map[string]*mockTask{}, clock: clock, ctx: ctx, cancel: cancel}
+}
+
+// start records a new task and runs transfer in the background. Once transfer
+// succeeds the task spends applyDuration in the Running state before it is
+// reported as Completed, at which point the apply function transfer returned
+// is called once; a transfer error ends the task in Exception. The context
+// passed to transfer is cancelled when the store is reset. The apply
+// step also runs on a timer, so its side effects happen without anyone
+// polling the task.
+func (s *taskStore) start(name string, applyDuration time.Duration, now time.Time, transfer func(context.Context) (func(), error)) string {
+	s.Lock()
+	task := &mockTask{
+		id:            strconv.Itoa(s.nextID),
+		name:          name,
+		startedAt:     now,
+		applyDuration: applyDuration,
+	}
+	s.nextID++
+	s.tasks[task.id] = task
+	ctx := s.ctx
+	s.Unlock()
+
+	go func() {
+		apply, err := transfer(ctx)
+		s.Lock()
+		task.transferred = true
+		task.transferredAt = s.clock.now()
+		task.err = err
+		task.apply = apply
+		s.Unlock()
+		if err == nil {
+			s.clock.afterFunc(applyDuration, func() { s.advance(s.clock.now()) })
+		}
+	}()
+	return task.id
+}
+
+func (s *taskStore) get(id string, now time.Time) (Task, bool) {
+	s.Lock()
+	defer s.Unlock()
+	task, ok := s.tasks[id]
+	if !ok {
+		return Task{}, false
+	}
+	task.settle(now)
+	return task.resource(now), true
+}
+
+func (s *taskStore) advance(now time.Time) {
+	s.Lock()
+	defer s.Unlock()
+	for _, task := range s.tasks {
+		task.settle(now)
+	}
+}
+
+// reset forgets every task and cancels the transfers still running, which
+// then finish without effect.
+func (s *taskStore) reset() {
+	s.Lock()
+	defer s.Unlock()
+	s.cancel()
+	s.nextID = 1
+	s.tasks = map[string]*mockTask{}
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+}
+
+func (s *taskStore) list() []string {
+	s.Lock()
+	defer s.Unlock()
+	ids := make([]string, 0, len(s.tasks))
+	for id := range s.tasks {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		left, _ := strconv.Atoi(ids[i])
+		right, _ := strconv.Atoi(ids[j])
+		return left < right
+	})
+	return ids
+}
+
+func (t *mockTask) settle(now time.Time) {
+	if t.transferred && t.err == nil && t.apply != nil && now.Sub(t.transferredAt) >= t.applyDuration {
+		t.apply()
+		t.apply = nil
+	}
+}
+
+func (t *mockTask) resource(now time.Time) Task {
+	task := Task{
+		ODataContext: "/redfish/v1/$metadata#Task.Task",
+		ODataType:    "#Task.v1_7_3.Task",
+		ODataID:      "/redfish/v1/TaskService/Tasks/" + t.id,
+		ID:           t.id,
+		Name:         t.name,
+		TaskState:    "Running",
+		TaskStatus:   "OK",
+		StartTime:    t.startedAt.UTC().Format(time.RFC3339),
+		TaskMonitor:  "/redfish/v1/TaskService/TaskMonitors/" + t.id,
+		Messages:     []Message{registryMessage("TaskEvent", "TaskStarted", t.id)},
+	}
+
+	switch {
+	case !t.transferred:
+	case t.err != nil:
+		task.TaskState = "Exception"
+		task.TaskStatus = "Critical"
+		task.PercentComplete = 100
+		task.EndTime = t.transferredAt.UTC().Format(time.RFC3339)
+		failure := baseMessage("GeneralError")
+		failure.Resolution = t.err.Error()
+		task.Messages = append(task.Messages, failure, registryMessage("TaskEvent", "TaskAborted", t.id))
+	case now.Sub(t.transferredAt) >= t.applyDuration:
+		task.TaskState = "Completed"
+		task.PercentComplete = 100
+		task.EndTime = t.transferredAt.Add(t.applyDuration).UTC().Format(time.RFC3339)
+		task.Messages = append(task.Messages, registryMessage("TaskEvent", "TaskCompletedOK", t.id))
+	default:
+		task.PercentComplete = int(100 * now.Sub(t.transferredAt) / t.applyDuration)
+	}
+	return task
+}
+
+func taskFinished(task Task) bool {
+	return task.TaskState == "Completed" || task.TaskState == "Exception"
+}
+
+func (b *mockBMC) getTaskService(c *gin.Context) {
+	c.Header("OData-Version", "4.0")
+	taskService := TaskService{
+		ODataContext:                 "/redfish/v1/$metadata#TaskService.TaskService",
+		ODataType:                    "#TaskService.v1_2_1.TaskService",
+		ODataID:                      "/redfish/v1/TaskService",
+		ID:                           "TaskService",
+		Name:                         "Task Service",
+		ServiceEnabled:               true,
+		DateTime:                     b.now().UTC().Format(time.RFC3339),
+		CompletedTaskOverWritePolicy: "Manual",
+		Tasks:                        Link{ODataID: "/redfish/v1/TaskService/Tasks"},
+		Status:                       Status{State: "Enabled", Health: "OK"},
+	}
+	c.JSON(http.StatusOK, taskService)
+}
+
+func (b *mockBMC) getTasksCollection(c *gin.Context) {
+	c.Header("OData-Version", "4.0")
+	ids := b.tasks.list()
+	members := make([]Link, 0, len(ids))
+	for _, id := range ids {
+		members = append(members, Link{ODataID: "/redfish/v1/TaskService/Tasks/" + id})
+	}
+	collection := Collection{
+		ODataContext: "/redfish/v1/$metadata#TaskCollection.TaskCollection",
+		ODataType:    "#TaskCollection.TaskCollection",
+		ODataID:      "/redfish/v1/TaskService/Tasks",
+		Name:         "Task Collection",
+		MembersCount: len(members),
+		Members:      members,
+	}
+	c.JSON(http.StatusOK, collection)
+}
+
+func (b *mockBMC) getTask(c *gin.Context) {
+	c.Header("OData-Version", "4.0")
+	task, ok := b.tasks.get(c.Param("id"), b.now())
+	if !ok {
+		resourceNotFound(c, "Task", c.Param("id"))
+		return
+	}
+	c.JSON(http.StatusOK, task)
+}
+
+func (b *mockBMC) getTaskMonitor(c *gin.Context) {
+	c.Header("OData-Version", "4.0")
+	task, ok := b.tasks.get(c.Param("id"), b.now())
+	if !ok {
+		resourceNotFound(c, "Task", c.Param("id"))
+		return
+	}
+	if !taskFinished(task) {
+		c.Header("Location", task.TaskMonitor)
+		c.JSON(http.StatusAccepted, task)
+		return
+	}
+	c.JSON(http.StatusOK, task)
+}
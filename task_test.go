@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func waitForTask(t *testing.T, router http.Handler, location string) Task {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		request := httptest.NewRequest(http.MethodGet, location, nil)
+		request.SetBasicAuth(defaultConfig().Authentication.Username, defaultConfig().Authentication.Password)
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+		// Task monitors answer 202 Accepted until the task has finished.
+		if recorder.Code != http.StatusOK && recorder.Code != http.StatusAccepted {
+			t.Fatalf("get task status = %d, want %d", recorder.Code, http.StatusOK)
+		}
+		var task Task
+		if err := json.Unmarshal(recorder.Body.Bytes(), &task); err != nil {
+			t.Fatalf("decode task: %v", err)
+		}
+		if taskFinished(task) {
+			return task
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("task %s did not finish: %#v", task.ID, task)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestSimpleUpdateTasks(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	config := defaultConfig()
+	config.UpdateService.UpdateDurationSeconds = 0
+	bmc := newTestBMC(t, config)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/bios-2.0.0.bin" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte("firmware"))
+	}))
+	defer server.Close()
+	router := newRouter(bmc)
+
+	tests := []struct {
+		imageURI string
+		state    string
+	}{
+		{imageURI: server.URL + "/bios-2.0.0.bin", state: "Completed"},
+		{imageURI: server.URL + "/missing.bin", state: "Exception"},
+	}
+	locations := map[string]bool{}
+	for _, test := range tests {
+		request := httptest.NewRequest(http.MethodPost, "/redfish/v1/UpdateService/Actions/UpdateService.SimpleUpdate",
+			strings.NewReader(`{"ImageURI":"`+test.imageURI+`"}`))
+		request.SetBasicAuth(bmc.config.Authentication.Username, bmc.config.Authentication.Password)
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+		if recorder.Code != http.StatusAccepted {
+			t.Fatalf("SimpleUpdate status = %d, want %d", recorder.Code, http.StatusAccepted)
+		}
+		location := recorder.Header().Get("Location")
+		if !strings.HasPrefix(location, "/redfish/v1/TaskService/TaskMonitors/") {
+			t.Fatalf("SimpleUpdate Location = %q, want a task monitor", location)
+		}
+		if locations[location] {
+			t.Fatalf("SimpleUpdate reused task %q", location)
+		}
+		locations[location] = true
+
+		task := waitForTask(t, router, location)
+		if task.TaskState != test.state || task.PercentComplete != 100 || task.EndTime == "" {
+			t.Fatalf("task for %s = %#v, want %s", test.imageURI, task, test.state)
+		}
+	}
+}
+
+func TestTaskProgress(t *testing.T) {
+	store := newTaskStore(newMockClock(ClockConfig{}))
+	start := time.Now()
+	id := store.start("Test", 10*time.Second, start, func(context.Context) (func(), error) { return nil, nil })
+	deadline := time.Now().Add(5 * time.Second)
+	for task, _ := store.get(id, start.Add(time.Hour)); !taskFinished(task); task, _ = store.get(id, start.Add(time.Hour)) {
+		if time.Now().After(deadline) {
+			t.Fatal("transfer did not finish")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	store.Lock()
+	transferredAt := store.tasks[id].transferredAt
+	store.Unlock()
+	task, _ := store.get(id, transferredAt.Add(5*time.Second))
+	if task.TaskState != "Running" || task.PercentComplete != 50 {
+		t.Fatalf("halfway task = %s %d%%, want Running 50%%", task.TaskState, task.PercentComplete)
+	}
+	task, _ = store.get(id, transferredAt.Add(10*time.Second))
+	if task.TaskState != "Completed" {
+		t.Fatalf("finished task state = %s, want Completed", task.TaskState)
+	}
+
+	failed := store.start("Test", 0, start, func(context.Context) (func(), error) { return nil, errors.New("unreachable") })
+	deadline = time.Now().Add(5 * time.Second)
+	for task, _ = store.get(failed, time.Now()); !taskFinished(task); task, _ = store.get(failed, time.Now()) {
+		if time.Now().After(deadline) {
+			t.Fatal("failed transfer did not finish")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if task.TaskState != "Exception" || task.TaskStatus != "Critical" {
+		t.Fatalf("failed task = %s/%s, want Exception/Critical", task.TaskState, task.TaskStatus)
+	}
+}
+
+func TestTaskStoreResetCancelsTransfers(t *testing.T) {
+	store := newTaskStore(newMockClock(ClockConfig{}))
+	cancelled := make(chan error)
+	store.start("Test", 0, time.Now(), func(ctx context.Context) (func(), error) {
+		<-ctx.Done()
+		cancelled <- ctx.Err()
+		return nil, ctx.Err()
+	})
+	store.reset()
+	select {
+	case err := <-cancelled:
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("transfer context error = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("reset did not cancel the transfer")
+	}
+	id := store.start("Test", 0, time.Now(), func(ctx context.Context) (func(), error) { return nil, ctx.Err() })
+	deadline := time.Now().Add(5 * time.Second)
+	for task, _ := store.get(id, time.Now()); !taskFinished(task); task, _ = store.get(id, time.Now()) {
+		if time.Now().After(deadline) {
+			t.Fatal("transfer after reset did not finish")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if task, _ := store.get(id, time.Now()); task.TaskState != "Completed" {
+		t.Fatalf("task after reset = %s, want Completed", task.TaskState)
+	}
+}
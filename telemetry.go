@@ -0,0 +1,856 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TelemetryConfig sets the sensor readings of a chassis while its systems
+// are on. Readings wander randomly within DriftPercent of these baselines.
+// While every system in the chassis is off, fans, voltages, and power read 0
+// and temperatures settle at AmbientCelsius.
+type TelemetryConfig struct {
+	DriftPercent       float64             `json:"drift_percent"`
+	AmbientCelsius     float64             `json:"ambient_celsius"`
+	PowerConsumedWatts float64             `json:"power_consumed_watts"`
+	Fans               []FanConfig         `json:"fans"`
+	Temperatures       []TemperatureConfig `json:"temperatures"`
+	Voltages           []VoltageConfig     `json:"voltages"`
+	PowerSupplies      []PowerSupplyConfig `json:"power_supplies"`
+}
+
+type FanConfig struct {
+	Name string  `json:"name"`
+	RPM  float64 `json:"rpm"`
+}
+
+type TemperatureConfig struct {
+	Name                   string  `json:"name"`
+	Celsius                float64 `json:"celsius"`
+	UpperThresholdCritical float64 `json:"upper_threshold_critical"`
+	PhysicalContext        string  `json:"physical_context"`
+}
+
+type VoltageConfig struct {
+	Name  string  `json:"name"`
+	Volts float64 `json:"volts"`
+}
+
+type PowerSupplyConfig struct {
+	Name             string  `json:"name"`
+	CapacityWatts    float64 `json:"capacity_watts"`
+	LineInputVoltage float64 `json:"line_input_voltage"`
+}
+
+func defaultTelemetry() TelemetryConfig {
+	return TelemetryConfig{
+		DriftPercent:       5,
+		AmbientCelsius:     24,
+		PowerConsumedWatts: 320,
+		Fans: []FanConfig{
+			{Name: "System Fan 1", RPM: 8400},
+			{Name: "System Fan 2", RPM: 8400},
+			{Name: "System Fan 3", RPM: 8200},
+			{Name: "System Fan 4", RPM: 8200},
+		},
+		Temperatures: []TemperatureConfig{
+			{Name: "Inlet Temp", Celsius: 24, UpperThresholdCritical: 42, PhysicalContext: "Intake"},
+			{Name: "CPU1 Temp", Celsius: 55, UpperThresholdCritical: 95, PhysicalContext: "CPU"},
+			{Name: "CPU2 Temp", Celsius: 53, UpperThresholdCritical: 95, PhysicalContext: "CPU"},
+			{Name: "System Board Temp", Celsius: 38, UpperThresholdCritical: 80, PhysicalContext: "SystemBoard"},
+			{Name: "Exhaust Temp", Celsius: 40, UpperThresholdCritical: 75, PhysicalContext: "Exhaust"},
+		},
+		Voltages: []VoltageConfig{
+			{Name: "12V", Volts: 12},
+			{Name: "5V", Volts: 5},
+			{Name: "3.3V", Volts: 3.3},
+		},
+		PowerSupplies: []PowerSupplyConfig{
+			{Name: "PSU1", CapacityWatts: 800, LineInputVoltage: 230},
+			{Name: "PSU2", CapacityWatts: 800, LineInputVoltage: 230},
+		},
+	}
+}
+
+// totalPowerSensorID names the Sensor that reports the chassis power draw.
+const totalPowerSensorID = "TotalPower"
+
+func (t TelemetryConfig) validate() error {
+	if t.DriftPercent < 0 || t.DriftPercent > 50 {
+		return errors.New("drift_percent must be between 0 and 50")
+	}
+	if t.PowerConsumedWatts < 0 {
+		return errors.New("power_consumed_watts must not be negative")
+	}
+	ids := map[string]bool{totalPowerSensorID: true}
+	checkName := func(section string, i int, name string) error {
+		id := sensorID(name)
+		if id == "" {
+			return fmt.Errorf("%s[%d].name must contain a letter or digit", section, i)
+		}
+		if ids[id] {
+			return fmt.Errorf("%s[%d].name %q is used by another sensor", section, i, name)
+		}
+		ids[id] = true
+		return nil
+	}
+	for i, fan := range t.Fans {
+		if err := checkName("fans", i, fan.Name); err != nil {
+			return err
+		}
+		if fan.RPM < 0 {
+			return fmt.Errorf("fans[%d].rpm must not be negative", i)
+		}
+	}
+	for i, temperature := range t.Temperatures {
+		if err := checkName("temperatures", i, temperature.Name); err != nil {
+			return err
+		}
+	}
+	for i, voltage := range t.Voltages {
+		if err := checkName("voltages", i, voltage.Name); err != nil {
+			return err
+		}
+	}
+	for i, supply := range t.PowerSupplies {
+		if err := checkName("power_supplies", i, supply.Name); err != nil {
+			return err
+		}
+		if supply.CapacityWatts < 0 {
+			return fmt.Errorf("power_supplies[%d].capacity_watts must not be negative", i)
+		}
+	}
+	return nil
+}
+
+// sensorID turns a configured sensor name such as "CPU1 Temp" into a
+// resource ID such as "CPU1Temp".
+func sensorID(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			return r
+		}
+		return -1
+	}, name)
+}
+
+type Thermal struct {
+	ODataContext string               `json:"@odata.context"`
+	ODataType    string               `json:"@odata.type"`
+	ODataID      string               `json:"@odata.id"`
+	ID           string               `json:"Id"`
+	Name         string               `json:"Name"`
+	Fans         []ThermalFan         `json:"Fans"`
+	Temperatures []ThermalTemperature `json:"Temperatures"`
+	Status       Status               `json:"Status"`
+}
+
+type ThermalFan struct {
+	ODataID      string `json:"@odata.id"`
+	MemberID     string `json:"MemberId"`
+	Name         string `json:"Name"`
+	Reading      int    `json:"Reading"`
+	ReadingUnits string `json:"ReadingUnits"`
+	Status       Status `json:"Status"`
+}
+
+type ThermalTemperature struct {
+	ODataID                string  `json:"@odata.id"`
+	MemberID               string  `json:"MemberId"`
+	Name                   string  `json:"Name"`
+	ReadingCelsius         float64 `json:"ReadingCelsius"`
+	UpperThresholdCritical float64 `json:"UpperThresholdCritical"`
+	PhysicalContext        string  `json:"PhysicalContext"`
+	Status                 Status  `json:"Status"`
+}
+
+type Power struct {
+	ODataContext  string             `json:"@odata.context"`
+	ODataType     string             `json:"@odata.type"`
+	ODataID       string             `json:"@odata.id"`
+	ID            string             `json:"Id"`
+	Name          string             `json:"Name"`
+	PowerControl  []PowerControl     `json:"PowerControl"`
+	Voltages      []PowerVoltage     `json:"Voltages"`
+	PowerSupplies []PowerSupplyEntry `json:"PowerSupplies"`
+	Redundancy    []PowerRedundancy  `json:"Redundancy"`
+}
+
+type PowerControl struct {
+	ODataID            string       `json:"@odata.id"`
+	MemberID           string       `json:"MemberId"`
+	Name               string       `json:"Name"`
+	PowerConsumedWatts float64      `json:"PowerConsumedWatts"`
+	PowerCapacityWatts float64      `json:"PowerCapacityWatts"`
+	PowerMetrics       PowerMetrics `json:"PowerMetrics"`
+	Status             Status       `json:"Status"`
+}
+
+type PowerMetrics struct {
+	IntervalInMin        int     `json:"IntervalInMin"`
+	MinConsumedWatts     float64 `json:"MinConsumedWatts"`
+	MaxConsumedWatts     float64 `json:"MaxConsumedWatts"`
+	AverageConsumedWatts float64 `json:"AverageConsumedWatts"`
+}
+
+type PowerVoltage struct {
+	ODataID         string  `json:"@odata.id"`
+	MemberID        string  `json:"MemberId"`
+	Name            string  `json:"Name"`
+	ReadingVolts    float64 `json:"ReadingVolts"`
+	PhysicalContext string  `json:"PhysicalContext"`
+	Status          Status  `json:"Status"`
+}
+
+type PowerSupplyEntry struct {
+	ODataID              string  `json:"@odata.id"`
+	MemberID             string  `json:"MemberId"`
+	Name                 string  `json:"Name"`
+	PowerSupplyType      string  `json:"PowerSupplyType"`
+	PowerCapacityWatts   float64 `json:"PowerCapacityWatts"`
+	LastPowerOutputWatts float64 `json:"LastPowerOutputWatts"`
+	LineInputVoltage     float64 `json:"LineInputVoltage"`
+	Status               Status  `json:"Status"`
+}
+
+type PowerRedundancy struct {
+	ODataID         string `json:"@odata.id"`
+	MemberID        string `json:"MemberId"`
+	Name            string `json:"Name"`
+	Mode            string `json:"Mode"`
+	MaxNumSupported int    `json:"MaxNumSupported"`
+	MinNumNeeded    int    `json:"MinNumNeeded"`
+	RedundancySet   []Link `json:"RedundancySet"`
+	Status          Status `json:"Status"`
+}
+
+type ThermalSubsystem struct {
+	ODataContext string `json:"@odata.context"`
+	ODataType    string `json:"@odata.type"`
+	ODataID      string `json:"@odata.id"`
+	ID           string `json:"Id"`
+	Name         string `json:"Name"`
+	Fans         Link   `json:"Fans"`
+	Status       Status `json:"Status"`
+}
+
+type Fan struct {
+	ODataContext string      `json:"@odata.context"`
+	ODataType    string      `json:"@odata.type"`
+	ODataID      string      `json:"@odata.id"`
+	ID           string      `json:"Id"`
+	Name         string      `json:"Name"`
+	SpeedPercent FanSpeedRPM `json:"SpeedPercent"`
+	Status       Status      `json:"Status"`
+}
+
+type FanSpeedRPM struct {
+	DataSourceURI string `json:"DataSourceUri"`
+	SpeedRPM      int    `json:"SpeedRPM"`
+}
+
+type PowerSubsystem struct {
+	ODataContext          string           `json:"@odata.context"`
+	ODataType             string           `json:"@odata.type"`
+	ODataID               string           `json:"@odata.id"`
+	ID                    string           `json:"Id"`
+	Name                  string           `json:"Name"`
+	CapacityWatts         float64          `json:"CapacityWatts"`
+	PowerSupplies         Link             `json:"PowerSupplies"`
+	PowerSupplyRedundancy []RedundantGroup `json:"PowerSupplyRedundancy,omitempty"`
+	Status                Status           `json:"Status"`
+}
+
+type RedundantGroup struct {
+	RedundancyType      string `json:"RedundancyType"`
+	MaxSupportedInGroup int    `json:"MaxSupportedInGroup"`
+	MinNeededInGroup    int    `json:"MinNeededInGroup"`
+	RedundancyGroup     []Link `json:"RedundancyGroup"`
+	Status              Status `json:"Status"`
+}
+
+type PowerSupply struct {
+	ODataContext       string  `json:"@odata.context"`
+	ODataType          string  `json:"@odata.type"`
+	ODataID            string  `json:"@odata.id"`
+	ID                 string  `json:"Id"`
+	Name               string  `json:"Name"`
+	PowerSupplyType    string  `json:"PowerSupplyType"`
+	PowerCapacityWatts float64 `json:"PowerCapacityWatts"`
+	LineInputStatus    string  `json:"LineInputStatus"`
+	Status             Status  `json:"Status"`
+}
+
+type Sensor struct {
+	ODataContext    string            `json:"@odata.context"`
+	ODataType       string            `json:"@odata.type"`
+	ODataID         string            `json:"@odata.id"`
+	ID              string            `json:"Id"`
+	Name            string            `json:"Name"`
+	Reading         float64           `json:"Reading"`
+	ReadingUnits    string            `json:"ReadingUnits"`
+	ReadingType     string            `json:"ReadingType"`
+	PhysicalContext string            `json:"PhysicalContext"`
+	Thresholds      *SensorThresholds `json:"Thresholds,omitempty"`
+	Status          Status            `json:"Status"`
+}
+
+type SensorThresholds struct {
+	UpperCritical SensorThreshold `json:"UpperCritical"`
+}
+
+type SensorThreshold struct {
+	Reading float64 `json:"Reading"`
+}
+
+// sensorReading is one reading computed from a chassis's telemetry config.
+type sensorReading struct {
+	id              string
+	name            string
+	readingType     string
+	units           string
+	physicalContext string
+	reading         float64
+	upperCritical   float64
+	health          string
+}
+
+type chassisReadings struct {
+	poweredOn     bool
+	fans          []sensorReading
+	temperatures  []sensorReading
+	voltages      []sensorReading
+	supplies      []sensorReading
+	power         sensorReading
+	capacityWatts float64
+	// redundancyHealth is the Health of the N+1 power supply redundancy
+	// group, or empty for a chassis with fewer than two supplies.
+	redundancyHealth string
+}
+
+func (r chassisReadings) sensors() []sensorReading {
+	sensors := append([]sensorReading{}, r.temperatures...)
+	sensors = append(sensors, r.fans...)
+	sensors = append(sensors, r.voltages...)
+	return append(sensors, r.power)
+}
+
+// sensorDrift keeps a bounded random walk per sensor. Each elapsed second
+// moves every offset a little, within ±limit of the baseline.
+type sensorDrift struct {
+	sync.Mutex
+	limit    float64
+	lastStep time.Time
+	offsets  map[string]float64
+}
+
+// maxDriftSteps caps how many seconds of drift one read catches up on.
+const maxDriftSteps = 60
+
+func newSensorDrift(percent float64, now time.Time) *sensorDrift {
+	return &sensorDrift{limit: percent / 100, lastStep: now, offsets: map[string]float64{}}
+}
+
+// factors advances the walk to now and returns each sensor's multiplier.
+func (d *sensorDrift) factors(ids []string, now time.Time) map[string]float64 {
+	d.Lock()
+	defer d.Unlock()
+	steps := min(int(now.Sub(d.lastStep)/time.Second), maxDriftSteps)
+	if steps > 0 {
+		d.lastStep = now
+	}
+	factors := make(map[string]float64, len(ids))
+	for _, id := range ids {
+		offset := d.offsets[id]
+		for i := 0; i < steps; i++ {
+			offset += (rand.Float64()*2 - 1) * d.limit / 4
+			offset = math.Max(-d.limit, math.Min(d.limit, offset))
+		}
+		d.offsets[id] = offset
+		factors[id] = 1 + offset
+	}
+	return factors
+}
+
+// reset puts every sensor back on its baseline.
+func (d *sensorDrift) reset(now time.Time) {
+	d.Lock()
+	defer d.Unlock()
+	d.lastStep = now
+	d.offsets = map[string]float64{}
+}
+
+func newSensorDrifts(chassis []ChassisConfig, now time.Time) map[string]*sensorDrift {
+	drifts := make(map[string]*sensorDrift, len(chassis))
+	for _, member := range chassis {
+		drifts[member.ID] = newSensorDrift(member.Telemetry.DriftPercent, now)
+	}
+	return drifts
+}
+
+func roundTo(value float64, places int) float64 {
+	scale := math.Pow(10, float64(places))
+	return math.Round(value*scale) / scale
+}
+
+// chassisPoweredOn reports whether any system in the chassis is running. A
+// chassis without systems is always powered.
+func (b *mockBMC) chassisPoweredOn(chassisID string, now time.Time) bool {
+	hasSystems := false
+	for _, system := range b.config.Systems {
+		if system.ChassisID != chassisID {
+			continue
+		}
+		hasSystems = true
+		state := b.systemStates[system.ID]
+		state.Lock()
+		state.advancePower(now)
+		running := state.powerState == "On" || state.powerState == "PoweringOff"
+		state.Unlock()
+		if running {
+			return true
+		}
+	}
+	return !hasSystems
+}
+
+func (b *mockBMC) chassisReadings(chassis ChassisConfig, now time.Time) chassisReadings {
+	telemetry := chassis.Telemetry
+	readings := chassisReadings{poweredOn: b.chassisPoweredOn(chassis.ID, now)}
+	chassisURI := "/redfish/v1/Chassis/" + chassis.ID
+	ids := []string{totalPowerSensorID}
+	for _, fan := range telemetry.Fans {
+		ids = append(ids, sensorID(fan.Name))
+	}
+	for _, temperature := range telemetry.Temperatures {
+		ids = append(ids, sensorID(temperature.Name))
+	}
+	for _, voltage := range telemetry.Voltages {
+		ids = append(ids, sensorID(voltage.Name))
+	}
+	factors := b.sensorDrifts[chassis.ID].factors(ids, now)
+	// running scales a reading that only exists while the chassis is on.
+	running := func(id string, baseline float64) float64 {
+		if !readings.poweredOn {
+			return 0
+		}
+		return baseline * factors[id]
+	}
+
+	// A failed fan stops and an overheating sensor reads above its critical
+	// threshold whatever the power state.
+	for _, fan := range telemetry.Fans {
+		id := sensorID(fan.Name)
+		reading := sensorReading{
+			id: id, name: fan.Name, readingType: "Rotational", units: "RPM", physicalContext: "Fan",
+			reading: math.Round(running(id, fan.RPM)), health: "OK",
+		}
+		if b.faults.has(faultFanFailed, chassisURI+"/ThermalSubsystem/Fans/"+id) {
+			reading.reading, reading.health = 0, faultHealth[faultFanFailed]
+		}
+		readings.fans = append(readings.fans, reading)
+	}
+	for _, temperature := range telemetry.Temperatures {
+		id := sensorID(temperature.Name)
+		celsius := temperature.Celsius
+		if !readings.poweredOn {
+			celsius = math.Min(celsius, telemetry.AmbientCelsius)
+		}
+		reading := sensorReading{
+			id: id, name: temperature.Name, readingType: "Temperature", units: "Cel", physicalContext: temperature.PhysicalContext,
+			reading: roundTo(celsius*factors[id], 1), upperCritical: temperature.UpperThresholdCritical, health: "OK",
+		}
+		if b.faults.has(faultOverTemperature, chassisURI+"/Sensors/"+id) {
+			reading.reading = math.Max(temperature.Celsius, temperature.UpperThresholdCritical) + overTemperatureMargin
+			reading.health = faultHealth[faultOverTemperature]
+		}
+		readings.temperatures = append(readings.temperatures, reading)
+	}
+	for _, voltage := range telemetry.Voltages {
+		id := sensorID(voltage.Name)
+		readings.voltages = append(readings.voltages, sensorReading{
+			id: id, name: voltage.Name, readingType: "Voltage", units: "V", physicalContext: "VoltageRegulator",
+			reading: roundTo(running(id, voltage.Volts), 2), health: "OK",
+		})
+	}
+
+	consumed := math.Round(running(totalPowerSensorID, telemetry.PowerConsumedWatts))
+	readings.power = sensorReading{
+		id: totalPowerSensorID, name: "Total Power", readingType: "Power", units: "W", physicalContext: "Chassis",
+		reading: consumed, health: "OK",
+	}
+	// The supplies that still work share the load of a failed one.
+	working := 0
+	for _, supply := range telemetry.PowerSupplies {
+		id := sensorID(supply.Name)
+		reading := sensorReading{
+			id: id, name: supply.Name, readingType: "Power", units: "W", physicalContext: "PowerSupply", health: "OK",
+		}
+		if b.faults.has(faultPowerSupplyRedundancyLost, chassisURI+"/PowerSubsystem/PowerSupplies/"+id) {
+			reading.health = faultHealth[faultPowerSupplyRedundancyLost]
+		} else {
+			working++
+		}
+		readings.capacityWatts += supply.CapacityWatts
+		readings.supplies = append(readings.supplies, reading)
+	}
+	for i := range readings.supplies {
+		if readings.supplies[i].health == "OK" {
+			readings.supplies[i].reading = math.Round(consumed / float64(working))
+		}
+	}
+	// Losing one supply loses redundancy; losing more leaves too few.
+	if supplies := len(readings.supplies); supplies >= 2 {
+		switch {
+		case working == supplies:
+			readings.redundancyHealth = "OK"
+		case working >= supplies-1:
+			readings.redundancyHealth = "Warning"
+		default:
+			readings.redundancyHealth = "Critical"
+		}
+	}
+	return readings
+}
+
+func (b *mockBMC) lookupChassisReadings(c *gin.Context) (ChassisConfig, chassisReadings, bool) {
+	chassis, ok := b.findChassis(c.Param("id"))
+	if !ok {
+		resourceNotFound(c, "Chassis", c.Param("id"))
+		return ChassisConfig{}, chassisReadings{}, false
+	}
+	return chassis, b.chassisReadings(chassis, b.now()), true
+}
+
+func (b *mockBMC) getThermal(c *gin.Context) {
+	c.Header("OData-Version", "4.0")
+	chassis, readings, ok := b.lookupChassisReadings(c)
+	if !ok {
+		return
+	}
+	uri := "/redfish/v1/Chassis/" + chassis.ID + "/Thermal"
+	thermal := Thermal{
+		ODataContext: "/redfish/v1/$metadata#Thermal.Thermal",
+		ODataType:    "#Thermal.v1_7_1.Thermal",
+		ODataID:      uri,
+		ID:           "Thermal",
+		Name:         "Thermal",
+		Fans:         make([]ThermalFan, 0, len(readings.fans)),
+		Temperatures: make([]ThermalTemperature, 0, len(readings.temperatures)),
+		Status:       Status{State: "Enabled", Health: "OK", HealthRollup: b.faults.rollupOf(chassis.ID, faultFanFailed, faultOverTemperature)},
+	}
+	for i, fan := range readings.fans {
+		thermal.Fans = append(thermal.Fans, ThermalFan{
+			ODataID:      uri + "#/Fans/" + strconv.Itoa(i),
+			MemberID:     strconv.Itoa(i),
+			Name:         fan.name,
+			Reading:      int(fan.reading),
+			ReadingUnits: "RPM",
+			Status:       Status{State: "Enabled", Health: fan.health},
+		})
+	}
+	for i, temperature := range readings.temperatures {
+		thermal.Temperatures = append(thermal.Temperatures, ThermalTemperature{
+			ODataID:                uri + "#/Temperatures/" + strconv.Itoa(i),
+			MemberID:               strconv.Itoa(i),
+			Name:                   temperature.name,
+			ReadingCelsius:         temperature.reading,
+			UpperThresholdCritical: temperature.upperCritical,
+			PhysicalContext:        temperature.physicalContext,
+			Status:                 Status{State: "Enabled", Health: temperature.health},
+		})
+	}
+	c.JSON(http.StatusOK, thermal)
+}
+
+func (b *mockBMC) getPower(c *gin.Context) {
+	c.Header("OData-Version", "4.0")
+	chassis, readings, ok := b.lookupChassisReadings(c)
+	if !ok {
+		return
+	}
+	uri := "/redfish/v1/Chassis/" + chassis.ID + "/Power"
+	baseline := chassis.Telemetry.PowerConsumedWatts
+	metrics := PowerMetrics{IntervalInMin: 1}
+	if readings.poweredOn {
+		drift := chassis.Telemetry.DriftPercent / 100
+		metrics.MinConsumedWatts = math.Round(baseline * (1 - drift))
+		metrics.MaxConsumedWatts = math.Round(baseline * (1 + drift))
+		metrics.AverageConsumedWatts = math.Round(baseline)
+	}
+	power := Power{
+		ODataContext: "/redfish/v1/$metadata#Power.Power",
+		ODataType:    "#Power.v1_7_1.Power",
+		ODataID:      uri,
+		ID:           "Power",
+		Name:         "Power",
+		PowerControl: []PowerControl{{
+			ODataID:            uri + "#/PowerControl/0",
+			MemberID:           "0",
+			Name:               "Chassis Power Control",
+			PowerConsumedWatts: readings.power.reading,
+			PowerCapacityWatts: readings.capacityWatts,
+			PowerMetrics:       metrics,
+			Status:             Status{State: "Enabled", Health: "OK"},
+		}},
+		Voltages:      make([]PowerVoltage, 0, len(readings.voltages)),
+		PowerSupplies: make([]PowerSupplyEntry, 0, len(readings.supplies)),
+		Redundancy:    []PowerRedundancy{},
+	}
+	for i, voltage := range readings.voltages {
+		power.Voltages = append(power.Voltages, PowerVoltage{
+			ODataID:         uri + "#/Voltages/" + strconv.Itoa(i),
+			MemberID:        strconv.Itoa(i),
+			Name:            voltage.name,
+			ReadingVolts:    voltage.reading,
+			PhysicalContext: voltage.physicalContext,
+			Status:          Status{State: "Enabled", Health: voltage.health},
+		})
+	}
+	for i, supply := range chassis.Telemetry.PowerSupplies {
+		power.PowerSupplies = append(power.PowerSupplies, PowerSupplyEntry{
+			ODataID:              uri + "#/PowerSupplies/" + strconv.Itoa(i),
+			MemberID:             strconv.Itoa(i),
+			Name:                 supply.Name,
+			PowerSupplyType:      "AC",
+			PowerCapacityWatts:   supply.CapacityWatts,
+			LastPowerOutputWatts: readings.supplies[i].reading,
+			LineInputVoltage:     supply.LineInputVoltage,
+			Status:               Status{State: "Enabled", Health: readings.supplies[i].health},
+		})
+	}
+	if readings.redundancyHealth != "" {
+		set := make([]Link, 0, len(power.PowerSupplies))
+		for _, supply := range power.PowerSupplies {
+			set = append(set, Link{ODataID: supply.ODataID})
+		}
+		power.Redundancy = append(power.Redundancy, PowerRedundancy{
+			ODataID:         uri + "#/Redundancy/0",
+			MemberID:        "0",
+			Name:            "PowerSupply Redundancy",
+			Mode:            "N+m",
+			MaxNumSupported: len(set),
+			MinNumNeeded:    len(set) - 1,
+			RedundancySet:   set,
+			Status:          Status{State: "Enabled", Health: readings.redundancyHealth},
+		})
+	}
+	c.JSON(http.StatusOK, power)
+}
+
+func (b *mockBMC) getThermalSubsystem(c *gin.Context) {
+	c.Header("OData-Version", "4.0")
+	chassis, ok := b.findChassis(c.Param("id"))
+	if !ok {
+		resourceNotFound(c, "Chassis", c.Param("id"))
+		return
+	}
+	uri := "/redfish/v1/Chassis/" + chassis.ID + "/ThermalSubsystem"
+	c.JSON(http.StatusOK, ThermalSubsystem{
+		ODataContext: "/redfish/v1/$metadata#ThermalSubsystem.ThermalSubsystem",
+		ODataType:    "#ThermalSubsystem.v1_3_0.ThermalSubsystem",
+		ODataID:      uri,
+		ID:           "ThermalSubsystem",
+		Name:         "Thermal Subsystem",
+		Fans:         Link{ODataID: uri + "/Fans"},
+		Status:       Status{State: "Enabled", Health: "OK", HealthRollup: b.faults.rollupOf(chassis.ID, faultFanFailed, faultOverTemperature)},
+	})
+}
+
+func (b *mockBMC) getFansCollection(c *gin.Context) {
+	c.Header("OData-Version", "4.0")
+	chassis, ok := b.findChassis(c.Param("id"))
+	if !ok {
+		resourceNotFound(c, "Chassis", c.Param("id"))
+		return
+	}
+	uri := "/redfish/v1/Chassis/" + chassis.ID + "/ThermalSubsystem/Fans"
+	members := make([]Link, 0, len(chassis.Telemetry.Fans))
+	for _, fan := range chassis.Telemetry.Fans {
+		members = append(members, Link{ODataID: uri + "/" + sensorID(fan.Name)})
+	}
+	c.JSON(http.StatusOK, Collection{
+		ODataContext: "/redfish/v1/$metadata#FanCollection.FanCollection",
+		ODataType:    "#FanCollection.FanCollection",
+		ODataID:      uri,
+		Name:         "Fan Collection",
+		MembersCount: len(members),
+		Members:      members,
+	})
+}
+
+func (b *mockBMC) getFan(c *gin.Context) {
+	c.Header("OData-Version", "4.0")
+	chassis, readings, ok := b.lookupChassisReadings(c)
+	if !ok {
+		return
+	}
+	for _, fan := range readings.fans {
+		if fan.id != c.Param("sensorID") {
+			continue
+		}
+		c.JSON(http.StatusOK, Fan{
+			ODataContext: "/redfish/v1/$metadata#Fan.Fan",
+			ODataType:    "#Fan.v1_5_0.Fan",
+			ODataID:      "/redfish/v1/Chassis/" + chassis.ID + "/ThermalSubsystem/Fans/" + fan.id,
+			ID:           fan.id,
+			Name:         fan.name,
+			SpeedPercent: FanSpeedRPM{
+				DataSourceURI: "/redfish/v1/Chassis/" + chassis.ID + "/Sensors/" + fan.id,
+				SpeedRPM:      int(fan.reading),
+			},
+			Status: Status{State: "Enabled", Health: fan.health},
+		})
+		return
+	}
+	resourceNotFound(c, "Fan", c.Param("sensorID"))
+}
+
+func (b *mockBMC) getPowerSubsystem(c *gin.Context) {
+	c.Header("OData-Version", "4.0")
+	chassis, readings, ok := b.lookupChassisReadings(c)
+	if !ok {
+		return
+	}
+	uri := "/redfish/v1/Chassis/" + chassis.ID + "/PowerSubsystem"
+	subsystem := PowerSubsystem{
+		ODataContext:  "/redfish/v1/$metadata#PowerSubsystem.PowerSubsystem",
+		ODataType:     "#PowerSubsystem.v1_1_0.PowerSubsystem",
+		ODataID:       uri,
+		ID:            "PowerSubsystem",
+		Name:          "Power Subsystem",
+		CapacityWatts: readings.capacityWatts,
+		PowerSupplies: Link{ODataID: uri + "/PowerSupplies"},
+		Status:        Status{State: "Enabled", Health: "OK", HealthRollup: b.faults.rollupUnder(uri)},
+	}
+	if readings.redundancyHealth != "" {
+		group := make([]Link, 0, len(chassis.Telemetry.PowerSupplies))
+		for _, supply := range chassis.Telemetry.PowerSupplies {
+			group = append(group, Link{ODataID: uri + "/PowerSupplies/" + sensorID(supply.Name)})
+		}
+		subsystem.PowerSupplyRedundancy = []RedundantGroup{{
+			RedundancyType:      "NPlusM",
+			MaxSupportedInGroup: len(group),
+			MinNeededInGroup:    len(group) - 1,
+			RedundancyGroup:     group,
+			Status:              Status{State: "Enabled", Health: readings.redundancyHealth},
+		}}
+	}
+	c.JSON(http.StatusOK, subsystem)
+}
+
+func (b *mockBMC) getPowerSuppliesCollection(c *gin.Context) {
+	c.Header("OData-Version", "4.0")
+	chassis, ok := b.findChassis(c.Param("id"))
+	if !ok {
+		resourceNotFound(c, "Chassis", c.Param("id"))
+		return
+	}
+	uri := "/redfish/v1/Chassis/" + chassis.ID + "/PowerSubsystem/PowerSupplies"
+	members := make([]Link, 0, len(chassis.Telemetry.PowerSupplies))
+	for _, supply := range chassis.Telemetry.PowerSupplies {
+		members = append(members, Link{ODataID: uri + "/" + sensorID(supply.Name)})
+	}
+	c.JSON(http.StatusOK, Collection{
+		ODataContext: "/redfish/v1/$metadata#PowerSupplyCollection.PowerSupplyCollection",
+		ODataType:    "#PowerSupplyCollection.PowerSupplyCollection",
+		ODataID:      uri,
+		Name:         "Power Supply Collection",
+		MembersCount: len(members),
+		Members:      members,
+	})
+}
+
+func (b *mockBMC) getPowerSupply(c *gin.Context) {
+	c.Header("OData-Version", "4.0")
+	chassis, readings, ok := b.lookupChassisReadings(c)
+	if !ok {
+		return
+	}
+	for i, supply := range chassis.Telemetry.PowerSupplies {
+		id := sensorID(supply.Name)
+		if id != c.Param("sensorID") {
+			continue
+		}
+		lineInputStatus := "Normal"
+		if readings.supplies[i].health != "OK" {
+			lineInputStatus = "LossOfInput"
+		}
+		c.JSON(http.StatusOK, PowerSupply{
+			ODataContext:       "/redfish/v1/$metadata#PowerSupply.PowerSupply",
+			ODataType:          "#PowerSupply.v1_5_0.PowerSupply",
+			ODataID:            "/redfish/v1/Chassis/" + chassis.ID + "/PowerSubsystem/PowerSupplies/" + id,
+			ID:                 id,
+			Name:               supply.Name,
+			PowerSupplyType:    "AC",
+			PowerCapacityWatts: supply.CapacityWatts,
+			LineInputStatus:    lineInputStatus,
+			Status:             Status{State: "Enabled", Health: readings.supplies[i].health},
+		})
+		return
+	}
+	resourceNotFound(c, "PowerSupply", c.Param("sensorID"))
+}
+
+func (b *mockBMC) getSensorsCollection(c *gin.Context) {
+	c.Header("OData-Version", "4.0")
+	chassis, readings, ok := b.lookupChassisReadings(c)
+	if !ok {
+		return
+	}
+	uri := "/redfish/v1/Chassis/" + chassis.ID + "/Sensors"
+	sensors := readings.sensors()
+	members := make([]Link, 0, len(sensors))
+	for _, sensor := range sensors {
+		members = append(members, Link{ODataID: uri + "/" + sensor.id})
+	}
+	c.JSON(http.StatusOK, Collection{
+		ODataContext: "/redfish/v1/$metadata#SensorCollection.SensorCollection",
+		ODataType:    "#SensorCollection.SensorCollection",
+		ODataID:      uri,
+		Name:         "Sensor Collection",
+		MembersCount: len(members),
+		Members:      members,
+	})
+}
+
+func (b *mockBMC) getSensor(c *gin.Context) {
+	c.Header("OData-Version", "4.0")
+	chassis, readings, ok := b.lookupChassisReadings(c)
+	if !ok {
+		return
+	}
+	for _, sensor := range readings.sensors() {
+		if sensor.id != c.Param("sensorID") {
+			continue
+		}
+		resource := Sensor{
+			ODataContext:    "/redfish/v1/$metadata#Sensor.Sensor",
+			ODataType:       "#Sensor.v1_7_0.Sensor",
+			ODataID:         "/redfish/v1/Chassis/" + chassis.ID + "/Sensors/" + sensor.id,
+			ID:              sensor.id,
+			Name:            sensor.name,
+			Reading:         sensor.reading,
+			ReadingUnits:    sensor.units,
+			ReadingType:     sensor.readingType,
+			PhysicalContext: sensor.physicalContext,
+			Status:          Status{State: "Enabled", Health: sensor.health},
+		}
+		if sensor.upperCritical != 0 {
+			resource.Thresholds = &SensorThresholds{UpperCritical: SensorThreshold{Reading: sensor.upperCritical}}
+		}
+		c.JSON(http.StatusOK, resource)
+		return
+	}
+	resourceNotFound(c, "Sensor", c.Param("sensorID"))
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestTelemetryFollowsPowerState(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	config := defaultConfig()
+	config.ChassisMembers[0].Telemetry.DriftPercent = 0
+	bmc := newTestBMC(t, config)
+	router := newRouter(bmc)
+	admin, password := bmc.config.Authentication.Username, bmc.config.Authentication.Password
+	get := func(path string, response any) {
+		t.Helper()
+		recorder := accountRequest(router, http.MethodGet, path, "", admin, password)
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("GET %s status = %d", path, recorder.Code)
+		}
+		if err := json.Unmarshal(recorder.Body.Bytes(), response); err != nil {
+			t.Fatalf("decode %s: %v", path, err)
+		}
+	}
+
+	var power Power
+	get("/redfish/v1/Chassis/1/Power", &power)
+	if power.PowerControl[0].PowerConsumedWatts != 320 || power.PowerControl[0].PowerCapacityWatts != 1600 ||
+		power.PowerSupplies[0].LastPowerOutputWatts != 160 || power.Voltages[0].ReadingVolts != 12 {
+		t.Fatalf("powered-on Power = %+v", power)
+	}
+	var thermal Thermal
+	get("/redfish/v1/Chassis/1/Thermal", &thermal)
+	if thermal.Fans[0].Reading != 8400 || thermal.Temperatures[1].Name != "CPU1 Temp" || thermal.Temperatures[1].ReadingCelsius != 55 {
+		t.Fatalf("powered-on Thermal = %+v", thermal)
+	}
+	var sensor Sensor
+	get("/redfish/v1/Chassis/1/Sensors/CPU1Temp", &sensor)
+	if sensor.Reading != 55 || sensor.ReadingUnits != "Cel" || sensor.Thresholds == nil || sensor.Thresholds.UpperCritical.Reading != 95 {
+		t.Fatalf("CPU1Temp sensor = %+v", sensor)
+	}
+	var fan Fan
+	get("/redfish/v1/Chassis/1/ThermalSubsystem/Fans/SystemFan1", &fan)
+	if fan.SpeedPercent.SpeedRPM != 8400 || fan.SpeedPercent.DataSourceURI != "/redfish/v1/Chassis/1/Sensors/SystemFan1" {
+		t.Fatalf("fan = %+v", fan)
+	}
+
+	if recorder := accountRequest(router, http.MethodPost, "/redfish/v1/Systems/1/Actions/ComputerSystem.Reset", `{"ResetType":"ForceOff"}`, admin, password); recorder.Code != http.StatusNoContent {
+		t.Fatalf("reset status = %d", recorder.Code)
+	}
+	power = Power{}
+	get("/redfish/v1/Chassis/1/Power", &power)
+	if power.PowerControl[0].PowerConsumedWatts != 0 || power.PowerSupplies[0].LastPowerOutputWatts != 0 || power.Voltages[0].ReadingVolts != 0 {
+		t.Fatalf("powered-off Power = %+v", power)
+	}
+	get("/redfish/v1/Chassis/1/Sensors/CPU1Temp", &sensor)
+	if sensor.Reading != 24 {
+		t.Fatalf("powered-off CPU1Temp = %v, want ambient 24", sensor.Reading)
+	}
+	var chassis Chassis
+	get("/redfish/v1/Chassis/1", &chassis)
+	if chassis.PowerState != "Off" {
+		t.Fatalf("chassis PowerState = %q", chassis.PowerState)
+	}
+}
+
+func TestSensorDriftStaysWithinBounds(t *testing.T) {
+	start := time.Now()
+	drift := newSensorDrift(5, start)
+	for second := 1; second <= 600; second++ {
+		factor := drift.factors([]string{"CPU1Temp"}, start.Add(time.Duration(second)*time.Second))["CPU1Temp"]
+		if factor < 0.95 || factor > 1.05 {
+			t.Fatalf("factor after %ds = %v, want within 5%%", second, factor)
+		}
+	}
+}
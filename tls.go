@@ -0,0 +1,132 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"time"
+)
+
+// listenOptions describes the listeners of one mock BMC. The main listener
+// serves HTTPS when tlsConfig is set; httpAddr adds a plain HTTP listener that
+// either serves the API as well or redirects to HTTPS.
+type listenOptions struct {
+	addr      string
+	tlsConfig *tls.Config
+	httpAddr  string
+	redirect  bool
+}
+
+func (o listenOptions) serve(handler http.Handler) error {
+	errs := make(chan error, 2)
+	server := &http.Server{Addr: o.addr, Handler: handler, TLSConfig: o.tlsConfig}
+	go func() {
+		if o.tlsConfig != nil {
+			errs <- fmt.Errorf("%s: %w", o.addr, server.ListenAndServeTLS("", ""))
+			return
+		}
+		errs <- fmt.Errorf("%s: %w", o.addr, server.ListenAndServe())
+	}()
+	if o.httpAddr != "" {
+		plain := handler
+		if o.redirect {
+			plain = httpsRedirect(o.addr)
+		}
+		go func() {
+			errs <- fmt.Errorf("%s: %w", o.httpAddr, (&http.Server{Addr: o.httpAddr, Handler: plain}).ListenAndServe())
+		}()
+	}
+	return <-errs
+}
+
+// httpsRedirect sends every request to the same host on the HTTPS listener at
+// httpsAddr. 308 keeps the method and body, so POSTed actions survive.
+func httpsRedirect(httpsAddr string) http.Handler {
+	_, port, _ := net.SplitHostPort(httpsAddr)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, _, err := net.SplitHostPort(r.Host)
+		if err != nil {
+			host = r.Host
+		}
+		http.Redirect(w, r, "https://"+net.JoinHostPort(host, port)+r.URL.RequestURI(), http.StatusPermanentRedirect)
+	})
+}
+
+// serverCertificate loads certFile and keyFile, or generates a self-signed
+// certificate for host when neither is given.
+func serverCertificate(certFile, keyFile, host string) (tls.Certificate, error) {
+	switch {
+	case certFile == "" && keyFile == "":
+		return selfSignedCertificate(host, time.Now())
+	case certFile == "" || keyFile == "":
+		return tls.Certificate{}, errors.New("-tls-cert and -tls-key must be used together")
+	default:
+		return tls.LoadX509KeyPair(certFile, keyFile)
+	}
+}
+
+func selfSignedCertificate(host string, now time.Time) (tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("generate TLS key: %w", err)
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("generate certificate serial: %w", err)
+	}
+
+	names := certificateNames(host)
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: names[0], Organization: []string{"Redfish Mock"}},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.AddDate(1, 0, 0),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+	}
+	for _, name := range names {
+		if ip := net.ParseIP(name); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, name)
+		}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("create self-signed certificate: %w", err)
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}, nil
+}
+
+// certificateNames lists the subject alternative names of a self-signed
+// certificate: the listen host first, then the loopback names. A wildcard
+// listen host is replaced with the machine's host name.
+func certificateNames(host string) []string {
+	if ip := net.ParseIP(host); host == "" || (ip != nil && ip.IsUnspecified()) {
+		host, _ = os.Hostname()
+	}
+	names := []string{}
+	seen := map[string]bool{}
+	for _, name := range []string{host, "localhost", "127.0.0.1", "::1"} {
+		if name != "" && !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	return names
+}
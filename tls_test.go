@@ -0,0 +1,79 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestSelfSignedCertificateCoversHost(t *testing.T) {
+	certificate, err := selfSignedCertificate("bmc.example.test", time.Now())
+	if err != nil {
+		t.Fatalf("selfSignedCertificate() error = %v", err)
+	}
+	leaf := certificate.Leaf
+	if !slices.Contains(leaf.DNSNames, "bmc.example.test") || !slices.Contains(leaf.DNSNames, "localhost") {
+		t.Fatalf("DNSNames = %v", leaf.DNSNames)
+	}
+	if len(leaf.IPAddresses) != 2 {
+		t.Fatalf("IPAddresses = %v, want the loopback addresses", leaf.IPAddresses)
+	}
+
+	certificate, err = selfSignedCertificate("10.0.0.209", time.Now())
+	if err != nil {
+		t.Fatalf("selfSignedCertificate() error = %v", err)
+	}
+	if err := certificate.Leaf.VerifyHostname("10.0.0.209"); err != nil {
+		t.Fatalf("VerifyHostname() error = %v", err)
+	}
+}
+
+func TestServerCertificateRequiresCertAndKey(t *testing.T) {
+	if _, err := serverCertificate("cert.pem", "", "localhost"); err == nil {
+		t.Fatal("serverCertificate() with only a certificate succeeded")
+	}
+}
+
+func TestServeHTTPSWithSelfSignedCertificate(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	certificate, err := serverCertificate("", "", "127.0.0.1")
+	if err != nil {
+		t.Fatalf("serverCertificate() error = %v", err)
+	}
+	bmc := newTestBMC(t, defaultConfig())
+	bmc.certificates.install(certificate)
+	server := httptest.NewUnstartedServer(newRouter(bmc))
+	server.Listener = tls.NewListener(server.Listener, bmc.certificates.tlsConfig())
+	server.Start()
+	defer server.Close()
+
+	pool := x509.NewCertPool()
+	pool.AddCert(certificate.Leaf)
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}}}
+	response, err := client.Get("https://" + server.Listener.Addr().String() + "/redfish/v1/")
+	if err != nil {
+		t.Fatalf("GET service root over HTTPS: %v", err)
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		t.Fatalf("service root status = %d", response.StatusCode)
+	}
+}
+
+func TestHTTPSRedirect(t *testing.T) {
+	request := httptest.NewRequest(http.MethodPost, "http://bmc.example.test:8080/redfish/v1/SessionService/Sessions?x=1", nil)
+	recorder := httptest.NewRecorder()
+	httpsRedirect("0.0.0.0:8443").ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusPermanentRedirect {
+		t.Fatalf("redirect status = %d", recorder.Code)
+	}
+	if location := recorder.Header().Get("Location"); location != "https://bmc.example.test:8443/redfish/v1/SessionService/Sessions?x=1" {
+		t.Fatalf("Location = %q", location)
+	}
+}
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// decodeTopology decodes the systems, chassis_members and managers arrays.
+// Each entry starts from the singular system, chassis or manager section, so
+// entries only need the fields that differ.
+func decodeTopology(contents []byte, loaded *Config) error {
+	var entries struct {
+		Systems        []json.RawMessage `json:"systems"`
+		ChassisMembers []json.RawMessage `json:"chassis_members"`
+		Managers       []json.RawMessage `json:"managers"`
+	}
+	if err := json.Unmarshal(contents, &entries); err != nil {
+		return fmt.Errorf("decode config: %w", err)
+	}
+
+	loaded.Systems = nil
+	for i, entry := range entries.Systems {
+		system := loaded.System
+		system.Oem = maps.Clone(loaded.System.Oem)
+		if err := decodeEntry(entry, &system); err != nil {
+			return fmt.Errorf("decode systems[%d]: %w", i, err)
+		}
+		loaded.Systems = append(loaded.Systems, system)
+	}
+	loaded.ChassisMembers = nil
+	for i, entry := range entries.ChassisMembers {
+		chassis := loaded.Chassis
+		if err := decodeEntry(entry, &chassis); err != nil {
+			return fmt.Errorf("decode chassis_members[%d]: %w", i, err)
+		}
+		loaded.ChassisMembers = append(loaded.ChassisMembers, chassis)
+	}
+	loaded.Managers = nil
+	for i, entry := range entries.Managers {
+		manager := loaded.Manager
+		if err := decodeEntry(entry, &manager); err != nil {
+			return fmt.Errorf("decode managers[%d]: %w", i, err)
+		}
+		loaded.Managers = append(loaded.Managers, manager)
+	}
+	return nil
+}
+
+func decodeEntry(entry json.RawMessage, target any) error {
+	decoder := json.NewDecoder(bytes.NewReader(entry))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(target)
+}
+
+// resolveTopology fills in a single system, chassis and manager from the
+// singular sections when no arrays are configured, defaults relationships to
+// the first chassis and manager, and checks that every reference resolves.
+func resolveTopology(config *Config, ids oemResourceIDs) error {
+	if len(config.Managers) == 0 {
+		manager := config.Manager
+		if manager.ID == "" {
+			manager.ID = ids.Manager
+		}
+		config.Managers = []ManagerConfig{manager}
+	}
+	if len(config.ChassisMembers) == 0 {
+		chassis := config.Chassis
+		if chassis.ID == "" {
+			chassis.ID = ids.Chassis
+		}
+		config.ChassisMembers = []ChassisConfig{chassis}
+	}
+	if len(config.Systems) == 0 {
+		system := config.System
+		if system.ID == "" {
+			system.ID = ids.System
+		}
+		config.Systems = []SystemConfig{system}
+	}
+
+	managerIDs := map[string]bool{}
+	for i, manager := range config.Managers {
+		if err := checkResourceID("managers", i, manager.ID, managerIDs); err != nil {
+			return err
+		}
+	}
+	chassisIDs := map[string]bool{}
+	for i := range config.ChassisMembers {
+		chassis := &config.ChassisMembers[i]
+		if err := checkResourceID("chassis_members", i, chassis.ID, chassisIDs); err != nil {
+			return err
+		}
+		if chassis.ManagerID == "" {
+			chassis.ManagerID = config.Managers[0].ID
+		}
+		if !managerIDs[chassis.ManagerID] {
+			return fmt.Errorf("chassis_members[%d].manager_id %q does not match a manager", i, chassis.ManagerID)
+		}
+	}
+	systemIDs := map[string]bool{}
+	for i := range config.Systems {
+		system := &config.Systems[i]
+		if err := checkResourceID("systems", i, system.ID, systemIDs); err != nil {
+			return err
+		}
+		if system.ChassisID == "" {
+			system.ChassisID = config.ChassisMembers[0].ID
+		}
+		if !chassisIDs[system.ChassisID] {
+			return fmt.Errorf("systems[%d].chassis_id %q does not match a chassis", i, system.ChassisID)
+		}
+		if system.ManagerID == "" {
+			system.ManagerID = config.Managers[0].ID
+		}
+		if !managerIDs[system.ManagerID] {
+			return fmt.Errorf("systems[%d].manager_id %q does not match a manager", i, system.ManagerID)
+		}
+	}
+	return nil
+}
+
+func checkResourceID(section string, index int, id string, seen map[string]bool) error {
+	if id == "" {
+		return fmt.Errorf("%s[%d].id is required", section, index)
+	}
+	if seen[id] {
+		return fmt.Errorf("%s[%d].id %q is used more than once", section, index, id)
+	}
+	seen[id] = true
+	return nil
+}
+
+func (b *mockBMC) findSystem(id string) (SystemConfig, bool) {
+	for _, system := range b.config.Systems {
+		if system.ID == id {
+			return system, true
+		}
+	}
+	return SystemConfig{}, false
+}
+
+func (b *mockBMC) findChassis(id string) (ChassisConfig, bool) {
+	for _, chassis := range b.config.ChassisMembers {
+		if chassis.ID == id {
+			return chassis, true
+		}
+	}
+	return ChassisConfig{}, false
+}
+
+func (b *mockBMC) findManager(id string) (ManagerConfig, bool) {
+	for _, manager := range b.config.Managers {
+		if manager.ID == id {
+			return manager, true
+		}
+	}
+	return ManagerConfig{}, false
+}
+
+func (b *mockBMC) systemLinks(match func(SystemConfig) bool) []Link {
+	links := []Link{}
+	for _, system := range b.config.Systems {
+		if match(system) {
+			links = append(links, Link{ODataID: "/redfish/v1/Systems/" + system.ID})
+		}
+	}
+	return links
+}
+
+func (b *mockBMC) chassisLinks(match func(ChassisConfig) bool) []Link {
+	links := []Link{}
+	for _, chassis := range b.config.ChassisMembers {
+		if match(chassis) {
+			links = append(links, Link{ODataID: "/redfish/v1/Chassis/" + chassis.ID})
+		}
+	}
+	return links
+}
+
+func newSystemStates(systems []SystemConfig) map[string]*mockServerState {
+	states := make(map[string]*mockServerState, len(systems))
+	for _, system := range systems {
+		state := &mockServerState{}
+		state.reset(system)
+		states[system.ID] = state
+	}
+	return states
+}
+
+// reset returns the state to how system configures it, dropping media, boot
+// overrides, and pending power transitions. The caller must hold the state
+// lock unless the state is new.
+func (s *mockServerState) reset(system SystemConfig) {
+	s.image = ""
+	s.inserted = false
+	s.writeProtected = true
+	s.bootSourceOverrideEnabled = "Disabled"
+	s.bootSourceOverrideTarget = "None"
+	s.bootSourceOverrideMode = "UEFI"
+	s.installationStatus = "Ready"
+	s.installationStartedAt = time.Time{}
+	s.powerState = system.PowerState
+	s.powerTransitions = nil
+	s.powerEvents = nil
+}
+
+// virtualMediaSystem returns the system a manager's virtual media is
+// attached to: the first system the manager manages.
+func (b *mockBMC) virtualMediaSystem(managerID string) (SystemConfig, bool) {
+	for _, system := range b.config.Systems {
+		if system.ManagerID == managerID {
+			return system, true
+		}
+	}
+	return SystemConfig{}, false
+}
+
+// virtualMediaState returns the state behind a manager's virtual media.
+func (b *mockBMC) virtualMediaState(managerID string) (*mockServerState, bool) {
+	system, ok := b.virtualMediaSystem(managerID)
+	if !ok {
+		return nil, false
+	}
+	return b.systemStates[system.ID], true
+}
+
+func (b *mockBMC) virtualMediaURI(managerID string) string {
+	return "/redfish/v1/Managers/" + managerID + "/VirtualMedia/" + b.oem.resourceIDs().VirtualMedia
+}
+
+// lookupVirtualMedia resolves the manager and, on member routes, the media ID
+// of a VirtualMedia request. It writes ResourceNotFound for the first segment
+// that does not exist.
+func (b *mockBMC) lookupVirtualMedia(c *gin.Context) (*mockServerState, bool) {
+	managerID := c.Param("id")
+	if _, ok := b.findManager(managerID); !ok {
+		resourceNotFound(c, "Manager", managerID)
+		return nil, false
+	}
+	state, ok := b.virtualMediaState(managerID)
+	if !ok {
+		resourceNotFound(c, "VirtualMediaCollection", "VirtualMedia")
+		return nil, false
+	}
+	if mediaID := c.Param("mediaID"); mediaID != "" && mediaID != b.oem.resourceIDs().VirtualMedia {
+		resourceNotFound(c, "VirtualMedia", mediaID)
+		return nil, false
+	}
+	return state, true
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const twinConfig = `{
+	"oem": "supermicro",
+	"system": {"model": "TwinPro", "power_on_delay_seconds": 0, "power_off_delay_seconds": 0},
+	"systems": [
+		{"id": "Node1", "serial_number": "TWIN-A", "chassis_id": "Enclosure"},
+		{"id": "Node2", "serial_number": "TWIN-B", "chassis_id": "Enclosure", "manager_id": "BMC2", "power_state": "Off"}
+	],
+	"chassis_members": [{"id": "Enclosure", "chassis_type": "Enclosure"}],
+	"managers": [{"id": "BMC1"}, {"id": "BMC2", "name": "Node 2 BMC"}]
+}`
+
+func loadTopologyBMC(t *testing.T, contents string) *mockBMC {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(contents), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := loadConfig(path)
+	if err != nil {
+		t.Fatalf("loadConfig() error = %v", err)
+	}
+	return newTestBMC(t, loaded)
+}
+
+func TestMultipleSystemsChassisAndManagers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	bmc := loadTopologyBMC(t, twinConfig)
+	router := newRouter(bmc)
+	request := func(method, path, body string, response any) int {
+		t.Helper()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.SetBasicAuth(bmc.config.Authentication.Username, bmc.config.Authentication.Password)
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+		if response != nil {
+			if err := json.Unmarshal(recorder.Body.Bytes(), response); err != nil {
+				t.Fatalf("decode %s: %v", path, err)
+			}
+		}
+		return recorder.Code
+	}
+
+	var systems Collection
+	request(http.MethodGet, "/redfish/v1/Systems", "", &systems)
+	if systems.MembersCount != 2 || systems.Members[1].ODataID != "/redfish/v1/Systems/Node2" {
+		t.Fatalf("systems = %#v", systems)
+	}
+
+	var node2 ComputerSystem
+	request(http.MethodGet, "/redfish/v1/Systems/Node2", "", &node2)
+	if node2.SerialNumber != "TWIN-B" || node2.Model != "TwinPro" || node2.PowerState != "Off" {
+		t.Fatalf("Node2 = %q %q %q, want entry fields over the system template", node2.SerialNumber, node2.Model, node2.PowerState)
+	}
+	if node2.Links.Chassis[0].ODataID != "/redfish/v1/Chassis/Enclosure" || node2.Links.ManagedBy[0].ODataID != "/redfish/v1/Managers/BMC2" {
+		t.Fatalf("Node2 links = %#v", node2.Links)
+	}
+
+	var enclosure Chassis
+	request(http.MethodGet, "/redfish/v1/Chassis/Enclosure", "", &enclosure)
+	if len(enclosure.Links.ComputerSystems) != 2 || enclosure.Links.ManagedBy[0].ODataID != "/redfish/v1/Managers/BMC1" {
+		t.Fatalf("chassis links = %#v", enclosure.Links)
+	}
+
+	var bmc2 Manager
+	request(http.MethodGet, "/redfish/v1/Managers/BMC2", "", &bmc2)
+	if bmc2.Name != "Node 2 BMC" || len(bmc2.Links.ManagerForServers) != 1 || bmc2.Links.ManagerForServers[0].ODataID != "/redfish/v1/Systems/Node2" ||
+		len(bmc2.Links.ManagerForChassis) != 0 {
+		t.Fatalf("BMC2 = %#v", bmc2)
+	}
+
+	if status := request(http.MethodPost, "/redfish/v1/Systems/Node2/Actions/ComputerSystem.Reset", `{"ResetType":"On"}`, nil); status != http.StatusNoContent {
+		t.Fatalf("reset Node2 status = %d", status)
+	}
+	bmc.systemStates["Node2"].Lock()
+	bmc.systemStates["Node2"].advancePower(time.Now().Add(time.Second))
+	bmc.systemStates["Node2"].Unlock()
+	var node1 ComputerSystem
+	request(http.MethodGet, "/redfish/v1/Systems/Node1", "", &node1)
+	request(http.MethodGet, "/redfish/v1/Systems/Node2", "", &node2)
+	if node1.PowerState != "On" || node2.PowerState != "On" {
+		t.Fatalf("power states = %s, %s", node1.PowerState, node2.PowerState)
+	}
+	if status := request(http.MethodPost, "/redfish/v1/Systems/Node1/Actions/ComputerSystem.Reset", `{"ResetType":"ForceOff"}`, nil); status != http.StatusNoContent {
+		t.Fatalf("reset Node1 status = %d", status)
+	}
+	request(http.MethodGet, "/redfish/v1/Systems/Node2", "", &node2)
+	if node2.PowerState != "On" {
+		t.Fatalf("ForceOff on Node1 changed Node2 to %s", node2.PowerState)
+	}
+}
+
+func TestLoadConfigRejectsInvalidTopology(t *testing.T) {
+	tests := []struct {
+		name     string
+		contents string
+		wantErr  string
+	}{
+		{name: "missing id", contents: `{"systems":[{"serial_number":"A"}]}`, wantErr: "systems[0].id is required"},
+		{name: "duplicate id", contents: `{"managers":[{"id":"BMC"},{"id":"BMC"}]}`, wantErr: "managers[1].id"},
+		{name: "unknown chassis", contents: `{"systems":[{"id":"1","chassis_id":"Blade9"}]}`, wantErr: "systems[0].chassis_id"},
+		{name: "unknown field", contents: `{"chassis_members":[{"id":"1","slot":3}]}`, wantErr: `unknown field "slot"`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.json")
+			if err := os.WriteFile(path, []byte(test.contents), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := loadConfig(path); err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Fatalf("loadConfig() error = %v, want %q", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestUnknownResourceIDsReturnNotFound(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	bmc := newTestBMC(t, defaultConfig())
+	router := newRouter(bmc)
+	ids := bmc.oem.resourceIDs()
+	tests := []struct {
+		method string
+		path   string
+		body   string
+		args   []string
+	}{
+		{method: http.MethodGet, path: "/redfish/v1/Systems/garbage", args: []string{"ComputerSystem", "garbage"}},
+		{method: http.MethodPatch, path: "/redfish/v1/Systems/garbage", body: `{"Boot":{"BootSourceOverrideTarget":"Cd"}}`, args: []string{"ComputerSystem", "garbage"}},
+		{method: http.MethodPost, path: "/redfish/v1/Systems/garbage/Actions/ComputerSystem.Reset", body: `{"ResetType":"On"}`, args: []string{"ComputerSystem", "garbage"}},
+		{method: http.MethodGet, path: "/redfish/v1/Chassis/garbage", args: []string{"Chassis", "garbage"}},
+		{method: http.MethodGet, path: "/redfish/v1/Managers/garbage", args: []string{"Manager", "garbage"}},
+		{method: http.MethodGet, path: "/redfish/v1/Managers/garbage/VirtualMedia", args: []string{"Manager", "garbage"}},
+		{method: http.MethodGet, path: "/redfish/v1/Managers/garbage/VirtualMedia/" + ids.VirtualMedia, args: []string{"Manager", "garbage"}},
+		{method: http.MethodGet, path: "/redfish/v1/Managers/" + ids.Manager + "/VirtualMedia/Floppy1", args: []string{"VirtualMedia", "Floppy1"}},
+		{
+			method: http.MethodPost, path: "/redfish/v1/Managers/garbage/VirtualMedia/" + ids.VirtualMedia + "/Actions/VirtualMedia.InsertMedia",
+			body: `{"Image":"http://example.com/boot.iso"}`, args: []string{"Manager", "garbage"},
+		},
+		{method: http.MethodPost, path: "/redfish/v1/Managers/garbage/VirtualMedia/" + ids.VirtualMedia + "/Actions/VirtualMedia.EjectMedia", args: []string{"Manager", "garbage"}},
+	}
+	for _, test := range tests {
+		t.Run(test.method+" "+test.path, func(t *testing.T) {
+			request := httptest.NewRequest(test.method, test.path, strings.NewReader(test.body))
+			request.SetBasicAuth(bmc.config.Authentication.Username, bmc.config.Authentication.Password)
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, request)
+			if recorder.Code != http.StatusNotFound {
+				t.Fatalf("status = %d, want %d", recorder.Code, http.StatusNotFound)
+			}
+			var response RedfishError
+			if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+				t.Fatalf("decode error response: %v", err)
+			}
+			info := response.Error.ExtendedInfo
+			if response.Error.Code != "Base.1.16.0.ResourceNotFound" || len(info) != 1 ||
+				strings.Join(info[0].MessageArgs, "|") != strings.Join(test.args, "|") {
+				t.Fatalf("error = %#v, want ResourceNotFound %v", response.Error, test.args)
+			}
+		})
+	}
+}